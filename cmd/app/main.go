@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"frappuccino/internal/api"
 	"frappuccino/internal/api/handlers"
 	"frappuccino/internal/repo"
@@ -8,6 +9,8 @@ import (
 	"frappuccino/utils"
 	"log"
 	"net/http"
+	"os"
+	_ "time/tzdata"
 
 	_ "github.com/lib/pq"
 )
@@ -24,6 +27,15 @@ func main() {
 	services := services.New(repos)
 	handlers := handlers.New(services, baseHandler)
 
+	err := services.AuthService.Bootstrap(context.Background(), os.Getenv("ADMIN_USERNAME"), os.Getenv("ADMIN_PASSWORD"))
+	if err != nil {
+		log.Fatalf("Failed to create the first admin: %v", err)
+	}
+
+	go services.OrderStreamService.Run(context.Background())
+	go services.WebhookService.Run(context.Background())
+	go services.EventBus.Run(context.Background())
+
 	mux := api.Router(handlers)
-	log.Fatalln(http.ListenAndServe(":8080", mux))
+	log.Fatalln(http.ListenAndServe(":8080", api.WithLocation(api.WithAuth(mux, services.AuthService))))
 }
@@ -1,12 +1,114 @@
 -- Enable UUID extension
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
+-- Enable trigram matching for typo-tolerant search
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
 -- ENUM Types
-CREATE TYPE all_order_status AS ENUM ('PENDING', 'COMPLETED', 'CANCELLED');
+CREATE TYPE all_order_status AS ENUM ('PENDING', 'READY', 'COMPLETED', 'CANCELLED', 'REFUNDED');
 CREATE TYPE all_order_payment_method AS ENUM ('CASH', 'CARD');
-CREATE TYPE all_inventory_transaction_action AS ENUM ('ADD', 'REMOVE', 'ADJUST');
+CREATE TYPE all_inventory_transaction_action AS ENUM ('ADD', 'REMOVE', 'ADJUST', 'WASTE', 'SPOILAGE', 'THEFT', 'TRANSFER_OUT', 'TRANSFER_IN');
+CREATE TYPE all_stock_count_status AS ENUM ('OPEN', 'COMMITTED', 'CANCELLED');
+CREATE TYPE all_stock_transfer_status AS ENUM ('DRAFT', 'IN_TRANSIT', 'RECEIVED', 'CANCELLED');
+CREATE TYPE all_staff_role AS ENUM ('BARISTA', 'MANAGER', 'ADMIN');
+CREATE TYPE all_webhook_delivery_status AS ENUM ('PENDING', 'DELIVERED', 'FAILED');
+CREATE TYPE all_ticket_status AS ENUM ('OPEN', 'BUMPED');
 
 -- Tables
+CREATE TABLE locations (
+    location_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_name VARCHAR(255) NOT NULL UNIQUE,
+    address TEXT NOT NULL DEFAULT '',
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- The first store; rows created without an explicit location belong to it
+INSERT INTO locations (location_id, location_name) VALUES ('00000000-0000-0000-0000-000000000001', 'Main');
+
+CREATE TABLE staff (
+    staff_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    full_name VARCHAR(255) NOT NULL DEFAULT '',
+    username VARCHAR(64) NOT NULL UNIQUE,
+    -- PBKDF2-SHA256, encoded as pbkdf2-sha256$iterations$salt$key
+    password_hash TEXT NOT NULL,
+    staff_role all_staff_role NOT NULL DEFAULT 'BARISTA',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- Bearer tokens issued by POST /auth/login; only the SHA-256 of a token is kept
+CREATE TABLE auth_sessions (
+    token_hash CHAR(64) PRIMARY KEY,
+    staff_id UUID NOT NULL REFERENCES staff(staff_id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Keys for integrations; only the SHA-256 of a key is kept
+CREATE TABLE api_keys (
+    api_key_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    key_name VARCHAR(255) NOT NULL,
+    key_prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    key_role all_staff_role NOT NULL DEFAULT 'BARISTA',
+    created_by UUID REFERENCES staff(staff_id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Append-only record of every change made through the API. Actors are not
+-- foreign keys so entries outlive the staff members and keys they name.
+CREATE TABLE audit_log (
+    audit_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    actor_staff_id UUID,
+    actor_api_key_id UUID,
+    actor_name VARCHAR(255) NOT NULL DEFAULT '',
+    method VARCHAR(10) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    resource VARCHAR(50) NOT NULL,
+    resource_id UUID,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- Responses of requests sent with an Idempotency-Key, replayed when a client
+-- retries. Keys are scoped to the caller; a NULL status_code marks a request
+-- still being processed.
+CREATE TABLE idempotency_keys (
+    scope VARCHAR(100) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status_code INT,
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    response_body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (scope, idempotency_key)
+);
+
+-- Scheduled shifts of a staff member at a location; shifts of one staff
+-- member never overlap. clock_in_at and clock_out_at record the hours worked.
+CREATE TABLE shifts (
+    shift_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    staff_id UUID NOT NULL REFERENCES staff(staff_id) ON DELETE CASCADE,
+    location_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES locations(location_id) ON DELETE RESTRICT,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    clock_in_at TIMESTAMP WITH TIME ZONE,
+    clock_out_at TIMESTAMP WITH TIME ZONE,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CHECK (ends_at > starts_at),
+    CHECK (clock_out_at IS NULL OR (clock_in_at IS NOT NULL AND clock_out_at >= clock_in_at))
+);
+
 CREATE TABLE customers (
     customer_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     full_name VARCHAR(255) NOT NULL,
@@ -14,45 +116,90 @@ CREATE TABLE customers (
     email VARCHAR(255) NOT NULL,
     preferences JSONB DEFAULT '{}'::JSONB,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    -- Archived rows are hidden from listings but stay referenced by history
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Where order lines are prepared; each station's display shows its tickets.
+CREATE TABLE stations (
+    station_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    station_name VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
 );
 
+INSERT INTO stations (station_id, station_name) VALUES
+    ('00000000-0000-0000-0000-000000000101', 'Espresso Bar'),
+    ('00000000-0000-0000-0000-000000000102', 'Cold Bar'),
+    ('00000000-0000-0000-0000-000000000103', 'Kitchen');
+
 CREATE TABLE menu_items (
     menu_item_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     item_name VARCHAR(255) NOT NULL DEFAULT '',
     item_description TEXT NOT NULL DEFAULT '',
     price DECIMAL(10,2) NOT NULL CHECK (price >= 0),
     categories TEXT[] NOT NULL DEFAULT '{}',
+    -- Items without a station, such as bottled drinks, need no preparation
+    station_id UUID REFERENCES stations(station_id) ON DELETE SET NULL,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
-    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMP WITH TIME ZONE
 );
 
 CREATE TABLE inventory (
     ingredient_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    ingredient_name VARCHAR(255) NOT NULL UNIQUE,
+    -- Unique among active ingredients, see idx_inventory_ingredient_name
+    ingredient_name VARCHAR(255) NOT NULL,
     unit VARCHAR(15) NOT NULL,
-    quantity DECIMAL(10,2) NOT NULL CHECK (quantity >= 0),
+    -- Total across all locations, kept in sync from inventory_levels
+    quantity DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (quantity >= 0),
     reorder_level DECIMAL(10,2) NOT NULL CHECK (reorder_level >= 0),
+    unit_cost DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (unit_cost >= 0),
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
-    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()  
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE inventory_levels (
+    location_id UUID NOT NULL REFERENCES locations(location_id) ON DELETE RESTRICT,
+    ingredient_id UUID NOT NULL REFERENCES inventory(ingredient_id) ON DELETE CASCADE,
+    quantity DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (location_id, ingredient_id)
 );
 
 CREATE TABLE orders (
     order_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES locations(location_id) ON DELETE RESTRICT,
     customer_id UUID NOT NULL REFERENCES customers(customer_id) ON DELETE RESTRICT,
     special_instructions JSONB NOT NULL DEFAULT '{}'::JSONB,
     total_price DECIMAL(10,2) NOT NULL CHECK (total_price >= 0),
     order_status all_order_status NOT NULL DEFAULT 'PENDING',
     order_payment_method all_order_payment_method NOT NULL,
+    -- The staff member who took the order; for orders placed with an API key
+    -- the one the integration named, if any
+    staff_id UUID REFERENCES staff(staff_id) ON DELETE SET NULL,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
-    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMP WITH TIME ZONE
 );
 
+-- Every row is also an event of the order stream; event_id orders the
+-- events and is what SSE clients send back as Last-Event-ID.
 CREATE TABLE order_status_history (
     order_status_history_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id BIGINT GENERATED ALWAYS AS IDENTITY UNIQUE,
     order_id UUID REFERENCES orders(order_id) ON DELETE CASCADE,
     order_status all_order_status NOT NULL,
-    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+    -- NULL when the order was created
+    previous_status all_order_status,
+    notes TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    -- The transaction that wrote the event and the oldest one still running
+    -- at the time: events of those may commit after later event_ids
+    tx_id BIGINT NOT NULL DEFAULT pg_current_xact_id()::text::bigint,
+    snapshot_xmin BIGINT NOT NULL DEFAULT pg_snapshot_xmin(pg_current_snapshot())::text::bigint
 );
 
 CREATE TABLE order_items (
@@ -62,16 +209,86 @@ CREATE TABLE order_items (
     customizations JSONB NOT NULL DEFAULT '{}'::JSONB,
     item_name VARCHAR(255) NOT NULL,
     quantity DECIMAL(10,2) NOT NULL CHECK (quantity >= 0),
-    unit_price DECIMAL(10,2) NOT NULL CHECK (unit_price >= 0),
+    unit_price DECIMAL(10,2) NOT NULL CHECK (unit_price >= 0)
+);
+
+-- The lines of an order prepared at one station. Tickets are made when the
+-- order is created; once all tickets of a PENDING order are bumped it is READY.
+CREATE TABLE station_tickets (
+    ticket_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(order_id) ON DELETE CASCADE,
+    station_id UUID NOT NULL REFERENCES stations(station_id) ON DELETE RESTRICT,
+    location_id UUID NOT NULL REFERENCES locations(location_id) ON DELETE RESTRICT,
+    ticket_status all_ticket_status NOT NULL DEFAULT 'OPEN',
+    bumped_at TIMESTAMP WITH TIME ZONE,
+    bumped_by UUID REFERENCES staff(staff_id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE(order_id, station_id)
+);
+
+-- The order lines on a ticket. Lines can be bumped one by one; the ticket
+-- is bumped with its last line.
+CREATE TABLE station_ticket_items (
+    order_item_id UUID PRIMARY KEY REFERENCES order_items(order_item_id) ON DELETE CASCADE,
+    ticket_id UUID NOT NULL REFERENCES station_tickets(ticket_id) ON DELETE CASCADE,
+    bumped_at TIMESTAMP WITH TIME ZONE
+);
+
+-- An order can be settled by several payments (split bills, part cash and
+-- part card). Amounts count towards total_price; tips come on top of it.
+CREATE TABLE payments (
+    payment_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(order_id) ON DELETE CASCADE,
+    payment_method all_order_payment_method NOT NULL,
+    amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+    tip DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (tip >= 0),
+    -- The staff member who took the payment and is credited with the tip
+    staff_id UUID REFERENCES staff(staff_id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
 );
 
+-- Money given back on a completed order. A refund covers whole or partial
+-- order lines; once every line is refunded the order becomes REFUNDED.
+CREATE TABLE refunds (
+    refund_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(order_id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    amount DECIMAL(10,2) NOT NULL CHECK (amount >= 0),
+    -- Whether the ingredients of the refunded lines went back into stock
+    restocked BOOLEAN NOT NULL DEFAULT false,
+    staff_id UUID REFERENCES staff(staff_id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE TABLE refund_lines (
+    refund_line_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    refund_id UUID NOT NULL REFERENCES refunds(refund_id) ON DELETE CASCADE,
+    order_item_id UUID NOT NULL REFERENCES order_items(order_item_id) ON DELETE CASCADE,
+    quantity DECIMAL(10,2) NOT NULL CHECK (quantity > 0),
+    amount DECIMAL(10,2) NOT NULL CHECK (amount >= 0)
+);
+
+-- Every price a menu item has had; the current one has no effective_to
 CREATE TABLE price_history (
     price_history_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     menu_item_id UUID NOT NULL REFERENCES menu_items(menu_item_id) ON DELETE CASCADE,
     price DECIMAL(10,2) NOT NULL CHECK (price >= 0),
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    effective_to TIMESTAMP WITH TIME ZONE,
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
 );
 
+-- Per-location menu: a NULL price falls back to menu_items.price
+CREATE TABLE location_menu_prices (
+    location_id UUID NOT NULL REFERENCES locations(location_id) ON DELETE CASCADE,
+    menu_item_id UUID NOT NULL REFERENCES menu_items(menu_item_id) ON DELETE CASCADE,
+    price DECIMAL(10,2) CHECK (price >= 0),
+    is_available BOOLEAN NOT NULL DEFAULT true,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (location_id, menu_item_id)
+);
+
 CREATE TABLE menu_item_ingredients (
     menu_item_ingredients_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     menu_item_id UUID NOT NULL REFERENCES menu_items(menu_item_id) ON DELETE CASCADE,
@@ -83,26 +300,154 @@ CREATE TABLE menu_item_ingredients (
 
 CREATE TABLE inventory_transactions (
     inventory_transactions_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES locations(location_id) ON DELETE RESTRICT,
     ingredient_id UUID REFERENCES inventory(ingredient_id) ON DELETE RESTRICT NOT NULL,
     quantity DECIMAL(10,2) NOT NULL,
     inventory_transaction_action all_inventory_transaction_action NOT NULL,
+    reference_id UUID,
+    notes TEXT NOT NULL DEFAULT '',
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
 );    
 
+CREATE TABLE inventory_batches (
+    batch_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES locations(location_id) ON DELETE RESTRICT,
+    ingredient_id UUID NOT NULL REFERENCES inventory(ingredient_id) ON DELETE RESTRICT,
+    quantity_received DECIMAL(10,2) NOT NULL CHECK (quantity_received > 0),
+    quantity_remaining DECIMAL(10,2) NOT NULL CHECK (quantity_remaining >= 0),
+    expires_at TIMESTAMP WITH TIME ZONE,
+    notes TEXT NOT NULL DEFAULT '',
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE TABLE stock_counts (
+    stock_count_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    location_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES locations(location_id) ON DELETE RESTRICT,
+    stock_count_status all_stock_count_status NOT NULL DEFAULT 'OPEN',
+    opened_by VARCHAR(255) NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    closed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE stock_count_lines (
+    stock_count_line_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    stock_count_id UUID NOT NULL REFERENCES stock_counts(stock_count_id) ON DELETE CASCADE,
+    ingredient_id UUID NOT NULL REFERENCES inventory(ingredient_id) ON DELETE RESTRICT,
+    counted_quantity DECIMAL(10,2) NOT NULL CHECK (counted_quantity >= 0),
+    -- Stock on record when the count was committed, kept for the audit trail
+    expected_quantity DECIMAL(10,2),
+    counted_by VARCHAR(255) NOT NULL DEFAULT '',
+    counted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE(stock_count_id, ingredient_id)
+);
+
+CREATE TABLE stock_transfers (
+    transfer_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    from_location_id UUID NOT NULL REFERENCES locations(location_id) ON DELETE RESTRICT,
+    to_location_id UUID NOT NULL REFERENCES locations(location_id) ON DELETE RESTRICT,
+    transfer_status all_stock_transfer_status NOT NULL DEFAULT 'DRAFT',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    received_by VARCHAR(255) NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    shipped_at TIMESTAMP WITH TIME ZONE,
+    received_at TIMESTAMP WITH TIME ZONE,
+    CHECK (from_location_id <> to_location_id)
+);
+
+CREATE TABLE stock_transfer_lines (
+    transfer_line_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    transfer_id UUID NOT NULL REFERENCES stock_transfers(transfer_id) ON DELETE CASCADE,
+    ingredient_id UUID NOT NULL REFERENCES inventory(ingredient_id) ON DELETE RESTRICT,
+    quantity_sent DECIMAL(10,2) NOT NULL CHECK (quantity_sent > 0),
+    -- Set on receipt; the difference to quantity_sent is the discrepancy
+    quantity_received DECIMAL(10,2) CHECK (quantity_received >= 0),
+    UNIQUE(transfer_id, ingredient_id)
+);
+
+-- The batches a line was shipped from, so that the stock is received with
+-- the same expiry dates
+CREATE TABLE stock_transfer_batches (
+    transfer_batch_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    transfer_line_id UUID NOT NULL REFERENCES stock_transfer_lines(transfer_line_id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    quantity DECIMAL(10,2) NOT NULL CHECK (quantity > 0)
+);
+
+-- Other systems notified of the listed event types. Deliveries carry an
+-- HMAC-SHA256 signature made with the secret.
+CREATE TABLE webhook_subscriptions (
+    subscription_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    url TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- Domain events written in the same transaction as the change they
+-- describe, so that an event exists if and only if its change was committed.
+-- tx_id is the writing transaction: event ids are taken before commit and so
+-- can become visible out of order, but once every transaction below a
+-- snapshot's xmin has ended, (tx_id, event_id) order no longer changes.
+CREATE TABLE outbox_events (
+    event_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    tx_id BIGINT NOT NULL DEFAULT pg_current_xact_id()::text::bigint,
+    event_type VARCHAR(50) NOT NULL,
+    location_id UUID REFERENCES locations(location_id) ON DELETE SET NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- How far each subscriber of the event bus has got through outbox_events.
+CREATE TABLE outbox_cursors (
+    subscriber VARCHAR(100) PRIMARY KEY,
+    last_tx_id BIGINT NOT NULL DEFAULT 0,
+    last_event_id BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- One webhook event sent, or still to be sent, to one subscription. PENDING
+-- deliveries are attempted again at next_attempt_at until they succeed or
+-- run out of attempts. event_id is the domain event the webhook event was
+-- made from; one domain event can yield several webhook events.
+CREATE TABLE webhook_deliveries (
+    delivery_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(subscription_id) ON DELETE CASCADE,
+    event_id BIGINT NOT NULL REFERENCES outbox_events(event_id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    delivery_status all_webhook_delivery_status NOT NULL DEFAULT 'PENDING',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    -- HTTP status of the last attempt; NULL when no response was received
+    response_status INT,
+    last_error TEXT NOT NULL DEFAULT '',
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE(subscription_id, event_id, event_type)
+);
+
 -- Indexes for order_items table
 CREATE INDEX idx_order_items_order_id ON order_items(order_id);
 CREATE INDEX idx_order_items_menu_item_id ON order_items(menu_item_id);
 
 -- Indexes for menu_items table
 CREATE INDEX idx_menu_items_categories ON menu_items USING GIN(categories);
+CREATE INDEX idx_menu_items_item_name_trgm ON menu_items USING GIN(item_name gin_trgm_ops);
 CREATE INDEX idx_menu_items_price ON menu_items(price);
+CREATE INDEX idx_menu_items_deleted_at ON menu_items(deleted_at) WHERE deleted_at IS NOT NULL;
 
 -- Indexes for price_history table
 CREATE INDEX idx_price_history_menu_item_id ON price_history(menu_item_id);
 CREATE INDEX idx_price_history_updated_at ON price_history(updated_at);
 
 -- Indexes for inventory table
-CREATE INDEX idx_inventory_ingredient_name ON inventory(ingredient_name);
+CREATE UNIQUE INDEX idx_inventory_ingredient_name ON inventory(ingredient_name) WHERE deleted_at IS NULL;
+CREATE INDEX idx_inventory_deleted_at ON inventory(deleted_at) WHERE deleted_at IS NOT NULL;
 CREATE INDEX idx_inventory_reorder_level ON inventory(reorder_level);
 
 -- Indexes for menu_item_ingredients table
@@ -112,22 +457,83 @@ CREATE INDEX idx_menu_item_ingredients_ingredient_id ON menu_item_ingredients(in
 -- Indexes for customer table
 CREATE INDEX idx_customers_full_name ON customers(full_name);
 CREATE INDEX idx_customers_email ON customers(email);
+CREATE INDEX idx_customers_deleted_at ON customers(deleted_at) WHERE deleted_at IS NOT NULL;
 
 -- Indexes for inventory_transactions table
 CREATE INDEX idx_inventory_transactions_ingredient_id ON inventory_transactions(ingredient_id);
 CREATE INDEX idx_inventory_transactions_created_at ON inventory_transactions(created_at);
 CREATE INDEX idx_inventory_transactions_action ON inventory_transactions(inventory_transaction_action);
+CREATE INDEX idx_inventory_transactions_location_id ON inventory_transactions(location_id);
+
+-- Indexes for inventory_levels table
+CREATE INDEX idx_inventory_levels_ingredient_id ON inventory_levels(ingredient_id);
+
+-- Indexes for inventory_batches table
+CREATE INDEX idx_inventory_batches_ingredient_id ON inventory_batches(ingredient_id);
+CREATE INDEX idx_inventory_batches_location_id ON inventory_batches(location_id, ingredient_id);
+CREATE INDEX idx_inventory_batches_expires_at ON inventory_batches(expires_at) WHERE quantity_remaining > 0;
+
+-- Indexes for stock count tables
+CREATE INDEX idx_stock_counts_status ON stock_counts(stock_count_status);
+CREATE INDEX idx_stock_counts_location_id ON stock_counts(location_id);
+CREATE INDEX idx_stock_count_lines_stock_count_id ON stock_count_lines(stock_count_id);
+
+-- Indexes for stock transfer tables
+CREATE INDEX idx_stock_transfers_status ON stock_transfers(transfer_status);
+CREATE INDEX idx_stock_transfers_from_location_id ON stock_transfers(from_location_id);
+CREATE INDEX idx_stock_transfers_to_location_id ON stock_transfers(to_location_id);
+CREATE INDEX idx_stock_transfer_lines_transfer_id ON stock_transfer_lines(transfer_id);
+CREATE INDEX idx_stock_transfer_batches_transfer_line_id ON stock_transfer_batches(transfer_line_id);
+
+-- Indexes for auth tables
+CREATE INDEX idx_auth_sessions_staff_id ON auth_sessions(staff_id);
+CREATE INDEX idx_auth_sessions_expires_at ON auth_sessions(expires_at);
+
+-- Indexes for shifts table
+CREATE INDEX idx_shifts_staff_id ON shifts(staff_id, starts_at);
+CREATE INDEX idx_shifts_location_id ON shifts(location_id, starts_at);
+
+-- Indexes for audit_log table
+CREATE INDEX idx_audit_log_resource ON audit_log(resource, resource_id, created_at);
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+
+-- Indexes for idempotency keys
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+-- Indexes for station tickets
+CREATE INDEX idx_menu_items_station_id ON menu_items(station_id);
+CREATE INDEX idx_station_tickets_station_id ON station_tickets(station_id, location_id, ticket_status, created_at);
+CREATE INDEX idx_station_ticket_items_ticket_id ON station_ticket_items(ticket_id);
+
+-- Indexes for outbox and webhook tables
+CREATE INDEX idx_outbox_events_tx_id ON outbox_events(tx_id, event_id);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE delivery_status = 'PENDING';
+CREATE INDEX idx_webhook_deliveries_subscription_id ON webhook_deliveries(subscription_id, created_at);
 
 -- Indexes for orders table
 CREATE INDEX idx_orders_customer_id ON orders(customer_id);
 CREATE INDEX idx_orders_created_at ON orders(created_at);
 CREATE INDEX idx_orders_order_status ON orders(order_status);
 CREATE INDEX idx_orders_payment_method ON orders(order_payment_method); 
+CREATE INDEX idx_orders_location_id ON orders(location_id);
+CREATE INDEX idx_orders_staff_id ON orders(staff_id);
+CREATE INDEX idx_orders_deleted_at ON orders(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Indexes for payments table
+CREATE INDEX idx_payments_order_id ON payments(order_id);
+CREATE INDEX idx_payments_created_at ON payments(created_at);
+CREATE INDEX idx_payments_staff_id ON payments(staff_id);
+
+-- Indexes for refund tables
+CREATE INDEX idx_refunds_order_id ON refunds(order_id);
+CREATE INDEX idx_refund_lines_refund_id ON refund_lines(refund_id);
+CREATE INDEX idx_refund_lines_order_item_id ON refund_lines(order_item_id);
 
 -- Indexes for order_status_history table
 CREATE INDEX idx_order_status_history_order_id ON order_status_history(order_id);
 CREATE INDEX idx_order_status_history_order_status ON order_status_history(order_status);
 CREATE INDEX idx_order_status_history_updated_at ON order_status_history(updated_at);
+CREATE INDEX idx_order_status_history_tx_id ON order_status_history(tx_id);
 
 CREATE INDEX idx_price_history_effective_dates ON price_history (effective_from, effective_to);
 
@@ -149,6 +555,26 @@ CREATE TRIGGER update_menu_items_timestamp
     FOR EACH ROW
     EXECUTE FUNCTION update_timestamp();
 
+CREATE TRIGGER update_staff_timestamp
+    BEFORE UPDATE ON staff
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER update_shifts_timestamp
+    BEFORE UPDATE ON shifts
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER update_locations_timestamp
+    BEFORE UPDATE ON locations
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER update_inventory_levels_timestamp
+    BEFORE UPDATE ON inventory_levels
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+
 CREATE TRIGGER update_inventory_timestamp
     BEFORE UPDATE ON inventory
     FOR EACH ROW
@@ -159,37 +585,145 @@ CREATE TRIGGER update_orders_timestamp
     FOR EACH ROW
     EXECUTE FUNCTION update_timestamp();
 
--- Automatically log status changes to order_status_history
+CREATE TRIGGER update_stations_timestamp
+    BEFORE UPDATE ON stations
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER update_station_tickets_timestamp
+    BEFORE UPDATE ON station_tickets
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER update_webhook_subscriptions_timestamp
+    BEFORE UPDATE ON webhook_subscriptions
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER update_webhook_deliveries_timestamp
+    BEFORE UPDATE ON webhook_deliveries
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+
+-- The audit log can only be appended to
+CREATE OR REPLACE FUNCTION reject_audit_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_reject_audit_log_change
+BEFORE UPDATE OR DELETE ON audit_log
+FOR EACH ROW EXECUTE FUNCTION reject_audit_log_change();
+
+-- Automatically log status changes to order_status_history and announce
+-- them on the order_events channel, which the order stream listens to.
+-- Notifications are delivered when the transaction commits.
 CREATE OR REPLACE FUNCTION log_order_status_change()
 RETURNS TRIGGER AS $$
+DECLARE
+    history order_status_history%ROWTYPE;
 BEGIN
-    IF NEW.order_status <> OLD.order_status THEN
-        INSERT INTO order_status_history (order_id, order_status, notes)
-        VALUES (NEW.order_id, NEW.order_status, 'Status changed from ' || OLD.order_status || ' to ' || NEW.order_status);
+    IF TG_OP = 'INSERT' THEN
+        -- Record the initial status so durations can be measured from it
+        INSERT INTO order_status_history (order_id, order_status, notes, updated_at)
+        VALUES (NEW.order_id, NEW.order_status, 'Order created as ' || NEW.order_status, NEW.created_at)
+        RETURNING * INTO history;
+    ELSIF NEW.order_status <> OLD.order_status THEN
+        INSERT INTO order_status_history (order_id, order_status, previous_status, notes)
+        VALUES (NEW.order_id, NEW.order_status, OLD.order_status, 'Status changed from ' || OLD.order_status || ' to ' || NEW.order_status)
+        RETURNING * INTO history;
+    ELSE
+        RETURN NEW;
     END IF;
+
+    PERFORM pg_notify('order_events', json_build_object(
+        'event_id', history.event_id,
+        'event_type', CASE WHEN TG_OP = 'INSERT' THEN 'order.created' ELSE 'order.status_changed' END,
+        'order_id', NEW.order_id,
+        'location_id', NEW.location_id,
+        'order_status', history.order_status,
+        'previous_status', history.previous_status,
+        'notes', history.notes,
+        'created_at', history.updated_at
+    )::text);
     RETURN NEW;
 END;
 $$ LANGUAGE plpgsql;
 
 CREATE TRIGGER trigger_order_status_change
-AFTER UPDATE ON orders
+AFTER INSERT OR UPDATE ON orders
 FOR EACH ROW EXECUTE FUNCTION log_order_status_change();
 
+-- Keep inventory.quantity equal to the stock of all locations
+CREATE OR REPLACE FUNCTION sync_inventory_total()
+RETURNS TRIGGER AS $$
+DECLARE
+    target UUID := CASE WHEN TG_OP = 'DELETE' THEN OLD.ingredient_id ELSE NEW.ingredient_id END;
+BEGIN
+    UPDATE inventory
+    SET quantity = (
+        SELECT COALESCE(SUM(quantity), 0)
+        FROM inventory_levels
+        WHERE ingredient_id = target
+    )
+    WHERE ingredient_id = target;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_sync_inventory_total
+AFTER INSERT OR UPDATE OR DELETE ON inventory_levels
+FOR EACH ROW EXECUTE FUNCTION sync_inventory_total();
+
+-- Consume stock from ingredient batches, earliest expiry first (FEFO).
+-- Batches without an expiry date are used last, oldest receipt first (FIFO).
+-- Returns what was taken from each batch.
+CREATE OR REPLACE FUNCTION consume_inventory_batches(p_location_id UUID, p_ingredient_id UUID, p_quantity DECIMAL)
+RETURNS TABLE (taken_expires_at TIMESTAMP WITH TIME ZONE, taken_quantity DECIMAL) AS $$
+DECLARE
+    batch RECORD;
+    remaining DECIMAL := p_quantity;
+    taken DECIMAL;
+BEGIN
+    FOR batch IN
+        SELECT b.batch_id, b.quantity_remaining, b.expires_at
+        FROM inventory_batches b
+        WHERE b.location_id = p_location_id
+            AND b.ingredient_id = p_ingredient_id
+            AND b.quantity_remaining > 0
+        ORDER BY b.expires_at NULLS LAST, b.received_at
+        FOR UPDATE
+    LOOP
+        EXIT WHEN remaining <= 0;
+        taken := LEAST(batch.quantity_remaining, remaining);
+        UPDATE inventory_batches
+        SET quantity_remaining = quantity_remaining - taken
+        WHERE batch_id = batch.batch_id;
+        remaining := remaining - taken;
+        taken_expires_at := batch.expires_at;
+        taken_quantity := taken;
+        RETURN NEXT;
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
 -- Function to update inventory when order is completed
 CREATE OR REPLACE FUNCTION update_inventory_on_order_complete()
 RETURNS TRIGGER AS $$
 BEGIN
-    IF NEW.order_status = 'COMPLETE' AND OLD.order_status <> 'COMPLETE' THEN
+    IF NEW.order_status = 'COMPLETED' AND OLD.order_status <> 'COMPLETED' THEN
         -- Reduce inventory for each ingredient used in this order
-        INSERT INTO inventory_transactions (ingredient_id, quantity, inventory_transaction_action, reference_id, notes)
-        SELECT mii.ingredient_id, -1 * (mii.quantity * oi.quantity), 'REMOVE', NEW.order_id, 'Automatic deduction for order ' || NEW.order_id
+        INSERT INTO inventory_transactions (location_id, ingredient_id, quantity, inventory_transaction_action, reference_id, notes)
+        SELECT NEW.location_id, mii.ingredient_id, -1 * (mii.quantity * oi.quantity), 'REMOVE', NEW.order_id, 'Automatic deduction for order ' || NEW.order_id
         FROM order_items oi
         JOIN menu_item_ingredients mii ON oi.menu_item_id = mii.menu_item_id
         WHERE oi.order_id = NEW.order_id;
         
-        -- Update inventory quantities
-        UPDATE inventory i
-        SET quantity = i.quantity - subquery.total_quantity
+        -- Update inventory quantities at the order's location
+        UPDATE inventory_levels l
+        SET quantity = l.quantity - subquery.total_quantity
         FROM (
             SELECT mii.ingredient_id, SUM(mii.quantity * oi.quantity) as total_quantity
             FROM order_items oi
@@ -197,7 +731,15 @@ BEGIN
             WHERE oi.order_id = NEW.order_id
             GROUP BY mii.ingredient_id
         ) as subquery
-        WHERE i.ingredient_id = subquery.ingredient_id;
+        WHERE l.location_id = NEW.location_id
+            AND l.ingredient_id = subquery.ingredient_id;
+
+        -- Draw the used quantities from the ingredient batches
+        PERFORM consume_inventory_batches(NEW.location_id, mii.ingredient_id, SUM(mii.quantity * oi.quantity))
+        FROM order_items oi
+        JOIN menu_item_ingredients mii ON oi.menu_item_id = mii.menu_item_id
+        WHERE oi.order_id = NEW.order_id
+        GROUP BY mii.ingredient_id;
     END IF;
     RETURN NEW;
 END;
@@ -261,7 +803,7 @@ RETURNS TRIGGER AS $$
 BEGIN
     UPDATE orders
     SET total_price = (
-        SELECT COALESCE(SUM(total_price), 0)
+        SELECT COALESCE(SUM(quantity * unit_price), 0)
         FROM order_items
         WHERE order_id = 
             CASE 
@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"frappuccino/internal/services"
+	"frappuccino/utils"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type AggregationHandler struct {
@@ -35,8 +38,29 @@ func (ah *AggregationHandler) GetTotalSales(w http.ResponseWriter, r *http.Reque
 func (ah *AggregationHandler) GetPopularItems(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	popularItems, err := ah.service.GetPopularItems(ctx)
+	query := r.URL.Query()
+	var limit int
+	if limitString := query.Get("limit"); len(limitString) != 0 {
+		var err error
+		limit, err = strconv.Atoi(limitString)
+		if err != nil || limit < 1 {
+			ah.handleError(w, r, http.StatusBadRequest, "Invalid limit value", err)
+			return
+		}
+	}
+
+	popularItems, err := ah.service.GetPopularItems(ctx,
+		query.Get("from"),
+		query.Get("to"),
+		query.Get("category"),
+		query.Get("metric"),
+		limit,
+	)
 	if err != nil {
+		if errors.Is(err, utils.ErrInvalidMetric) || errors.Is(err, utils.ErrInvalidDateRange) {
+			ah.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+			return
+		}
 		ah.handleError(w, r, http.StatusInternalServerError, "Failed to fetch popular items", err)
 		return
 	}
@@ -97,7 +121,7 @@ func (aggregationHandler *AggregationHandler) GetListOfOrderedItems(w http.Respo
 	year := r.URL.Query().Get("year")
 
 	if len(year) == 0 {
-		year = "2022"
+		year = strconv.Itoa(time.Now().Year())
 	}
 	result, err := aggregationHandler.service.GetListOfOrderedItems(ctx, period, month, year)
 	if err != nil {
@@ -107,3 +131,107 @@ func (aggregationHandler *AggregationHandler) GetListOfOrderedItems(w http.Respo
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
+
+func (ah *AggregationHandler) GetSalesReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+	report, err := ah.service.GetSalesReport(ctx,
+		query.Get("from"),
+		query.Get("to"),
+		query.Get("bucket"),
+		query.Get("timezone"),
+		query.Get("groupBy"),
+	)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidBucket) ||
+			errors.Is(err, utils.ErrInvalidGroupBy) ||
+			errors.Is(err, utils.ErrInvalidTimezone) ||
+			errors.Is(err, utils.ErrInvalidDateRange) {
+			ah.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+			return
+		}
+		ah.handleError(w, r, http.StatusInternalServerError, "Failed to build sales report", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func (ah *AggregationHandler) GetTipReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+	report, err := ah.service.GetTipReport(ctx, query.Get("from"), query.Get("to"), query.Get("timezone"))
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidTimezone) || errors.Is(err, utils.ErrInvalidDateRange) {
+			ah.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+			return
+		}
+		ah.handleError(w, r, http.StatusInternalServerError, "Failed to build tip report", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func (ah *AggregationHandler) GetHeatmap(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+	heatmap, err := ah.service.GetHeatmap(ctx, query.Get("from"), query.Get("to"), query.Get("timezone"))
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidTimezone) || errors.Is(err, utils.ErrInvalidDateRange) {
+			ah.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+			return
+		}
+		ah.handleError(w, r, http.StatusInternalServerError, "Failed to build heatmap", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(heatmap)
+}
+
+func (ah *AggregationHandler) GetPrepTimeReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+	report, err := ah.service.GetPrepTimeReport(ctx,
+		query.Get("from"),
+		query.Get("to"),
+		query.Get("timezone"),
+		query.Get("fromStatus"),
+		query.Get("toStatus"),
+		query.Get("threshold"),
+	)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidTimezone) ||
+			errors.Is(err, utils.ErrInvalidDateRange) ||
+			errors.Is(err, utils.ErrInvalidStatus) ||
+			errors.Is(err, utils.ErrInvalidThreshold) {
+			ah.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+			return
+		}
+		ah.handleError(w, r, http.StatusInternalServerError, "Failed to build preparation time report", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func (ah *AggregationHandler) GetInventoryVariance(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+	report, err := ah.service.GetInventoryVariance(ctx, query.Get("from"), query.Get("to"))
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidDateRange) {
+			ah.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+			return
+		}
+		ah.handleError(w, r, http.StatusInternalServerError, "Failed to build inventory variance report", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
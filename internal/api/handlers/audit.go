@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"frappuccino/internal/services"
+	"frappuccino/utils"
+	"net/http"
+	"strconv"
+)
+
+type AuditHandler struct {
+	service services.AuditServiceIfc
+	*BaseHandler
+}
+
+func NewAuditHandler(service services.AuditServiceIfc, baseHandler *BaseHandler) *AuditHandler {
+	return &AuditHandler{service: service, BaseHandler: baseHandler}
+}
+
+func (ah *AuditHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	resource := query.Get("resource")
+	if len(resource) == 0 {
+		ah.handleError(w, r, http.StatusBadRequest, "Query parameter 'resource' is required", nil)
+		return
+	}
+
+	var limit int
+	if limitString := query.Get("limit"); len(limitString) != 0 {
+		var err error
+		limit, err = strconv.Atoi(limitString)
+		if err != nil || limit < 1 {
+			ah.handleError(w, r, http.StatusBadRequest, "Invalid limit value", err)
+			return
+		}
+	}
+
+	entries, err := ah.service.GetByResource(ctx, resource, query.Get("id"), limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrInvalidAuditResource),
+			errors.Is(err, utils.ErrInvalidAuditId):
+			ah.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+		default:
+			ah.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
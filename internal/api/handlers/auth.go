@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"frappuccino/internal/services"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+type AuthHandler struct {
+	service      services.AuthServiceIfc
+	staffService services.StaffServiceIfc
+	*BaseHandler
+}
+
+func NewAuthHandler(service services.AuthServiceIfc, staffService services.StaffServiceIfc, baseHandler *BaseHandler) *AuthHandler {
+	return &AuthHandler{service: service, staffService: staffService, BaseHandler: baseHandler}
+}
+
+func (ah *AuthHandler) PostLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var request models.LoginRequest
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		ah.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if err := json.Unmarshal(data, &request); err != nil {
+		ah.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	session, err := ah.service.Login(ctx, &request)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidCredentials) {
+			ah.handleError(w, r, http.StatusUnauthorized, utils.TEXT(err.Error()), nil)
+			return
+		}
+		ah.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+	ah.logger.Info("Staff logged in", slog.String("staff_id", string(session.Staff.StaffId)))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+func (ah *AuthHandler) PostLogout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	token := utils.BearerToken(r)
+	if token == "" {
+		ah.handleError(w, r, http.StatusBadRequest, "Logout needs a bearer token", nil)
+		return
+	}
+	if err := ah.service.Logout(ctx, token); err != nil {
+		ah.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Logged out successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (ah *AuthHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	principal, _ := utils.PrincipalFrom(ctx)
+	if principal.StaffId == "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"api_key_id": principal.ApiKeyId,
+			"key_name":   principal.Name,
+			"key_role":   principal.Role,
+		})
+		return
+	}
+
+	staff, err := ah.staffService.GetByID(ctx, principal.StaffId)
+	if err != nil {
+		ah.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(staff)
+}
+
+func (ah *AuthHandler) PostAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var newKey models.APIKey
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		ah.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if err := json.Unmarshal(data, &newKey); err != nil {
+		ah.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	created, err := ah.service.CreateAPIKey(ctx, &newKey)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidKeyName) || errors.Is(err, utils.ErrInvalidRole) {
+			ah.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+			return
+		}
+		ah.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+	ah.logger.Info("API key created", slog.String("api_key_id", string(created.ApiKeyId)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (ah *AuthHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	keys, err := ah.service.GetAPIKeys(ctx)
+	if err != nil {
+		ah.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+func (ah *AuthHandler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	if err := ah.service.RevokeAPIKey(ctx, id); err != nil {
+		if errors.Is(err, utils.ErrIdNotFound) {
+			ah.handleError(w, r, http.StatusNotFound, "ID not found", err)
+			return
+		}
+		ah.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+	ah.logger.Info("API key revoked", slog.String("api_key_id", id))
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "API key revoked successfully",
+	}
+	successResponse.Send(w)
+}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"frappuccino/internal/services"
 	"frappuccino/utils"
+	"io"
 	"log/slog"
+	"mime"
 	"net/http"
 )
 
@@ -21,6 +25,20 @@ type Handler struct {
 	MenuHandler        *MenuHandler
 	OrderHandler       *OrderHandler
 	AggregationHandler *AggregationHandler
+	StockCountHandler  *StockCountHandler
+	LocationHandler    *LocationHandler
+	TransferHandler    *StockTransferHandler
+	StaffHandler       *StaffHandler
+	AuthHandler        *AuthHandler
+	AuditHandler       *AuditHandler
+	ShiftHandler       *ShiftHandler
+	PaymentHandler     *PaymentHandler
+	RefundHandler      *RefundHandler
+	IdempotencyHandler *IdempotencyHandler
+	OrderStreamHandler *OrderStreamHandler
+	WebhookHandler     *WebhookHandler
+	StationHandler     *StationHandler
+	ReceiptHandler     *ReceiptHandler
 }
 
 func New(service *services.Base, base *BaseHandler) *Handler {
@@ -30,6 +48,20 @@ func New(service *services.Base, base *BaseHandler) *Handler {
 		MenuHandler:        NewMenuHandler(service.MenuService, base),
 		OrderHandler:       NewOrderHandler(service.OrderService, base),
 		AggregationHandler: NewAggregationHandler(service.AggregationService, base),
+		StockCountHandler:  NewStockCountHandler(service.StockCountService, base),
+		LocationHandler:    NewLocationHandler(service.LocationService, base),
+		TransferHandler:    NewStockTransferHandler(service.TransferService, base),
+		StaffHandler:       NewStaffHandler(service.StaffService, base),
+		AuthHandler:        NewAuthHandler(service.AuthService, service.StaffService, base),
+		AuditHandler:       NewAuditHandler(service.AuditService, base),
+		ShiftHandler:       NewShiftHandler(service.ShiftService, base),
+		PaymentHandler:     NewPaymentHandler(service.PaymentService, base),
+		RefundHandler:      NewRefundHandler(service.RefundService, base),
+		IdempotencyHandler: NewIdempotencyHandler(service.IdempotencyService, base),
+		OrderStreamHandler: NewOrderStreamHandler(service.OrderStreamService, base),
+		WebhookHandler:     NewWebhookHandler(service.WebhookService, base),
+		StationHandler:     NewStationHandler(service.StationService, base),
+		ReceiptHandler:     NewReceiptHandler(service.ReceiptService, base),
 	}
 }
 
@@ -47,3 +79,67 @@ func (b *BaseHandler) handleError(w http.ResponseWriter, r *http.Request, code u
 	}
 	jsonErr.Send(w)
 }
+
+// setETag tags the response with the version of the returned row.
+func setETag(w http.ResponseWriter, updatedAt utils.TIME) {
+	w.Header().Set("ETag", utils.ETag(updatedAt))
+}
+
+// requireIfMatch makes the write of a request conditional on its If-Match
+// header and returns the context to write with. Without the header it
+// answers 428 and returns false.
+func (b *BaseHandler) requireIfMatch(w http.ResponseWriter, r *http.Request) (context.Context, bool) {
+	tags := utils.ParseIfMatch(r.Header.Get("If-Match"))
+	if len(tags) == 0 {
+		b.handleError(w, r, http.StatusPreconditionRequired, utils.TEXT(utils.ErrPreconditionRequired.Error()), nil)
+		return nil, false
+	}
+	return utils.WithIfMatch(r.Context(), tags), true
+}
+
+// readMergePatch reads the body of a PATCH request, which must be a JSON
+// merge patch (RFC 7396). It answers 415 or 400 and returns false otherwise.
+func (b *BaseHandler) readMergePatch(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+		b.handleError(w, r, http.StatusUnsupportedMediaType, "Content-Type must be application/merge-patch+json", err)
+		return nil, false
+	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		b.handleError(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return nil, false
+	}
+	return patch, true
+}
+
+// handlePatchError answers a failed PATCH request.
+func (b *BaseHandler) handlePatchError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, utils.ErrIdNotFound):
+		b.handleError(w, r, http.StatusNotFound, "ID not found", err)
+	case errors.Is(err, utils.ErrPreconditionFailed):
+		b.handleError(w, r, http.StatusPreconditionFailed, utils.TEXT(err.Error()), err)
+	case errors.Is(err, utils.ErrConflictFields):
+		b.handleError(w, r, http.StatusConflict, "Conflict Fields", err)
+	case errors.Is(err, utils.ErrOrderUnpaid), errors.Is(err, utils.ErrOverpayment), errors.Is(err, utils.ErrOrderFinal),
+		errors.Is(err, utils.ErrOrderItemsLocked):
+		b.handleError(w, r, http.StatusConflict, utils.TEXT(err.Error()), err)
+	case errors.Is(err, utils.ErrInvalidMergePatch),
+		errors.Is(err, utils.ErrInvalidPatchValue),
+		errors.Is(err, utils.ErrPatchField),
+		errors.Is(err, utils.ErrEmptyField),
+		errors.Is(err, utils.ErrOrderItemsChanged),
+		errors.Is(err, utils.ErrInvalidIngredientName),
+		errors.Is(err, utils.ErrInvalidQuantity),
+		errors.Is(err, utils.ErrInvalidReorderLevel),
+		errors.Is(err, utils.ErrInvalidPrice),
+		errors.Is(err, utils.ErrInvalidStatus),
+		errors.Is(err, utils.ErrStatusNotSettable),
+		errors.Is(err, utils.ErrInvalidPaymentMethod),
+		errors.Is(err, utils.ErrInvalidStationId):
+		b.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+	default:
+		b.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+	}
+}
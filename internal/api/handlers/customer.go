@@ -89,12 +89,16 @@ func (ch *CustomerHandler) Get(w http.ResponseWriter, r *http.Request) {
 		slog.String("name", string(customer.FullName)),
 		slog.String("url", r.URL.Path),
 	)
+	setETag(w, customer.UpdatedAt)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(customer)
 }
 
 func (ch *CustomerHandler) Put(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, ok := ch.requireIfMatch(w, r)
+	if !ok {
+		return
+	}
 
 	var newCustomer models.Customer
 	data, err := io.ReadAll(r.Body)
@@ -121,8 +125,12 @@ func (ch *CustomerHandler) Put(w http.ResponseWriter, r *http.Request) {
 		} else if errors.Is(err, utils.ErrConflictFields) {
 			ch.handleError(w, r, http.StatusConflict, "Conflict Fields", err)
 			return
+		} else if errors.Is(err, utils.ErrPreconditionFailed) {
+			ch.handleError(w, r, http.StatusPreconditionFailed, utils.TEXT(err.Error()), err)
+			return
 		}
 		ch.handleError(w, r, http.StatusInternalServerError, "Unexpected error", err)
+		return
 	}
 
 	successResponse := utils.APIResponse{
@@ -132,8 +140,37 @@ func (ch *CustomerHandler) Put(w http.ResponseWriter, r *http.Request) {
 	successResponse.Send(w)
 }
 
+// Patch applies a JSON merge patch; like Put it requires If-Match.
+func (ch *CustomerHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := ch.requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+	patch, ok := ch.readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.PathValue("id")
+	customer, err := ch.service.PatchById(ctx, id, patch)
+	if err != nil {
+		ch.handlePatchError(w, r, err)
+		return
+	}
+	ch.logger.Info("Customer patched successfully",
+		slog.String("id", id),
+		slog.String("url", r.URL.Path),
+	)
+	setETag(w, customer.UpdatedAt)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(customer)
+}
+
 func (ch *CustomerHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, ok := ch.requireIfMatch(w, r)
+	if !ok {
+		return
+	}
 
 	id := r.PathValue("id")
 
@@ -142,6 +179,10 @@ func (ch *CustomerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 			ch.handleError(w, r, http.StatusNotFound, "ID not found", err)
 			return
 		}
+		if errors.Is(err, utils.ErrPreconditionFailed) {
+			ch.handleError(w, r, http.StatusPreconditionFailed, utils.TEXT(err.Error()), err)
+			return
+		}
 		ch.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
 		return
 	}
@@ -151,3 +192,26 @@ func (ch *CustomerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 	successResponse.Send(w)
 }
+
+func (ch *CustomerHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+
+	if err := ch.service.RestoreCustomerById(ctx, id); err != nil {
+		switch {
+		case errors.Is(err, utils.ErrIdNotFound):
+			ch.handleError(w, r, http.StatusNotFound, "ID not found", err)
+		case errors.Is(err, utils.ErrNotArchived):
+			ch.handleError(w, r, http.StatusConflict, utils.TEXT(err.Error()), err)
+		default:
+			ch.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		}
+		return
+	}
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Customer restored successfully",
+	}
+	successResponse.Send(w)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"frappuccino/internal/services"
+	"frappuccino/utils"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+type IdempotencyHandler struct {
+	service services.IdempotencyServiceIfc
+	*BaseHandler
+}
+
+func NewIdempotencyHandler(service services.IdempotencyServiceIfc, baseHandler *BaseHandler) *IdempotencyHandler {
+	return &IdempotencyHandler{
+		service:     service,
+		BaseHandler: baseHandler,
+	}
+}
+
+// Wrap makes next honour the Idempotency-Key header: a retried request gets
+// the stored response instead of being processed again. Requests without
+// the header are passed through unchanged.
+func (ih *IdempotencyHandler) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+		ctx := r.Context()
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			ih.handleError(w, r, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(data))
+
+		stored, err := ih.service.Begin(ctx, key, data)
+		if err != nil {
+			switch {
+			case errors.Is(err, utils.ErrInvalidIdempotencyKey):
+				ih.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+			case errors.Is(err, utils.ErrIdempotencyMismatch), errors.Is(err, utils.ErrIdempotencyInProgress):
+				ih.handleError(w, r, http.StatusConflict, utils.TEXT(err.Error()), err)
+			default:
+				ih.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+			}
+			return
+		}
+		if stored != nil {
+			ih.logger.Info("Replaying stored response",
+				slog.String("key", key),
+				slog.String("url", r.URL.Path),
+			)
+			if stored.ContentType != "" {
+				w.Header().Set("Content-Type", string(stored.ContentType))
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(*stored.StatusCode)
+			w.Write(stored.ResponseBody)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+		// The response is kept even when the client went away before it
+		// was written, since the retry is then the one that needs it.
+		ih.service.Finish(context.WithoutCancel(ctx), key, recorder.status, recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+	}
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if !rr.wroteHeader {
+		rr.status = status
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
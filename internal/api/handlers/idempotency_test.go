@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"frappuccino/internal/services"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryIdempotencyRepo keeps idempotency keys in memory the way the
+// idempotency_keys table does.
+type memoryIdempotencyRepo struct {
+	keys map[string]models.IdempotencyKey
+}
+
+func (mr *memoryIdempotencyRepo) Reserve(ctx context.Context, key *models.IdempotencyKey, ttl time.Duration, lockTimeout time.Duration) (bool, error) {
+	id := string(key.Scope) + "/" + string(key.Key)
+	if _, ok := mr.keys[id]; ok {
+		return false, nil
+	}
+	mr.keys[id] = *key
+	return true, nil
+}
+
+func (mr *memoryIdempotencyRepo) Get(ctx context.Context, scope string, key string) (models.IdempotencyKey, error) {
+	stored, ok := mr.keys[scope+"/"+key]
+	if !ok {
+		return models.IdempotencyKey{}, utils.ErrIdNotFound
+	}
+	return stored, nil
+}
+
+func (mr *memoryIdempotencyRepo) Complete(ctx context.Context, scope string, key string, statusCode int, contentType string, body []byte) error {
+	stored := mr.keys[scope+"/"+key]
+	stored.StatusCode = &statusCode
+	stored.ContentType = utils.TEXT(contentType)
+	stored.ResponseBody = body
+	mr.keys[scope+"/"+key] = stored
+	return nil
+}
+
+func (mr *memoryIdempotencyRepo) Release(ctx context.Context, scope string, key string) error {
+	delete(mr.keys, scope+"/"+key)
+	return nil
+}
+
+// countingOrderService creates orders without a database and counts the
+// orders created.
+type countingOrderService struct {
+	services.OrderServiceIfc
+	created int
+}
+
+func (cs *countingOrderService) Create(ctx context.Context, order *models.Orders) (*models.Orders, error) {
+	cs.created++
+	order.OrderId = utils.TEXT(fmt.Sprintf("order-%d", cs.created))
+	return order, nil
+}
+
+func (cs *countingOrderService) CreateBatch(ctx context.Context, orders []models.Orders) ([]models.Orders, error) {
+	for i := range orders {
+		if _, err := cs.Create(ctx, &orders[i]); err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+func TestIdempotentOrderCreation(t *testing.T) {
+	const order = `{"customer_id":"c1","payment_method":"CASH","OrderItems":[{"menu_item_id":"m1","quantity":1}]}`
+	const otherOrder = `{"customer_id":"c2","payment_method":"CARD","OrderItems":[{"menu_item_id":"m1","quantity":2}]}`
+
+	tests := []struct {
+		route   string
+		handler func(oh *OrderHandler) http.HandlerFunc
+		body    string
+		other   string
+		orders  int
+	}{
+		{
+			route:   "POST /order",
+			handler: func(oh *OrderHandler) http.HandlerFunc { return oh.Post },
+			body:    order,
+			other:   otherOrder,
+			orders:  1,
+		},
+		{
+			route:   "POST /order/batch-process",
+			handler: func(oh *OrderHandler) http.HandlerFunc { return oh.BatchProcess },
+			body:    "[" + order + "," + otherOrder + "]",
+			other:   "[" + otherOrder + "]",
+			orders:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			base := NewBaseHandler(slog.New(slog.NewTextHandler(io.Discard, nil)))
+			orderService := &countingOrderService{}
+			idempotency := NewIdempotencyHandler(
+				services.NewIdempotencyService(&memoryIdempotencyRepo{keys: map[string]models.IdempotencyKey{}}),
+				base,
+			)
+			handler := idempotency.Wrap(tt.handler(NewOrderHandler(orderService, base)))
+			_, path, _ := strings.Cut(tt.route, " ")
+			send := func(key string, body string) *httptest.ResponseRecorder {
+				r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+				r = r.WithContext(utils.WithRoute(r.Context(), tt.route))
+				r.Header.Set("Content-Type", "application/json")
+				if key != "" {
+					r.Header.Set("Idempotency-Key", key)
+				}
+				w := httptest.NewRecorder()
+				handler(w, r)
+				return w
+			}
+
+			first := send("key-1", tt.body)
+			if first.Code != http.StatusCreated {
+				t.Fatalf("first request: status %d, want %d: %s", first.Code, http.StatusCreated, first.Body)
+			}
+			if orderService.created != tt.orders {
+				t.Fatalf("first request created %d orders, want %d", orderService.created, tt.orders)
+			}
+
+			replay := send("key-1", tt.body)
+			if replay.Code != http.StatusCreated {
+				t.Errorf("replay: status %d, want %d", replay.Code, http.StatusCreated)
+			}
+			if replay.Header().Get("Idempotent-Replayed") != "true" {
+				t.Error("replay is not marked as replayed")
+			}
+			if replay.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+				t.Errorf("replay Content-Type %q, want %q", replay.Header().Get("Content-Type"), first.Header().Get("Content-Type"))
+			}
+			if replay.Body.String() != first.Body.String() {
+				t.Errorf("replay body %s, want %s", replay.Body, first.Body)
+			}
+			if orderService.created != tt.orders {
+				t.Errorf("replay created orders again: %d, want %d", orderService.created, tt.orders)
+			}
+
+			if mismatch := send("key-1", tt.other); mismatch.Code != http.StatusConflict {
+				t.Errorf("same key with another body: status %d, want %d", mismatch.Code, http.StatusConflict)
+			}
+			if orderService.created != tt.orders {
+				t.Errorf("mismatched request created orders: %d, want %d", orderService.created, tt.orders)
+			}
+
+			if fresh := send("key-2", tt.body); fresh.Code != http.StatusCreated || fresh.Header().Get("Idempotent-Replayed") != "" {
+				t.Errorf("new key: status %d, replayed %q; want a new response", fresh.Code, fresh.Header().Get("Idempotent-Replayed"))
+			}
+			if unkeyed := send("", tt.body); unkeyed.Code != http.StatusCreated {
+				t.Errorf("no key: status %d, want %d", unkeyed.Code, http.StatusCreated)
+			}
+			if orderService.created != 3*tt.orders {
+				t.Errorf("created %d orders in all, want %d", orderService.created, 3*tt.orders)
+			}
+		})
+	}
+}
@@ -3,7 +3,6 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"frappuccino/internal/services"
 	"frappuccino/models"
 	"frappuccino/utils"
@@ -99,13 +98,7 @@ func (ih *InventoryHandler) Get(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	id := r.PathValue("id")
-
-	var newInventory models.Inventory
-
-	newInventory.IngredientId = utils.TEXT(id)
-
-	status := "GET"
-	inventoryItem, err := ih.service.Create(ctx, &newInventory)
+	inventoryItem, err := ih.service.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, utils.ErrIdNotFound) {
 			ih.handleError(w, r, http.StatusNotFound, "ID not found", err)
@@ -114,8 +107,6 @@ func (ih *InventoryHandler) Get(w http.ResponseWriter, r *http.Request) {
 		ih.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
 		return
 	}
-	fmt.Println(inventoryItem)
-	err = ih.service.CreateTransaction(ctx, inventoryItem, status)
 	ih.logger.Info("Fetched inventory item by ID",
 		slog.String("id", id),
 		slog.String("name", string(inventoryItem.IngredientName)),
@@ -124,12 +115,16 @@ func (ih *InventoryHandler) Get(w http.ResponseWriter, r *http.Request) {
 		slog.String("url", r.URL.Path),
 	)
 
+	setETag(w, inventoryItem.UpdatedAt)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(inventoryItem)
 }
 
 func (ih *InventoryHandler) Put(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, ok := ih.requireIfMatch(w, r)
+	if !ok {
+		return
+	}
 
 	id := r.PathValue("id")
 	status := "PUT"
@@ -150,8 +145,13 @@ func (ih *InventoryHandler) Put(w http.ResponseWriter, r *http.Request) {
 		ih.handleError(w, r, http.StatusBadRequest, "Invalid inventory item data", nil)
 		return
 	}
+	newInventoryItem.IngredientId = utils.TEXT(id)
 	err = ih.service.UpdateByID(ctx, &newInventoryItem)
 	if err != nil {
+		if errors.Is(err, utils.ErrPreconditionFailed) {
+			ih.handleError(w, r, http.StatusPreconditionFailed, utils.TEXT(err.Error()), err)
+			return
+		}
 		ih.handleError(w, r, http.StatusNotFound, "ID not found", err)
 		return
 	}
@@ -176,12 +176,45 @@ func (ih *InventoryHandler) Put(w http.ResponseWriter, r *http.Request) {
 	successResponse.Send(w)
 }
 
+// Patch applies a JSON merge patch; like Put it requires If-Match.
+func (ih *InventoryHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := ih.requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+	patch, ok := ih.readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.PathValue("id")
+	inventoryItem, err := ih.service.Patch(ctx, id, patch)
+	if err != nil {
+		ih.handlePatchError(w, r, err)
+		return
+	}
+	ih.logger.Info("Inventory item patched successfully",
+		slog.String("id", id),
+		slog.String("url", r.URL.Path),
+	)
+	setETag(w, inventoryItem.UpdatedAt)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inventoryItem)
+}
+
 func (ih *InventoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, ok := ih.requireIfMatch(w, r)
+	if !ok {
+		return
+	}
 
 	id := r.PathValue("id")
 	err := ih.service.DeleteByID(ctx, id)
 	if err != nil {
+		if errors.Is(err, utils.ErrPreconditionFailed) {
+			ih.handleError(w, r, http.StatusPreconditionFailed, utils.TEXT(err.Error()), err)
+			return
+		}
 		ih.handleError(w, r, http.StatusNotFound, "ID not found", err)
 		return
 	}
@@ -198,6 +231,174 @@ func (ih *InventoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	successResponse.Send(w)
 }
 
+func (ih *InventoryHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	err := ih.service.Restore(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrIdNotFound):
+			ih.handleError(w, r, http.StatusNotFound, "ID not found", err)
+		case errors.Is(err, utils.ErrNotArchived):
+			ih.handleError(w, r, http.StatusConflict, utils.TEXT(err.Error()), err)
+		case errors.Is(err, utils.ErrConflictFields):
+			ih.handleError(w, r, http.StatusConflict, "an active ingredient already uses this name", err)
+		case errors.Is(err, utils.ErrInvalidIngredientId):
+			ih.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+		default:
+			ih.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		}
+		return
+	}
+
+	ih.logger.Info("Inventory item restored successfully",
+		slog.String("id", id),
+		slog.String("url", r.URL.Path),
+	)
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Inventory item restored successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (ih *InventoryHandler) PostWaste(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	var waste models.WasteRecord
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		ih.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+
+	err = json.Unmarshal(data, &waste)
+	if err != nil {
+		ih.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	transaction, err := ih.service.RecordWaste(ctx, id, &waste)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrIdNotFound):
+			ih.handleError(w, r, http.StatusNotFound, "ID not found", err)
+		case errors.Is(err, utils.ErrInvalidQuantity), errors.Is(err, utils.ErrInvalidWasteReason):
+			ih.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+		case errors.Is(err, utils.ErrInsufficientStock):
+			ih.handleError(w, r, http.StatusConflict, "Not enough stock to write off", err)
+		default:
+			ih.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		}
+		return
+	}
+	ih.logger.Info("Inventory written off",
+		slog.String("id", id),
+		slog.String("transaction_id", string(transaction.InventoryTransactionId)),
+		slog.String("reason", string(waste.Reason)),
+		slog.Float64("quantity", float64(waste.Quantity)),
+		slog.String("url", r.URL.Path),
+	)
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusCreated,
+		Message: "Inventory write-off recorded successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (ih *InventoryHandler) PostBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	var batch models.InventoryBatch
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		ih.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+
+	err = json.Unmarshal(data, &batch)
+	if err != nil {
+		ih.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+	batch.IngredientId = utils.TEXT(id)
+
+	created, err := ih.service.CreateBatch(ctx, &batch)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrIdNotFound):
+			ih.handleError(w, r, http.StatusNotFound, "ID not found", err)
+		case errors.Is(err, utils.ErrInvalidQuantity):
+			ih.handleError(w, r, http.StatusBadRequest, "Quantity must be greater than zero", err)
+		default:
+			ih.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		}
+		return
+	}
+	ih.logger.Info("Inventory batch received",
+		slog.String("id", id),
+		slog.String("batch_id", string(created.BatchId)),
+		slog.Float64("quantity", float64(created.QuantityReceived)),
+		slog.String("url", r.URL.Path),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (ih *InventoryHandler) GetBatches(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	batches, err := ih.service.GetBatches(ctx, id)
+	if err != nil {
+		ih.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batches)
+}
+
+func (ih *InventoryHandler) GetExpiring(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	batches, err := ih.service.GetExpiring(ctx, r.URL.Query().Get("within"))
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidWithin) {
+			ih.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+			return
+		}
+		ih.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batches)
+}
+
+func (ih *InventoryHandler) PostWriteOffExpired(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	batches, err := ih.service.WriteOffExpired(ctx)
+	if err != nil {
+		ih.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+	ih.logger.Info("Expired batches written off",
+		slog.Int("count", len(batches)),
+		slog.String("url", r.URL.Path),
+	)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batches)
+}
+
 func (ih *InventoryHandler) GETLeftOvers(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -222,3 +423,39 @@ func (ih *InventoryHandler) GETLeftOvers(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(leftOvers)
 }
+
+func (ih *InventoryHandler) GetForecast(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	historyDays := 0
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		var err error
+		historyDays, err = strconv.Atoi(daysStr)
+		if err != nil || historyDays < 1 {
+			ih.handleError(w, r, http.StatusBadRequest, "Invalid days value", err)
+			return
+		}
+	}
+
+	leadTimeDays := -1
+	if leadTimeStr := r.URL.Query().Get("leadTime"); leadTimeStr != "" {
+		var err error
+		leadTimeDays, err = strconv.Atoi(leadTimeStr)
+		if err != nil || leadTimeDays < 0 {
+			ih.handleError(w, r, http.StatusBadRequest, "Invalid leadTime value", err)
+			return
+		}
+	}
+
+	forecast, err := ih.service.GetForecast(ctx, historyDays, leadTimeDays)
+	if err != nil {
+		ih.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+	ih.logger.Info("Built inventory forecast",
+		slog.Int("count", len(forecast.Items)),
+		slog.String("url", r.URL.Path),
+	)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(forecast)
+}
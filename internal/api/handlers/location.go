@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"frappuccino/internal/services"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+type LocationHandler struct {
+	service services.LocationServiceIfc
+	*BaseHandler
+}
+
+func NewLocationHandler(service services.LocationServiceIfc, baseHandler *BaseHandler) *LocationHandler {
+	return &LocationHandler{service: service, BaseHandler: baseHandler}
+}
+
+func (lh *LocationHandler) Post(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var newLocation models.Location
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		lh.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if err := json.Unmarshal(data, &newLocation); err != nil {
+		lh.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	created, err := lh.service.Create(ctx, &newLocation)
+	if err != nil {
+		lh.handleLocationError(w, r, err)
+		return
+	}
+	lh.logger.Info("New location added successfully", slog.String("location_id", string(created.LocationId)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (lh *LocationHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	locations, err := lh.service.GetAll(ctx)
+	if err != nil {
+		lh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(locations)
+}
+
+func (lh *LocationHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	location, err := lh.service.GetByID(ctx, id)
+	if err != nil {
+		lh.handleLocationError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(location)
+}
+
+func (lh *LocationHandler) Put(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var location models.Location
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		lh.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if err := json.Unmarshal(data, &location); err != nil {
+		lh.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	location.LocationId = utils.TEXT(r.PathValue("id"))
+	if err := lh.service.UpdateByID(ctx, &location); err != nil {
+		lh.handleLocationError(w, r, err)
+		return
+	}
+	lh.logger.Info("Location updated", slog.String("location_id", string(location.LocationId)))
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Location updated successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (lh *LocationHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	if err := lh.service.DeleteByID(ctx, id); err != nil {
+		lh.handleLocationError(w, r, err)
+		return
+	}
+	lh.logger.Info("Location deleted", slog.String("location_id", id))
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Location deleted successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (lh *LocationHandler) GetPrices(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	prices, err := lh.service.GetMenuPrices(ctx, id)
+	if err != nil {
+		lh.handleLocationError(w, r, err)
+		return
+	}
+	if prices == nil {
+		prices = []models.LocationMenuPrice{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prices)
+}
+
+func (lh *LocationHandler) PutPrice(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	price := models.LocationMenuPrice{IsAvailable: true}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		lh.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if err := json.Unmarshal(data, &price); err != nil {
+		lh.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	price.LocationId = utils.TEXT(r.PathValue("id"))
+	price.MenuItemId = utils.TEXT(r.PathValue("menuItemId"))
+	if err := lh.service.SetMenuPrice(ctx, &price); err != nil {
+		lh.handleLocationError(w, r, err)
+		return
+	}
+	lh.logger.Info("Location menu price set",
+		slog.String("location_id", string(price.LocationId)),
+		slog.String("menu_item_id", string(price.MenuItemId)),
+	)
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Menu price updated successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (lh *LocationHandler) DeletePrice(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	menuItemId := r.PathValue("menuItemId")
+	if err := lh.service.DeleteMenuPrice(ctx, id, menuItemId); err != nil {
+		lh.handleLocationError(w, r, err)
+		return
+	}
+	lh.logger.Info("Location menu price reset",
+		slog.String("location_id", id),
+		slog.String("menu_item_id", menuItemId),
+	)
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Menu price reset to the base price",
+	}
+	successResponse.Send(w)
+}
+
+func (lh *LocationHandler) handleLocationError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, utils.ErrIdNotFound):
+		lh.handleError(w, r, http.StatusNotFound, "ID not found", err)
+	case errors.Is(err, utils.ErrConflictFields):
+		lh.handleError(w, r, http.StatusConflict, "Location name already exists", err)
+	case errors.Is(err, utils.ErrLocationInUse):
+		lh.handleError(w, r, http.StatusConflict, utils.TEXT(err.Error()), err)
+	case errors.Is(err, utils.ErrInvalidLocationName),
+		errors.Is(err, utils.ErrInvalidTimezone),
+		errors.Is(err, utils.ErrInvalidPrice):
+		lh.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+	default:
+		lh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+	}
+}
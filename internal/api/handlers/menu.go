@@ -9,6 +9,8 @@ import (
 	"frappuccino/utils"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type MenuHandler struct {
@@ -37,14 +39,18 @@ func (mh *MenuHandler) Post(w http.ResponseWriter, r *http.Request) {
 	if newMenuItem.MenuItemId != "" {
 		newMenuItem.MenuItemId = ""
 	}
-	err = mh.service.Create(ctx, &newMenuItem)
+	created, err := mh.service.Create(ctx, &newMenuItem)
 	if err != nil {
+		if errors.Is(err, utils.ErrInvalidStationId) || errors.Is(err, utils.ErrUnknownIngredient) {
+			mh.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+			return
+		}
 		mh.handleError(w, r, http.StatusInternalServerError, "Failed to add menu item", err)
 		return
 	}
 	mh.logger.Info(
 		"Successfully added a new Menu Item",
-		"name", newMenuItem.ItemName,
+		"name", created.ItemName,
 		"URL", r.URL.Path)
 
 	successResponse := utils.APIResponse{
@@ -72,19 +78,23 @@ func (mh *MenuHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	menuItem, err := mh.service.GetByID(ctx, id)
 	if err != nil {
-		if errors.Is(err, utils.ErrIDNotFound) {
+		if errors.Is(err, utils.ErrIdNotFound) {
 			mh.handleError(w, r, http.StatusNotFound, "ID not found", err)
 			return
 		}
 		mh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
 		return
 	}
+	setETag(w, menuItem.UpdatedAt)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(menuItem)
 }
 
 func (mh *MenuHandler) Put(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, ok := mh.requireIfMatch(w, r)
+	if !ok {
+		return
+	}
 
 	id := r.PathValue("id")
 	data, err := io.ReadAll(r.Body)
@@ -98,12 +108,21 @@ func (mh *MenuHandler) Put(w http.ResponseWriter, r *http.Request) {
 		mh.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
 		return
 	}
+	newMenuItem.MenuItemId = utils.TEXT(id)
 	err = mh.service.UpdateByID(ctx, &newMenuItem)
 	if err != nil {
 		if errors.Is(err, utils.ErrIdNotFound) {
 			mh.handleError(w, r, http.StatusNotFound, "ID not found", err)
 			return
 		}
+		if errors.Is(err, utils.ErrPreconditionFailed) {
+			mh.handleError(w, r, http.StatusPreconditionFailed, utils.TEXT(err.Error()), err)
+			return
+		}
+		if errors.Is(err, utils.ErrInvalidStationId) {
+			mh.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+			return
+		}
 		mh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
 		return
 	}
@@ -119,8 +138,36 @@ func (mh *MenuHandler) Put(w http.ResponseWriter, r *http.Request) {
 	successResponse.Send(w)
 }
 
+// Patch applies a JSON merge patch; like Put it requires If-Match.
+func (mh *MenuHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := mh.requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+	patch, ok := mh.readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.PathValue("id")
+	menuItem, err := mh.service.Patch(ctx, id, patch)
+	if err != nil {
+		mh.handlePatchError(w, r, err)
+		return
+	}
+	mh.logger.Info("Menu Item patched successfully",
+		"id", id,
+		"url", r.URL.Path)
+	setETag(w, menuItem.UpdatedAt)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(menuItem)
+}
+
 func (mh *MenuHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, ok := mh.requireIfMatch(w, r)
+	if !ok {
+		return
+	}
 
 	id := r.PathValue("id")
 	err := mh.service.DeleteByID(ctx, id)
@@ -129,6 +176,10 @@ func (mh *MenuHandler) Delete(w http.ResponseWriter, r *http.Request) {
 			mh.handleError(w, r, http.StatusNotFound, "ID not found", err)
 			return
 		}
+		if errors.Is(err, utils.ErrPreconditionFailed) {
+			mh.handleError(w, r, http.StatusPreconditionFailed, utils.TEXT(err.Error()), err)
+			return
+		}
 		mh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
 		return
 	}
@@ -141,3 +192,58 @@ func (mh *MenuHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 	successResponse.Send(w)
 }
+
+func (mh *MenuHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	err := mh.service.Restore(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrIdNotFound):
+			mh.handleError(w, r, http.StatusNotFound, "ID not found", err)
+		case errors.Is(err, utils.ErrNotArchived):
+			mh.handleError(w, r, http.StatusConflict, utils.TEXT(err.Error()), err)
+		default:
+			mh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		}
+		return
+	}
+	mh.logger.Info("Menu Item restored successfully",
+		"id", id,
+		"url", r.URL.Path)
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Menu Item restored successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (mh *MenuHandler) GetSuggestions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len(q) == 0 {
+		mh.handleError(w, r, http.StatusBadRequest, "Query parameter 'q' is required", nil)
+		return
+	}
+
+	var limit int
+	limitString := r.URL.Query().Get("limit")
+	if len(limitString) != 0 {
+		var err error
+		limit, err = strconv.Atoi(limitString)
+		if err != nil || limit < 1 {
+			mh.handleError(w, r, http.StatusBadRequest, "Invalid limit value", err)
+			return
+		}
+	}
+
+	suggestions, err := mh.service.Suggest(ctx, q, limit)
+	if err != nil {
+		mh.handleError(w, r, http.StatusInternalServerError, "Failed to fetch suggestions", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
@@ -40,7 +40,7 @@ func (o *OrderHandler) validateOrder(order models.Orders) error {
 func (o *OrderHandler) Post(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	var newOrder models.OrderItems
+	var newOrder models.Orders
 	data, err := io.ReadAll(r.Body)
 	if err != nil {
 		o.handleError(w, r, http.StatusBadRequest, "Invalid request body", err)
@@ -51,30 +51,25 @@ func (o *OrderHandler) Post(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err := o.validateOrder(newOrder); err != nil {
-		o.handleError(w, r, http.StatusBadRequest, "invalid order data", err)
+		o.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
 		return
 	}
-	if err = o.service.AddOrder(newOrder); err != nil {
-		if errors.Is(err, utils.ErrMenuItem) {
-			o.handleError(w, r, http.StatusBadRequest, "Menu Item does not exist", err)
-			return
-		}
-		o.handleError(w, r, http.StatusInternalServerError, "Failed to add order", err)
+	created, err := o.service.Create(ctx, &newOrder)
+	if err != nil {
+		o.handleCreateError(w, r, err)
 		return
 	}
-	o.logger.Info("New order is added successfully!", slog.String("users phone number", newOrder.PhoneNumber))
+	o.logger.Info("New order is added successfully!", slog.String("order_id", string(created.OrderId)))
 
-	successResponse := utils.APIResponse{
-		Code:    http.StatusCreated,
-		Message: "Order created successfully",
-	}
-	successResponse.Send(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
 }
 
 func (o *OrderHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	orders, err := o.service.GetAllOrders()
+	orders, err := o.service.GetAll(ctx)
 	if err != nil {
 		o.handleError(w, r, http.StatusInternalServerError, "Failed to get orders", err)
 		return
@@ -89,19 +84,23 @@ func (o *OrderHandler) Get(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	id := r.PathValue("id")
-	order, err := o.service.UpdateByID(ctx, id)
+	order, err := o.service.GetByID(ctx, id)
 	if err != nil {
 		o.handleError(w, r, http.StatusNotFound, "Order not found", err)
 		return
 	}
-	o.logger.Info("Order has been successfully taken", slog.String("Phone number", order.PhoneNumber))
+	o.logger.Info("Order has been successfully taken", slog.String("order_id", string(order.OrderId)))
 
+	setETag(w, order.UpdatedAt)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(order)
 }
 
 func (o *OrderHandler) Put(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, ok := o.requireIfMatch(w, r)
+	if !ok {
+		return
+	}
 
 	var orderChanges models.Orders
 	id := r.PathValue("id")
@@ -118,6 +117,22 @@ func (o *OrderHandler) Put(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err = o.service.UpdateByID(ctx, &orderChanges); err != nil {
+		if errors.Is(err, utils.ErrIdNotFound) {
+			o.handleError(w, r, http.StatusNotFound, "ID not found", err)
+			return
+		}
+		if errors.Is(err, utils.ErrPreconditionFailed) {
+			o.handleError(w, r, http.StatusPreconditionFailed, utils.TEXT(err.Error()), err)
+			return
+		}
+		if errors.Is(err, utils.ErrOrderUnpaid) || errors.Is(err, utils.ErrOrderFinal) {
+			o.handleError(w, r, http.StatusConflict, utils.TEXT(err.Error()), err)
+			return
+		}
+		if errors.Is(err, utils.ErrInvalidStatus) || errors.Is(err, utils.ErrStatusNotSettable) {
+			o.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+			return
+		}
 		o.handleError(w, r, http.StatusInternalServerError, "Failed to update order", err)
 		return
 	}
@@ -129,11 +144,45 @@ func (o *OrderHandler) Put(w http.ResponseWriter, r *http.Request) {
 	successResponse.Send(w)
 }
 
+// Patch applies a JSON merge patch; like Put it requires If-Match.
+func (o *OrderHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := o.requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+	patch, ok := o.readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.PathValue("id")
+	order, err := o.service.Patch(ctx, id, patch)
+	if err != nil {
+		o.handlePatchError(w, r, err)
+		return
+	}
+	o.logger.Info("Order patched successfully", slog.String("id", id))
+	setETag(w, order.UpdatedAt)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
 func (o *OrderHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, ok := o.requireIfMatch(w, r)
+	if !ok {
+		return
+	}
 
 	id := r.PathValue("id")
 	if err := o.service.DeleteByID(ctx, id); err != nil {
+		if errors.Is(err, utils.ErrIdNotFound) {
+			o.handleError(w, r, http.StatusNotFound, "ID not found", err)
+			return
+		}
+		if errors.Is(err, utils.ErrPreconditionFailed) {
+			o.handleError(w, r, http.StatusPreconditionFailed, utils.TEXT(err.Error()), err)
+			return
+		}
 		o.handleError(w, r, http.StatusInternalServerError, "Failed to delete order", err)
 		return
 	}
@@ -145,12 +194,42 @@ func (o *OrderHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	successResponse.Send(w)
 }
 
-func (o *OrderHandler) PostCloseOrderById(w http.ResponseWriter, r *http.Request) {
+func (o *OrderHandler) Restore(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	id := r.PathValue("id")
-	if err := o.service.CloseOrderById(id); err != nil {
-		o.handleError(w, r, http.StatusInternalServerError, "Failed to close order", err)
+	if err := o.service.Restore(ctx, id); err != nil {
+		switch {
+		case errors.Is(err, utils.ErrIdNotFound):
+			o.handleError(w, r, http.StatusNotFound, "ID not found", err)
+		case errors.Is(err, utils.ErrNotArchived):
+			o.handleError(w, r, http.StatusConflict, utils.TEXT(err.Error()), err)
+		default:
+			o.handleError(w, r, http.StatusInternalServerError, "Failed to restore order", err)
+		}
+		return
+	}
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Order restored successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (o *OrderHandler) PostClose(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	if _, err := o.service.Close(ctx, id); err != nil {
+		switch {
+		case errors.Is(err, utils.ErrIdNotFound):
+			o.handleError(w, r, http.StatusNotFound, "ID not found", err)
+		case errors.Is(err, utils.ErrOrderClosed), errors.Is(err, utils.ErrOrderUnpaid):
+			o.handleError(w, r, http.StatusConflict, utils.TEXT(err.Error()), err)
+		default:
+			o.handleError(w, r, http.StatusInternalServerError, "Failed to close order", err)
+		}
 		return
 	}
 
@@ -161,6 +240,8 @@ func (o *OrderHandler) PostCloseOrderById(w http.ResponseWriter, r *http.Request
 	successResponse.Send(w)
 }
 
+// BatchProcess creates several orders at once; either all of them are
+// created or none.
 func (o *OrderHandler) BatchProcess(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -175,17 +256,45 @@ func (o *OrderHandler) BatchProcess(w http.ResponseWriter, r *http.Request) {
 		o.handleError(w, r, http.StatusBadRequest, "Failed to parse JSON", err)
 		return
 	}
-	if err := o.service.BatchProcess(ordersArray); err != nil {
-		o.handleError(w, r, http.StatusInternalServerError, "Failed to process batch orders", err)
+	for i, order := range ordersArray {
+		if err := o.validateOrder(order); err != nil {
+			o.handleError(w, r, http.StatusBadRequest, utils.TEXT(fmt.Sprintf("order %d: %s", i+1, err)), err)
+			return
+		}
+	}
+	created, err := o.service.CreateBatch(ctx, ordersArray)
+	if err != nil {
+		o.handleCreateError(w, r, err)
 		return
 	}
 
-	o.logger.Info("New orders are added successfully!")
-	successResponse := utils.APIResponse{
-		Code:    http.StatusCreated,
-		Message: "Orders created successfully",
+	o.logger.Info("New orders are added successfully!", slog.Int("count", len(created)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// handleCreateError maps the errors of creating orders; messages name the
+// order of a batch that failed.
+func (o *OrderHandler) handleCreateError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, utils.ErrMenuItem),
+		errors.Is(err, utils.ErrInvalidCustomerId),
+		errors.Is(err, utils.ErrInvalidStaffId),
+		errors.Is(err, utils.ErrNewOrderStatus),
+		errors.Is(err, utils.ErrInvalidPaymentMethod),
+		errors.Is(err, utils.ErrInvalidPaymentAmount),
+		errors.Is(err, utils.ErrInvalidTip),
+		errors.Is(err, utils.ErrInvalidQuantity),
+		errors.Is(err, utils.ErrEmptyBatch):
+		o.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+	case errors.Is(err, utils.ErrInsufficientStock),
+		errors.Is(err, utils.ErrOverpayment),
+		errors.Is(err, utils.ErrOrderUnpaid):
+		o.handleError(w, r, http.StatusConflict, utils.TEXT(err.Error()), err)
+	default:
+		o.handleError(w, r, http.StatusInternalServerError, "Failed to add order", err)
 	}
-	successResponse.Send(w)
 }
 
 func (orderHandler *OrderHandler) NumberOfOrderedItems(w http.ResponseWriter, r *http.Request) {
@@ -194,11 +303,15 @@ func (orderHandler *OrderHandler) NumberOfOrderedItems(w http.ResponseWriter, r
 	startDate := r.URL.Query().Get("startDate")
 	endDate := r.URL.Query().Get("endDate")
 
-	result, err := orderHandler.service.NumberOfOrderedItems(startDate, endDate)
+	result, err := orderHandler.service.NumberOfOrderedItems(ctx, startDate, endDate)
 	if err != nil {
-		fmt.Println(err.Error())
+		if errors.Is(err, utils.ErrInvalidDateRange) {
+			orderHandler.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+			return
+		}
+		orderHandler.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
 		return
 	}
-	w.Header().Set("Content-Type", "apoplication/json")
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"frappuccino/internal/services"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// orderStreamHeartbeat keeps idle connections open through proxies.
+const orderStreamHeartbeat = 15 * time.Second
+
+type OrderStreamHandler struct {
+	service services.OrderStreamServiceIfc
+	*BaseHandler
+}
+
+func NewOrderStreamHandler(service services.OrderStreamServiceIfc, baseHandler *BaseHandler) *OrderStreamHandler {
+	return &OrderStreamHandler{
+		service:     service,
+		BaseHandler: baseHandler,
+	}
+}
+
+// Stream sends order events as server-sent events. ?status=PENDING,READY
+// keeps only events leaving an order in one of the statuses; a reconnecting
+// client gets the events after its Last-Event-ID first. When it missed too
+// many it gets a stream.reset event instead and should reload the orders.
+func (sh *OrderStreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		sh.handleError(w, r, http.StatusInternalServerError, "Streaming is not supported", nil)
+		return
+	}
+
+	var statuses []string
+	if status := r.URL.Query().Get("status"); status != "" {
+		statuses = strings.Split(status, ",")
+	}
+	var lastEventId int64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		var err error
+		lastEventId, err = strconv.ParseInt(header, 10, 64)
+		if err != nil {
+			sh.handleError(w, r, http.StatusBadRequest, utils.TEXT(utils.ErrInvalidEventId.Error()), err)
+			return
+		}
+	}
+
+	subscription, err := sh.service.Subscribe(ctx, statuses, lastEventId)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrInvalidStatus), errors.Is(err, utils.ErrInvalidEventId):
+			sh.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+		default:
+			sh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		}
+		return
+	}
+	sh.logger.Info("Order stream opened",
+		slog.String("status", r.URL.Query().Get("status")),
+		slog.Int64("last_event_id", lastEventId),
+	)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	if subscription.ResetId != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: stream.reset\ndata: {}\n\n", subscription.ResetId); err != nil {
+			return
+		}
+	}
+	replayed := make(map[int64]bool, len(subscription.Replay))
+	for _, event := range subscription.Replay {
+		if err := writeOrderEvent(w, event); err != nil {
+			return
+		}
+		replayed[event.EventId] = true
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(orderStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+			// Already sent as part of the replay
+			if replayed[event.EventId] {
+				continue
+			}
+			if err := writeOrderEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeOrderEvent(w http.ResponseWriter, event models.OrderEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.EventId, event.EventType, data)
+	return err
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"frappuccino/internal/services"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+type PaymentHandler struct {
+	service services.PaymentServiceIfc
+	*BaseHandler
+}
+
+func NewPaymentHandler(service services.PaymentServiceIfc, baseHandler *BaseHandler) *PaymentHandler {
+	return &PaymentHandler{service: service, BaseHandler: baseHandler}
+}
+
+// Post accepts a list of payments so a bill can be split in one request.
+func (ph *PaymentHandler) Post(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var payments []models.Payment
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		ph.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if err := json.Unmarshal(data, &payments); err != nil {
+		ph.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	id := r.PathValue("id")
+	summary, err := ph.service.AddPayments(ctx, id, payments)
+	if err != nil {
+		ph.handlePaymentError(w, r, err)
+		return
+	}
+	ph.logger.Info("Payments recorded", slog.String("order_id", id), slog.Int("count", len(payments)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(summary)
+}
+
+func (ph *PaymentHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	summary, err := ph.service.GetByOrderID(ctx, id)
+	if err != nil {
+		ph.handlePaymentError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+func (ph *PaymentHandler) handlePaymentError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, utils.ErrIdNotFound):
+		ph.handleError(w, r, http.StatusNotFound, "ID not found", err)
+	case errors.Is(err, utils.ErrOverpayment),
+		errors.Is(err, utils.ErrOrderNotPayable):
+		ph.handleError(w, r, http.StatusConflict, utils.TEXT(err.Error()), err)
+	case errors.Is(err, utils.ErrEmptyPayment),
+		errors.Is(err, utils.ErrInvalidPaymentMethod),
+		errors.Is(err, utils.ErrInvalidPaymentAmount),
+		errors.Is(err, utils.ErrInvalidTip):
+		ph.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+	default:
+		ph.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"frappuccino/internal/services"
+	"frappuccino/utils"
+	"net/http"
+	"strings"
+)
+
+type ReceiptHandler struct {
+	service services.ReceiptServiceIfc
+	*BaseHandler
+}
+
+func NewReceiptHandler(service services.ReceiptServiceIfc, baseHandler *BaseHandler) *ReceiptHandler {
+	return &ReceiptHandler{service: service, BaseHandler: baseHandler}
+}
+
+// Get renders the receipt of an order; ?format= is txt (the default), html
+// or pdf.
+func (rh *ReceiptHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	format := r.URL.Query().Get("format")
+	receipt, contentType, err := rh.service.Render(ctx, id, format)
+	if err != nil {
+		rh.handleReceiptError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if strings.EqualFold(format, "pdf") {
+		w.Header().Set("Content-Disposition", `inline; filename="receipt-`+id+`.pdf"`)
+	}
+	w.Write(receipt)
+}
+
+func (rh *ReceiptHandler) handleReceiptError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, utils.ErrIdNotFound):
+		rh.handleError(w, r, http.StatusNotFound, "ID not found", err)
+	case errors.Is(err, utils.ErrInvalidReceiptFormat):
+		rh.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+	default:
+		rh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+	}
+}
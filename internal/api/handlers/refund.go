@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"frappuccino/internal/services"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+type RefundHandler struct {
+	service services.RefundServiceIfc
+	*BaseHandler
+}
+
+func NewRefundHandler(service services.RefundServiceIfc, baseHandler *BaseHandler) *RefundHandler {
+	return &RefundHandler{service: service, BaseHandler: baseHandler}
+}
+
+func (rh *RefundHandler) Post(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var request models.RefundRequest
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		rh.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if err := json.Unmarshal(data, &request); err != nil {
+		rh.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	id := r.PathValue("id")
+	refund, err := rh.service.Create(ctx, id, &request)
+	if err != nil {
+		rh.handleRefundError(w, r, err)
+		return
+	}
+	rh.logger.Info("Order refunded", slog.String("order_id", id), slog.String("refund_id", string(refund.RefundId)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(refund)
+}
+
+func (rh *RefundHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	refunds, err := rh.service.GetByOrderID(ctx, id)
+	if err != nil {
+		rh.handleRefundError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(refunds)
+}
+
+func (rh *RefundHandler) handleRefundError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, utils.ErrIdNotFound):
+		rh.handleError(w, r, http.StatusNotFound, "ID not found", err)
+	case errors.Is(err, utils.ErrRefundState),
+		errors.Is(err, utils.ErrRefundQuantity):
+		rh.handleError(w, r, http.StatusConflict, utils.TEXT(err.Error()), err)
+	case errors.Is(err, utils.ErrMissingRefundReason),
+		errors.Is(err, utils.ErrRefundItemNotFound),
+		errors.Is(err, utils.ErrInvalidQuantity):
+		rh.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+	default:
+		rh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+	}
+}
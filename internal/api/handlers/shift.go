@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"frappuccino/internal/services"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+type ShiftHandler struct {
+	service services.ShiftServiceIfc
+	*BaseHandler
+}
+
+func NewShiftHandler(service services.ShiftServiceIfc, baseHandler *BaseHandler) *ShiftHandler {
+	return &ShiftHandler{service: service, BaseHandler: baseHandler}
+}
+
+func (sh *ShiftHandler) Post(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var newShift models.Shift
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		sh.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if err := json.Unmarshal(data, &newShift); err != nil {
+		sh.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	created, err := sh.service.Create(ctx, &newShift)
+	if err != nil {
+		sh.handleShiftError(w, r, err)
+		return
+	}
+	sh.logger.Info("Shift scheduled", slog.String("shift_id", string(created.ShiftId)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (sh *ShiftHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	shifts, err := sh.service.GetAll(ctx, query.Get("staff_id"), query.Get("from"), query.Get("to"))
+	if err != nil {
+		sh.handleShiftError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shifts)
+}
+
+func (sh *ShiftHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	shift, err := sh.service.GetByID(ctx, id)
+	if err != nil {
+		sh.handleShiftError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shift)
+}
+
+func (sh *ShiftHandler) Put(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var shift models.Shift
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		sh.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if err := json.Unmarshal(data, &shift); err != nil {
+		sh.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	shift.ShiftId = utils.TEXT(r.PathValue("id"))
+	if err := sh.service.UpdateByID(ctx, &shift); err != nil {
+		sh.handleShiftError(w, r, err)
+		return
+	}
+	sh.logger.Info("Shift rescheduled", slog.String("shift_id", string(shift.ShiftId)))
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Shift updated successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (sh *ShiftHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	if err := sh.service.DeleteByID(ctx, id); err != nil {
+		sh.handleShiftError(w, r, err)
+		return
+	}
+	sh.logger.Info("Shift deleted", slog.String("shift_id", id))
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Shift deleted successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (sh *ShiftHandler) PostClockIn(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	shift, err := sh.service.ClockIn(ctx, id)
+	if err != nil {
+		sh.handleShiftError(w, r, err)
+		return
+	}
+	sh.logger.Info("Clocked in", slog.String("shift_id", id), slog.String("staff_id", string(shift.StaffId)))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shift)
+}
+
+func (sh *ShiftHandler) PostClockOut(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	shift, err := sh.service.ClockOut(ctx, id)
+	if err != nil {
+		sh.handleShiftError(w, r, err)
+		return
+	}
+	sh.logger.Info("Clocked out", slog.String("shift_id", id), slog.String("staff_id", string(shift.StaffId)))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shift)
+}
+
+func (sh *ShiftHandler) handleShiftError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, utils.ErrIdNotFound):
+		sh.handleError(w, r, http.StatusNotFound, "ID not found", err)
+	case errors.Is(err, utils.ErrUnauthorized):
+		sh.handleError(w, r, http.StatusUnauthorized, utils.TEXT(err.Error()), err)
+	case errors.Is(err, utils.ErrForbidden):
+		sh.handleError(w, r, http.StatusForbidden, utils.TEXT(err.Error()), err)
+	case errors.Is(err, utils.ErrShiftOverlap),
+		errors.Is(err, utils.ErrShiftState),
+		errors.Is(err, utils.ErrClockInWindow):
+		sh.handleError(w, r, http.StatusConflict, utils.TEXT(err.Error()), err)
+	case errors.Is(err, utils.ErrMissingStaff),
+		errors.Is(err, utils.ErrInvalidStaffId),
+		errors.Is(err, utils.ErrInvalidShiftTime),
+		errors.Is(err, utils.ErrInvalidDateRange):
+		sh.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+	default:
+		sh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"frappuccino/internal/services"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+type StaffHandler struct {
+	service services.StaffServiceIfc
+	*BaseHandler
+}
+
+func NewStaffHandler(service services.StaffServiceIfc, baseHandler *BaseHandler) *StaffHandler {
+	return &StaffHandler{service: service, BaseHandler: baseHandler}
+}
+
+func (sh *StaffHandler) Post(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var newStaff models.Staff
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		sh.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if err := json.Unmarshal(data, &newStaff); err != nil {
+		sh.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	created, err := sh.service.Create(ctx, &newStaff)
+	if err != nil {
+		sh.handleStaffError(w, r, err)
+		return
+	}
+	sh.logger.Info("Staff account created", slog.String("staff_id", string(created.StaffId)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (sh *StaffHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	staffList, err := sh.service.GetAll(ctx)
+	if err != nil {
+		sh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+	if staffList == nil {
+		staffList = []models.Staff{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(staffList)
+}
+
+func (sh *StaffHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	staff, err := sh.service.GetByID(ctx, id)
+	if err != nil {
+		sh.handleStaffError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(staff)
+}
+
+func (sh *StaffHandler) Put(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var staff models.Staff
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		sh.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if err := json.Unmarshal(data, &staff); err != nil {
+		sh.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	staff.StaffId = utils.TEXT(r.PathValue("id"))
+	if err := sh.service.UpdateByID(ctx, &staff); err != nil {
+		sh.handleStaffError(w, r, err)
+		return
+	}
+	sh.logger.Info("Staff account updated", slog.String("staff_id", string(staff.StaffId)))
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Staff account updated successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (sh *StaffHandler) handleStaffError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, utils.ErrIdNotFound):
+		sh.handleError(w, r, http.StatusNotFound, "ID not found", err)
+	case errors.Is(err, utils.ErrConflictFields):
+		sh.handleError(w, r, http.StatusConflict, "Username already exists", err)
+	case errors.Is(err, utils.ErrInvalidUsername),
+		errors.Is(err, utils.ErrInvalidRole),
+		errors.Is(err, utils.ErrWeakPassword):
+		sh.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+	default:
+		sh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+	}
+}
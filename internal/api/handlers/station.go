@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"frappuccino/internal/services"
+	"frappuccino/utils"
+	"log/slog"
+	"net/http"
+)
+
+type StationHandler struct {
+	service services.StationServiceIfc
+	*BaseHandler
+}
+
+func NewStationHandler(service services.StationServiceIfc, baseHandler *BaseHandler) *StationHandler {
+	return &StationHandler{service: service, BaseHandler: baseHandler}
+}
+
+func (sh *StationHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	stations, err := sh.service.GetAll(ctx)
+	if err != nil {
+		sh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stations)
+}
+
+// GetTickets lists the open tickets of the station at the selected location,
+// oldest first. ?status=BUMPED lists the most recently bumped ones instead.
+func (sh *StationHandler) GetTickets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tickets, err := sh.service.GetTickets(ctx, r.PathValue("id"), r.URL.Query().Get("status"))
+	if err != nil {
+		sh.handleStationError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tickets)
+}
+
+func (sh *StationHandler) PostBump(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ticketId := r.PathValue("ticketId")
+	ticket, err := sh.service.BumpTicket(ctx, r.PathValue("id"), ticketId)
+	if err != nil {
+		sh.handleStationError(w, r, err)
+		return
+	}
+	sh.logger.Info("Ticket bumped", slog.String("ticket_id", ticketId), slog.String("order_id", string(ticket.OrderId)))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ticket)
+}
+
+func (sh *StationHandler) PostBumpItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ticketId := r.PathValue("ticketId")
+	itemId := r.PathValue("itemId")
+	ticket, err := sh.service.BumpItem(ctx, r.PathValue("id"), ticketId, itemId)
+	if err != nil {
+		sh.handleStationError(w, r, err)
+		return
+	}
+	sh.logger.Info("Ticket item bumped", slog.String("ticket_id", ticketId), slog.String("order_item_id", itemId))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ticket)
+}
+
+func (sh *StationHandler) handleStationError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, utils.ErrIdNotFound):
+		sh.handleError(w, r, http.StatusNotFound, "ID not found", err)
+	case errors.Is(err, utils.ErrTicketBumped),
+		errors.Is(err, utils.ErrTicketItemBumped):
+		sh.handleError(w, r, http.StatusConflict, utils.TEXT(err.Error()), err)
+	case errors.Is(err, utils.ErrInvalidTicketStatus):
+		sh.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+	default:
+		sh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"frappuccino/internal/services"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+type StockCountHandler struct {
+	service services.StockCountServiceIfc
+	*BaseHandler
+}
+
+func NewStockCountHandler(service services.StockCountServiceIfc, baseHandler *BaseHandler) *StockCountHandler {
+	return &StockCountHandler{service: service, BaseHandler: baseHandler}
+}
+
+func (sh *StockCountHandler) Post(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var newStockCount models.StockCount
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		sh.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &newStockCount); err != nil {
+			sh.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+			return
+		}
+	}
+
+	created, err := sh.service.Create(ctx, &newStockCount)
+	if err != nil {
+		sh.handleError(w, r, http.StatusInternalServerError, "Unexpected error", err)
+		return
+	}
+	sh.logger.Info("Stock count opened", slog.String("stock_count_id", string(created.StockCountId)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (sh *StockCountHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	stockCounts, err := sh.service.GetAll(ctx)
+	if err != nil {
+		sh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stockCounts)
+}
+
+func (sh *StockCountHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	stockCount, err := sh.service.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, utils.ErrIdNotFound) {
+			sh.handleError(w, r, http.StatusNotFound, "ID not found", err)
+			return
+		}
+		sh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stockCount)
+}
+
+func (sh *StockCountHandler) PostLines(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	var submission models.StockCountSubmission
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		sh.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if err := json.Unmarshal(data, &submission); err != nil {
+		sh.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	if err := sh.service.SubmitLines(ctx, id, &submission); err != nil {
+		sh.handleStockCountError(w, r, err)
+		return
+	}
+	sh.logger.Info("Stock count items submitted",
+		slog.String("stock_count_id", id),
+		slog.Int("count", len(submission.Items)),
+		slog.String("counted_by", string(submission.CountedBy)),
+	)
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Counted quantities recorded successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (sh *StockCountHandler) PostCommit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	if err := sh.service.Commit(ctx, id); err != nil {
+		sh.handleStockCountError(w, r, err)
+		return
+	}
+	sh.logger.Info("Stock count committed", slog.String("stock_count_id", id))
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Stock count committed successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (sh *StockCountHandler) PostCancel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	if err := sh.service.Cancel(ctx, id); err != nil {
+		sh.handleStockCountError(w, r, err)
+		return
+	}
+	sh.logger.Info("Stock count cancelled", slog.String("stock_count_id", id))
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Stock count cancelled successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (sh *StockCountHandler) handleStockCountError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, utils.ErrIdNotFound):
+		sh.handleError(w, r, http.StatusNotFound, "ID not found", err)
+	case errors.Is(err, utils.ErrStockCountClosed):
+		sh.handleError(w, r, http.StatusConflict, "Stock count is no longer open", err)
+	case errors.Is(err, utils.ErrEmptyStockCount),
+		errors.Is(err, utils.ErrInvalidIngredientId),
+		errors.Is(err, utils.ErrInvalidQuantity):
+		sh.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+	default:
+		sh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+	}
+}
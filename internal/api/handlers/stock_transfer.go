@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"frappuccino/internal/services"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+type StockTransferHandler struct {
+	service services.StockTransferServiceIfc
+	*BaseHandler
+}
+
+func NewStockTransferHandler(service services.StockTransferServiceIfc, baseHandler *BaseHandler) *StockTransferHandler {
+	return &StockTransferHandler{service: service, BaseHandler: baseHandler}
+}
+
+func (th *StockTransferHandler) Post(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var newTransfer models.StockTransfer
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		th.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if err := json.Unmarshal(data, &newTransfer); err != nil {
+		th.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	created, err := th.service.Create(ctx, &newTransfer)
+	if err != nil {
+		th.handleStockTransferError(w, r, err)
+		return
+	}
+	th.logger.Info("Stock transfer drafted", slog.String("transfer_id", string(created.TransferId)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (th *StockTransferHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	transfers, err := th.service.GetAll(ctx, r.URL.Query().Get("status"))
+	if err != nil {
+		th.handleStockTransferError(w, r, err)
+		return
+	}
+	if transfers == nil {
+		transfers = []models.StockTransfer{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfers)
+}
+
+func (th *StockTransferHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	transfer, err := th.service.GetByID(ctx, id)
+	if err != nil {
+		th.handleStockTransferError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+func (th *StockTransferHandler) PostShip(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	if err := th.service.Ship(ctx, id); err != nil {
+		th.handleStockTransferError(w, r, err)
+		return
+	}
+	th.logger.Info("Stock transfer shipped", slog.String("transfer_id", id))
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Stock transfer shipped successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (th *StockTransferHandler) PostReceive(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	var receipt models.StockTransferReceipt
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		th.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &receipt); err != nil {
+			th.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+			return
+		}
+	}
+
+	if err := th.service.Receive(ctx, id, &receipt); err != nil {
+		th.handleStockTransferError(w, r, err)
+		return
+	}
+	th.logger.Info("Stock transfer received",
+		slog.String("transfer_id", id),
+		slog.String("received_by", string(receipt.ReceivedBy)),
+	)
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Stock transfer received successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (th *StockTransferHandler) PostCancel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	if err := th.service.Cancel(ctx, id); err != nil {
+		th.handleStockTransferError(w, r, err)
+		return
+	}
+	th.logger.Info("Stock transfer cancelled", slog.String("transfer_id", id))
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Stock transfer cancelled successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (th *StockTransferHandler) handleStockTransferError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, utils.ErrIdNotFound):
+		th.handleError(w, r, http.StatusNotFound, "ID not found", err)
+	case errors.Is(err, utils.ErrTransferState),
+		errors.Is(err, utils.ErrInsufficientStock):
+		th.handleError(w, r, http.StatusConflict, utils.TEXT(err.Error()), err)
+	case errors.Is(err, utils.ErrConflictFields):
+		th.handleError(w, r, http.StatusConflict, "Ingredient listed more than once", err)
+	case errors.Is(err, utils.ErrSameLocation),
+		errors.Is(err, utils.ErrMissingDestination),
+		errors.Is(err, utils.ErrEmptyTransfer),
+		errors.Is(err, utils.ErrTransferItemNotFound),
+		errors.Is(err, utils.ErrInvalidIngredientId),
+		errors.Is(err, utils.ErrInvalidQuantity),
+		errors.Is(err, utils.ErrInvalidStatus):
+		th.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+	default:
+		th.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"frappuccino/internal/services"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type WebhookHandler struct {
+	service services.WebhookServiceIfc
+	*BaseHandler
+}
+
+func NewWebhookHandler(service services.WebhookServiceIfc, baseHandler *BaseHandler) *WebhookHandler {
+	return &WebhookHandler{service: service, BaseHandler: baseHandler}
+}
+
+func (wh *WebhookHandler) Post(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	subscription := models.WebhookSubscription{IsActive: true}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		wh.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if err := json.Unmarshal(data, &subscription); err != nil {
+		wh.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	created, err := wh.service.Create(ctx, &subscription)
+	if err != nil {
+		wh.handleWebhookError(w, r, err)
+		return
+	}
+	wh.logger.Info("Webhook subscription created", slog.String("subscription_id", string(created.SubscriptionId)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (wh *WebhookHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	subscriptions, err := wh.service.GetAll(ctx)
+	if err != nil {
+		wh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+		return
+	}
+	if subscriptions == nil {
+		subscriptions = []models.WebhookSubscription{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscriptions)
+}
+
+func (wh *WebhookHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	subscription, err := wh.service.GetByID(ctx, r.PathValue("id"))
+	if err != nil {
+		wh.handleWebhookError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscription)
+}
+
+func (wh *WebhookHandler) Put(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	subscription := models.WebhookSubscription{IsActive: true}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		wh.handleError(w, r, http.StatusInternalServerError, "Failed to read request body", err)
+		return
+	}
+	if err := json.Unmarshal(data, &subscription); err != nil {
+		wh.handleError(w, r, http.StatusBadRequest, "Invalid JSON format", err)
+		return
+	}
+
+	subscription.SubscriptionId = utils.TEXT(r.PathValue("id"))
+	if err := wh.service.UpdateByID(ctx, &subscription); err != nil {
+		wh.handleWebhookError(w, r, err)
+		return
+	}
+	wh.logger.Info("Webhook subscription updated", slog.String("subscription_id", string(subscription.SubscriptionId)))
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Webhook subscription updated successfully",
+	}
+	successResponse.Send(w)
+}
+
+func (wh *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.PathValue("id")
+	if err := wh.service.DeleteByID(ctx, id); err != nil {
+		wh.handleWebhookError(w, r, err)
+		return
+	}
+	wh.logger.Info("Webhook subscription deleted", slog.String("subscription_id", id))
+
+	successResponse := utils.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Webhook subscription deleted successfully",
+	}
+	successResponse.Send(w)
+}
+
+// GetDeliveries lists the delivery log of a subscription, newest first.
+// ?status= narrows it to PENDING, DELIVERED or FAILED deliveries.
+func (wh *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	var limit int
+	if limitString := query.Get("limit"); len(limitString) != 0 {
+		var err error
+		limit, err = strconv.Atoi(limitString)
+		if err != nil || limit < 1 {
+			wh.handleError(w, r, http.StatusBadRequest, "Invalid limit value", err)
+			return
+		}
+	}
+
+	deliveries, err := wh.service.GetDeliveries(ctx, r.PathValue("id"), query.Get("status"), limit)
+	if err != nil {
+		wh.handleWebhookError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+func (wh *WebhookHandler) handleWebhookError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, utils.ErrIdNotFound):
+		wh.handleError(w, r, http.StatusNotFound, "ID not found", err)
+	case errors.Is(err, utils.ErrInvalidWebhookUrl),
+		errors.Is(err, utils.ErrInvalidWebhookEvents),
+		errors.Is(err, utils.ErrInvalidWebhookSecret),
+		errors.Is(err, utils.ErrInvalidDeliveryState):
+		wh.handleError(w, r, http.StatusBadRequest, utils.TEXT(err.Error()), err)
+	default:
+		wh.handleError(w, r, http.StatusInternalServerError, "Unexpected Error", err)
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"frappuccino/internal/services"
+	"frappuccino/utils"
+	"net/http"
+	"regexp"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// WithLocation reads the store selected by the X-Location-Id header or the
+// location query parameter and puts it in the request context.
+func WithLocation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locationId := r.Header.Get("X-Location-Id")
+		if locationId == "" {
+			locationId = r.URL.Query().Get("location")
+		}
+		if locationId == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !uuidPattern.MatchString(locationId) {
+			sendError(w, r, http.StatusBadRequest, "location must be a valid UUID")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(utils.WithLocation(r.Context(), locationId)))
+	})
+}
+
+// WithAuth authenticates the caller with a bearer token or an X-API-Key
+// header and checks the role the matched route requires. Requests the mux
+// cannot route are passed through so it can answer 404 or 405 itself.
+func WithAuth(mux *http.ServeMux, auth services.AuthServiceIfc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		if pattern == "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		r = r.WithContext(utils.WithRoute(r.Context(), pattern))
+		need := requiredRole(pattern)
+		if need == rolePublic {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		var principal utils.Principal
+		var err error
+		if token := utils.BearerToken(r); token != "" {
+			principal, err = auth.AuthenticateToken(r.Context(), token)
+		} else if key := r.Header.Get("X-API-Key"); key != "" {
+			principal, err = auth.AuthenticateAPIKey(r.Context(), key)
+		} else {
+			err = utils.ErrUnauthorized
+		}
+		if err != nil {
+			if errors.Is(err, utils.ErrUnauthorized) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="frappuccino"`)
+				sendError(w, r, http.StatusUnauthorized, utils.TEXT(err.Error()))
+				return
+			}
+			sendError(w, r, http.StatusInternalServerError, "Unexpected Error")
+			return
+		}
+
+		if !utils.RoleAllows(principal.Role, need) {
+			sendError(w, r, http.StatusForbidden, utils.TEXT(utils.ErrForbidden.Error()))
+			return
+		}
+
+		mux.ServeHTTP(w, r.WithContext(utils.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func sendError(w http.ResponseWriter, r *http.Request, code utils.INT, message utils.TEXT) {
+	response := utils.APIError{
+		Code:     code,
+		Message:  message,
+		Resource: utils.TEXT(r.URL.Path),
+	}
+	response.Send(w)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"frappuccino/utils"
+	"net/http"
+	"strings"
+)
+
+// rolePublic marks routes that can be called without credentials.
+const rolePublic = "PUBLIC"
+
+// routeRoles lists the routes whose role differs from the default: reading
+// needs a barista, changing data needs a manager.
+var routeRoles = map[string]string{
+	"POST /auth/login": rolePublic,
+
+	"POST /auth/logout":                  utils.RoleBarista,
+	"POST /customer":                     utils.RoleBarista,
+	"PUT /customer/{id}":                 utils.RoleBarista,
+	"PATCH /customer/{id}":               utils.RoleBarista,
+	"POST /order":                        utils.RoleBarista,
+	"PUT /order/{id}":                    utils.RoleBarista,
+	"PATCH /order/{id}":                  utils.RoleBarista,
+	"POST /order/{id}/close":             utils.RoleBarista,
+	"POST /order/{id}/payments":          utils.RoleBarista,
+	"POST /inventory/{id}/waste":         utils.RoleBarista,
+	"POST /stock-counts/{id}/lines":      utils.RoleBarista,
+	"POST /stock-transfers/{id}/receive": utils.RoleBarista,
+	"POST /shifts/{id}/clock-in":         utils.RoleBarista,
+	"POST /shifts/{id}/clock-out":        utils.RoleBarista,
+
+	"POST /stations/{id}/tickets/{ticketId}/bump":                utils.RoleBarista,
+	"POST /stations/{id}/tickets/{ticketId}/items/{itemId}/bump": utils.RoleBarista,
+
+	"DELETE /locations/{id}/prices/{menuItemId}": utils.RoleManager,
+	"POST /order/batch-process":                  utils.RoleManager,
+	"GET /reports/total-sales":                   utils.RoleManager,
+	"GET /reports/sales":                         utils.RoleManager,
+	"GET /reports/popular-items":                 utils.RoleManager,
+	"GET /reports/heatmap":                       utils.RoleManager,
+	"GET /reports/tips":                          utils.RoleManager,
+	"GET /reports/prep-times":                    utils.RoleManager,
+	"GET /reports/inventory-variance":            utils.RoleManager,
+	"GET /reports/orderedItemsNyPeriod":          utils.RoleManager,
+
+	"GET /audit":             utils.RoleAdmin,
+	"POST /api-keys":         utils.RoleAdmin,
+	"GET /api-keys":          utils.RoleAdmin,
+	"DELETE /api-keys/{id}":  utils.RoleAdmin,
+	"POST /staff":            utils.RoleAdmin,
+	"GET /staff":             utils.RoleAdmin,
+	"GET /staff/{id}":        utils.RoleAdmin,
+	"PUT /staff/{id}":        utils.RoleAdmin,
+	"POST /locations":        utils.RoleAdmin,
+	"PUT /locations/{id}":    utils.RoleAdmin,
+	"DELETE /locations/{id}": utils.RoleAdmin,
+	"DELETE /customer/{id}":  utils.RoleAdmin,
+	"DELETE /inventory/{id}": utils.RoleAdmin,
+	"DELETE /menu/{id}":      utils.RoleAdmin,
+	"DELETE /order/{id}":     utils.RoleAdmin,
+
+	"POST /customer/{id}/restore":  utils.RoleAdmin,
+	"POST /inventory/{id}/restore": utils.RoleAdmin,
+	"POST /menu/{id}/restore":      utils.RoleAdmin,
+	"POST /order/{id}/restore":     utils.RoleAdmin,
+
+	"POST /webhooks":                utils.RoleAdmin,
+	"GET /webhooks":                 utils.RoleAdmin,
+	"GET /webhooks/{id}":            utils.RoleAdmin,
+	"PUT /webhooks/{id}":            utils.RoleAdmin,
+	"DELETE /webhooks/{id}":         utils.RoleAdmin,
+	"GET /webhooks/{id}/deliveries": utils.RoleAdmin,
+}
+
+func requiredRole(pattern string) string {
+	if role, ok := routeRoles[pattern]; ok {
+		return role
+	}
+	method, _, _ := strings.Cut(pattern, " ")
+	if method == http.MethodGet {
+		return utils.RoleBarista
+	}
+	return utils.RoleManager
+}
@@ -8,37 +8,121 @@ import (
 func Router(handlers *handlers.Handler) *http.ServeMux {
 	mux := http.NewServeMux()
 
+	mux.HandleFunc("POST /auth/login", handlers.AuthHandler.PostLogin)
+	mux.HandleFunc("POST /auth/logout", handlers.AuthHandler.PostLogout)
+	mux.HandleFunc("GET /auth/me", handlers.AuthHandler.GetMe)
+	mux.HandleFunc("POST /api-keys", handlers.AuthHandler.PostAPIKey)
+	mux.HandleFunc("GET /api-keys", handlers.AuthHandler.GetAPIKeys)
+	mux.HandleFunc("DELETE /api-keys/{id}", handlers.AuthHandler.DeleteAPIKey)
+
+	mux.HandleFunc("POST /staff", handlers.StaffHandler.Post)
+	mux.HandleFunc("GET /staff", handlers.StaffHandler.GetAll)
+	mux.HandleFunc("GET /staff/{id}", handlers.StaffHandler.Get)
+	mux.HandleFunc("PUT /staff/{id}", handlers.StaffHandler.Put)
+
+	mux.HandleFunc("POST /shifts", handlers.ShiftHandler.Post)
+	mux.HandleFunc("GET /shifts", handlers.ShiftHandler.GetAll)
+	mux.HandleFunc("GET /shifts/{id}", handlers.ShiftHandler.Get)
+	mux.HandleFunc("PUT /shifts/{id}", handlers.ShiftHandler.Put)
+	mux.HandleFunc("DELETE /shifts/{id}", handlers.ShiftHandler.Delete)
+	mux.HandleFunc("POST /shifts/{id}/clock-in", handlers.ShiftHandler.PostClockIn)
+	mux.HandleFunc("POST /shifts/{id}/clock-out", handlers.ShiftHandler.PostClockOut)
+
 	mux.HandleFunc("POST /customer", handlers.CustomerHandler.Post)
 	mux.HandleFunc("GET /customer", handlers.CustomerHandler.GetAll)
 	mux.HandleFunc("GET /customer/{id}", handlers.CustomerHandler.Get)
 	mux.HandleFunc("PUT /customer/{id}", handlers.CustomerHandler.Put)
+	mux.HandleFunc("PATCH /customer/{id}", handlers.CustomerHandler.Patch)
 	mux.HandleFunc("DELETE /customer/{id}", handlers.CustomerHandler.Delete)
+	mux.HandleFunc("POST /customer/{id}/restore", handlers.CustomerHandler.Restore)
 
 	mux.HandleFunc("POST /inventory", handlers.InventoryHandler.Post)
 	mux.HandleFunc("GET /inventory", handlers.InventoryHandler.GetAll)
 	mux.HandleFunc("GET /inventory/{id}", handlers.InventoryHandler.Get)
 	mux.HandleFunc("PUT /inventory/{id}", handlers.InventoryHandler.Put)
+	mux.HandleFunc("PATCH /inventory/{id}", handlers.InventoryHandler.Patch)
 	mux.HandleFunc("DELETE /inventory/{id}", handlers.InventoryHandler.Delete)
+	mux.HandleFunc("POST /inventory/{id}/restore", handlers.InventoryHandler.Restore)
+	mux.HandleFunc("POST /inventory/{id}/waste", handlers.InventoryHandler.PostWaste)
+	mux.HandleFunc("POST /inventory/{id}/batches", handlers.InventoryHandler.PostBatch)
+	mux.HandleFunc("GET /inventory/{id}/batches", handlers.InventoryHandler.GetBatches)
+	mux.HandleFunc("GET /inventory/expiring", handlers.InventoryHandler.GetExpiring)
+	mux.HandleFunc("POST /inventory/expired/write-off", handlers.InventoryHandler.PostWriteOffExpired)
 
 	mux.HandleFunc("POST /menu", handlers.MenuHandler.Post)
 	mux.HandleFunc("GET /menu", handlers.MenuHandler.GetAll)
+	mux.HandleFunc("GET /menu/suggest", handlers.MenuHandler.GetSuggestions)
 	mux.HandleFunc("GET /menu/{id}", handlers.MenuHandler.Get)
 	mux.HandleFunc("PUT /menu/{id}", handlers.MenuHandler.Put)
+	mux.HandleFunc("PATCH /menu/{id}", handlers.MenuHandler.Patch)
 	mux.HandleFunc("DELETE /menu/{id}", handlers.MenuHandler.Delete)
+	mux.HandleFunc("POST /menu/{id}/restore", handlers.MenuHandler.Restore)
 
 	mux.HandleFunc("GET /inventory/getLeftOvers/{page}/{pageSize}", handlers.InventoryHandler.GETLeftOvers)
+	mux.HandleFunc("GET /inventory/forecast", handlers.InventoryHandler.GetForecast)
+
+	mux.HandleFunc("POST /stock-counts", handlers.StockCountHandler.Post)
+	mux.HandleFunc("GET /stock-counts", handlers.StockCountHandler.GetAll)
+	mux.HandleFunc("GET /stock-counts/{id}", handlers.StockCountHandler.Get)
+	mux.HandleFunc("POST /stock-counts/{id}/lines", handlers.StockCountHandler.PostLines)
+	mux.HandleFunc("POST /stock-counts/{id}/commit", handlers.StockCountHandler.PostCommit)
+	mux.HandleFunc("POST /stock-counts/{id}/cancel", handlers.StockCountHandler.PostCancel)
+
+	mux.HandleFunc("POST /stock-transfers", handlers.TransferHandler.Post)
+	mux.HandleFunc("GET /stock-transfers", handlers.TransferHandler.GetAll)
+	mux.HandleFunc("GET /stock-transfers/{id}", handlers.TransferHandler.Get)
+	mux.HandleFunc("POST /stock-transfers/{id}/ship", handlers.TransferHandler.PostShip)
+	mux.HandleFunc("POST /stock-transfers/{id}/receive", handlers.TransferHandler.PostReceive)
+	mux.HandleFunc("POST /stock-transfers/{id}/cancel", handlers.TransferHandler.PostCancel)
 
-	mux.HandleFunc("POST /order", handlers.OrderHandler.Post)
+	mux.HandleFunc("POST /locations", handlers.LocationHandler.Post)
+	mux.HandleFunc("GET /locations", handlers.LocationHandler.GetAll)
+	mux.HandleFunc("GET /locations/{id}", handlers.LocationHandler.Get)
+	mux.HandleFunc("PUT /locations/{id}", handlers.LocationHandler.Put)
+	mux.HandleFunc("DELETE /locations/{id}", handlers.LocationHandler.Delete)
+	mux.HandleFunc("GET /locations/{id}/prices", handlers.LocationHandler.GetPrices)
+	mux.HandleFunc("PUT /locations/{id}/prices/{menuItemId}", handlers.LocationHandler.PutPrice)
+	mux.HandleFunc("DELETE /locations/{id}/prices/{menuItemId}", handlers.LocationHandler.DeletePrice)
+
+	mux.HandleFunc("POST /order", handlers.IdempotencyHandler.Wrap(handlers.OrderHandler.Post))
 	mux.HandleFunc("GET /order", handlers.OrderHandler.GetAll)
 	mux.HandleFunc("GET /order/{id}", handlers.OrderHandler.Get)
 	mux.HandleFunc("PUT /order/{id}", handlers.OrderHandler.Put)
+	mux.HandleFunc("PATCH /order/{id}", handlers.OrderHandler.Patch)
 	mux.HandleFunc("DELETE /order/{id}", handlers.OrderHandler.Delete)
+	mux.HandleFunc("POST /order/{id}/restore", handlers.OrderHandler.Restore)
 	mux.HandleFunc("POST /order/{id}/close", handlers.OrderHandler.PostClose)
-	mux.HandleFunc("GET /order/batch-process", handlers.OrderHandler.BatchProcess)
+	mux.HandleFunc("POST /order/batch-process", handlers.IdempotencyHandler.Wrap(handlers.OrderHandler.BatchProcess))
 	mux.HandleFunc("GET /order/numberOfOrderedItems", handlers.OrderHandler.NumberOfOrderedItems)
+	mux.HandleFunc("POST /order/{id}/payments", handlers.PaymentHandler.Post)
+	mux.HandleFunc("GET /order/{id}/payments", handlers.PaymentHandler.GetAll)
+	mux.HandleFunc("POST /order/{id}/refund", handlers.RefundHandler.Post)
+	mux.HandleFunc("GET /order/{id}/refunds", handlers.RefundHandler.GetAll)
+	mux.HandleFunc("GET /order/{id}/receipt", handlers.ReceiptHandler.Get)
+
+	mux.HandleFunc("GET /orders/stream", handlers.OrderStreamHandler.Stream)
+
+	mux.HandleFunc("GET /stations", handlers.StationHandler.GetAll)
+	mux.HandleFunc("GET /stations/{id}/tickets", handlers.StationHandler.GetTickets)
+	mux.HandleFunc("POST /stations/{id}/tickets/{ticketId}/bump", handlers.StationHandler.PostBump)
+	mux.HandleFunc("POST /stations/{id}/tickets/{ticketId}/items/{itemId}/bump", handlers.StationHandler.PostBumpItem)
+
+	mux.HandleFunc("POST /webhooks", handlers.WebhookHandler.Post)
+	mux.HandleFunc("GET /webhooks", handlers.WebhookHandler.GetAll)
+	mux.HandleFunc("GET /webhooks/{id}", handlers.WebhookHandler.Get)
+	mux.HandleFunc("PUT /webhooks/{id}", handlers.WebhookHandler.Put)
+	mux.HandleFunc("DELETE /webhooks/{id}", handlers.WebhookHandler.Delete)
+	mux.HandleFunc("GET /webhooks/{id}/deliveries", handlers.WebhookHandler.GetDeliveries)
+
+	mux.HandleFunc("GET /audit", handlers.AuditHandler.GetAll)
 
 	mux.HandleFunc("GET /reports/total-sales", handlers.AggregationHandler.GetTotalSales)
+	mux.HandleFunc("GET /reports/sales", handlers.AggregationHandler.GetSalesReport)
 	mux.HandleFunc("GET /reports/popular-items", handlers.AggregationHandler.GetPopularItems)
+	mux.HandleFunc("GET /reports/heatmap", handlers.AggregationHandler.GetHeatmap)
+	mux.HandleFunc("GET /reports/tips", handlers.AggregationHandler.GetTipReport)
+	mux.HandleFunc("GET /reports/prep-times", handlers.AggregationHandler.GetPrepTimeReport)
+	mux.HandleFunc("GET /reports/inventory-variance", handlers.AggregationHandler.GetInventoryVariance)
 	mux.HandleFunc("GET /reports/search", handlers.AggregationHandler.GetBySearch)
 	mux.HandleFunc("GET /reports/orderedItemsNyPeriod", handlers.AggregationHandler.GetListOfOrderedItems)
 
@@ -3,14 +3,49 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"frappuccino/models"
+	"frappuccino/utils"
+	"time"
 )
 
 type AggregationRepoIfc interface {
 	GetTotalSales(ctx context.Context) (float64, error)
-	GetPopularItems(ctx context.Context) (models.PopularItems, error)
+	GetPopularItems(ctx context.Context, filter models.PopularItemsFilter) (models.PopularItems, error)
 	GetSearchItems(ctx context.Context, q string, filter []string, maxPrice float64, minPrice float64) (models.Search, error)
 	GetListOfOrderedItems(ctx context.Context, period string, month string, year string) (models.ListOrderedItemByPeriods, error)
+	GetSalesReport(ctx context.Context, from time.Time, to time.Time, bucket string, timezone string, groupBy string) ([]models.SalesBucket, error)
+	GetHeatmap(ctx context.Context, from time.Time, to time.Time, timezone string) ([]models.HeatmapCell, error)
+	GetTipReport(ctx context.Context, from time.Time, to time.Time, timezone string) ([]models.TipReportRow, error)
+	GetPrepTimeStats(ctx context.Context, from time.Time, to time.Time, timezone string, fromStatus string, toStatus string) (map[string][]models.PrepTimeStats, error)
+	GetInventoryVariance(ctx context.Context, from time.Time, to time.Time) ([]models.IngredientVariance, error)
+	GetPrepTimeOutliers(ctx context.Context, from time.Time, to time.Time, fromStatus string, toStatus string, threshold time.Duration, limit int) ([]models.PrepTimeOutlier, error)
+}
+
+// salesGroupings maps a report dimension to the expression it is keyed by,
+// the name shown for a key when the key is an id, and the joins it needs on
+// top of orders and order_items.
+var salesGroupings = map[string]struct {
+	expr string
+	name string
+	join string
+}{
+	"":               {expr: "NULL::text"},
+	"menu_item":      {expr: "oi.menu_item_id::text", name: "mi.item_name", join: "JOIN menu_items mi ON mi.menu_item_id = oi.menu_item_id"},
+	"category":       {expr: "c.category", join: "JOIN menu_items mi ON mi.menu_item_id = oi.menu_item_id CROSS JOIN LATERAL unnest(mi.categories) AS c(category)"},
+	"payment_method": {expr: "o.order_payment_method::text"},
+	"status":         {expr: "o.order_status::text"},
+	"location":       {expr: "loc.location_name::text", join: "JOIN locations loc ON loc.location_id = o.location_id"},
+	"staff":          {expr: "COALESCE(NULLIF(st.full_name, ''), st.username, 'Unattributed')::text", join: "LEFT JOIN staff st ON st.staff_id = o.staff_id"},
+}
+
+// reportLocation returns the location a report is filtered by, or nil when
+// the caller selected none and the report aggregates across all locations.
+func reportLocation(ctx context.Context) any {
+	if locationId, ok := utils.SelectedLocation(ctx); ok {
+		return locationId
+	}
+	return nil
 }
 
 type AggregationRepo struct {
@@ -21,10 +56,17 @@ func NewAggregationRepo(db *sql.DB) *AggregationRepo {
 	return &AggregationRepo{db: db}
 }
 
+// GetTotalSales sums completed orders net of their refunds.
 func (ar *AggregationRepo) GetTotalSales(ctx context.Context) (float64, error) {
 	var totalSales float64
 
-	err := ar.db.QueryRowContext(ctx, `SELECT sum(total_price) FROM orders WHERE order_status = 'COMPLETED';`).Scan(
+	err := ar.db.QueryRowContext(ctx,
+		`SELECT COALESCE(sum(o.total_price - COALESCE(r.amount, 0)), 0)
+		FROM orders o
+		LEFT JOIN (SELECT order_id, SUM(amount) AS amount FROM refunds GROUP BY order_id) r ON r.order_id = o.order_id
+		WHERE o.order_status IN ('COMPLETED', 'REFUNDED') AND ($1::uuid IS NULL OR o.location_id = $1);`,
+		reportLocation(ctx),
+	).Scan(
 		&totalSales,
 	)
 	if err != nil {
@@ -34,34 +76,132 @@ func (ar *AggregationRepo) GetTotalSales(ctx context.Context) (float64, error) {
 	return totalSales, nil
 }
 
-func (ar *AggregationRepo) GetPopularItems(ctx context.Context) (models.PopularItems, error) {
-	var popularItems models.PopularItems
+func (ar *AggregationRepo) GetPopularItems(ctx context.Context, filter models.PopularItemsFilter) (models.PopularItems, error) {
+	metric, ok := popularItemMetrics[filter.Metric]
+	if !ok {
+		return models.PopularItems{}, fmt.Errorf("unsupported popularity metric %q", filter.Metric)
+	}
 
-	rows, err := ar.db.QueryContext(ctx,
-		`SELECT item_name, count(item_name) 
-		FROM order_items 
-		GROUP BY item_name
-		ORDER BY count(item_name) DESC
-		LIMIT 10;`,
+	// The previous window has the same length as the requested one and ends
+	// where it starts, so ranks can be compared period over period.
+	var previousFrom, previousTo *time.Time
+	if filter.From != nil && filter.To != nil {
+		start := filter.From.Add(-filter.To.Sub(*filter.From))
+		previousFrom, previousTo = &start, filter.From
+	}
+
+	query := fmt.Sprintf(
+		`WITH current_period AS (
+			%[1]s
+		),
+		previous_period AS (
+			%[2]s
+		),
+		ranked_current AS (
+			SELECT *, RANK() OVER (ORDER BY %[3]s DESC) AS item_rank FROM current_period
+		),
+		ranked_previous AS (
+			SELECT menu_item_id, RANK() OVER (ORDER BY %[3]s DESC) AS item_rank
+			FROM previous_period
+			WHERE $4::timestamptz IS NOT NULL
+		)
+		SELECT rc.menu_item_id, mi.item_name, rc.ordered_times, rc.units_sold, rc.revenue, rc.customers, rc.item_rank, rp.item_rank
+		FROM ranked_current rc
+		JOIN menu_items mi ON mi.menu_item_id = rc.menu_item_id
+		LEFT JOIN ranked_previous rp ON rp.menu_item_id = rc.menu_item_id
+		ORDER BY rc.item_rank, mi.item_name
+		LIMIT $6;`,
+		popularItemsWindow("$1", "$2"),
+		popularItemsWindow("$4", "$5"),
+		metric,
+	)
+
+	rows, err := ar.db.QueryContext(ctx, query,
+		filter.From,
+		filter.To,
+		filter.Category,
+		previousFrom,
+		previousTo,
+		filter.Limit,
+		reportLocation(ctx),
 	)
 	if err != nil {
 		return models.PopularItems{}, err
 	}
 	defer rows.Close()
 
+	var popularItems models.PopularItems
 	for rows.Next() {
 		var popularItem models.PopularItem
+		var previousRank sql.NullInt64
 
-		err = rows.Scan(&popularItem.ItemName, &popularItem.OrderedTimes)
+		err = rows.Scan(
+			&popularItem.MenuItemId,
+			&popularItem.ItemName,
+			&popularItem.OrderedTimes,
+			&popularItem.UnitsSold,
+			&popularItem.Revenue,
+			&popularItem.Customers,
+			&popularItem.Rank,
+			&previousRank,
+		)
 		if err != nil {
 			return models.PopularItems{}, err
 		}
+		if previousRank.Valid {
+			rank := int(previousRank.Int64)
+			change := rank - popularItem.Rank
+			popularItem.PreviousRank = &rank
+			popularItem.RankChange = &change
+		}
 		popularItems.Items = append(popularItems.Items, popularItem)
 	}
 
+	if err := rows.Err(); err != nil {
+		return models.PopularItems{}, err
+	}
+
 	return popularItems, nil
 }
 
+// popularItemMetrics maps a ranking metric to the column it orders by.
+var popularItemMetrics = map[string]string{
+	"units":     "units_sold",
+	"revenue":   "revenue",
+	"customers": "customers",
+}
+
+// popularItemsWindow aggregates the sold order lines per menu item for the
+// window bounded by the given placeholders. As in the sales report only
+// completed orders count and refunded quantities are netted out, so fully
+// refunded lines drop out. A NULL bound is open, $3 optionally restricts the
+// items to one category and $7 to one location.
+func popularItemsWindow(from string, to string) string {
+	return fmt.Sprintf(
+		`SELECT
+				oi.menu_item_id,
+				COUNT(*) AS ordered_times,
+				SUM(oi.quantity - COALESCE(rl.quantity, 0)) AS units_sold,
+				SUM((oi.quantity - COALESCE(rl.quantity, 0)) * oi.unit_price) AS revenue,
+				COUNT(DISTINCT o.customer_id) AS customers
+			FROM order_items oi
+			JOIN orders o ON o.order_id = oi.order_id
+			JOIN menu_items mi ON mi.menu_item_id = oi.menu_item_id
+			LEFT JOIN (
+				SELECT order_item_id, SUM(quantity) AS quantity FROM refund_lines GROUP BY order_item_id
+			) rl ON rl.order_item_id = oi.order_item_id
+			WHERE o.order_status IN ('COMPLETED', 'REFUNDED')
+				AND oi.quantity > COALESCE(rl.quantity, 0)
+				AND (%[1]s::timestamptz IS NULL OR o.created_at >= %[1]s)
+				AND (%[2]s::timestamptz IS NULL OR o.created_at < %[2]s)
+				AND ($3::text = '' OR $3 = ANY(mi.categories))
+				AND ($7::uuid IS NULL OR o.location_id = $7)
+			GROUP BY oi.menu_item_id`,
+		from,
+		to,
+	)
+}
+
 func (ar *AggregationRepo) GetSearchItems(ctx context.Context, q string, filter []string, maxPrice float64, minPrice float64) (models.Search, error) {
 	var search models.Search // Переименовал переменную в search
 	var err error
@@ -93,7 +233,7 @@ func (ar *AggregationRepo) getOrder(ctx context.Context, q string, maxPrice floa
 	query := `SELECT oi.order_id, c.full_name, oi.item_name, oi.unit_price 
 				FROM order_items as oi
 				JOIN orders as o on o.order_id = oi.order_id
-				JOIN customer as c on c.customer_id = o.customer_id
+				JOIN customers as c on c.customer_id = o.customer_id
 				WHERE  (c.full_name ILIKE '%' || $1 || '%' OR  oi.item_name ILIKE '%' || $1 || '%')`
 	var rows *sql.Rows
 	var err error
@@ -248,3 +388,440 @@ func (ar *AggregationRepo) GetListOfOrderedItems(ctx context.Context, period str
 	}
 	return list, nil
 }
+
+func (ar *AggregationRepo) GetSalesReport(ctx context.Context, from time.Time, to time.Time, bucket string, timezone string, groupBy string) ([]models.SalesBucket, error) {
+	switch bucket {
+	case "hour", "day", "week", "month":
+	default:
+		return nil, fmt.Errorf("unsupported sales bucket %q", bucket)
+	}
+	grouping, ok := salesGroupings[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sales grouping %q", groupBy)
+	}
+
+	// Sales only count completed orders unless the report is split by status.
+	// Refunded quantities are netted out of the bucket of the original sale,
+	// so fully refunded lines drop out of the sales figures.
+	statusFilter := "AND o.order_status IN ('COMPLETED', 'REFUNDED') AND oi.quantity > COALESCE(rl.quantity, 0)"
+	groups := "SELECT group_key, MAX(group_name) AS group_name FROM lines GROUP BY group_key"
+	if groupBy == "status" {
+		statusFilter = ""
+	}
+	if groupBy == "" {
+		groups = "SELECT NULL::text AS group_key, NULL::text AS group_name"
+	}
+	name := grouping.name
+	if name == "" {
+		name = "NULL::text"
+	}
+
+	query := fmt.Sprintf(
+		`WITH params AS (
+			SELECT $1::timestamptz AS from_ts, $2::timestamptz AS to_ts, $3::text AS tz, $4::uuid AS location_id
+		),
+		buckets AS (
+			SELECT generate_series(
+				date_trunc('%[1]s', p.from_ts AT TIME ZONE p.tz),
+				date_trunc('%[1]s', (p.to_ts - INTERVAL '1 microsecond') AT TIME ZONE p.tz),
+				INTERVAL '1 %[1]s'
+			) AS bucket_start
+			FROM params p
+		),
+		lines AS (
+			SELECT
+				date_trunc('%[1]s', o.created_at AT TIME ZONE p.tz) AS bucket_start,
+				%[2]s AS group_key,
+				%[6]s AS group_name,
+				o.order_id,
+				oi.quantity - COALESCE(rl.quantity, 0) AS quantity,
+				(oi.quantity - COALESCE(rl.quantity, 0)) * oi.unit_price AS revenue
+			FROM orders o
+			JOIN order_items oi ON oi.order_id = o.order_id
+			LEFT JOIN (
+				SELECT order_item_id, SUM(quantity) AS quantity FROM refund_lines GROUP BY order_item_id
+			) rl ON rl.order_item_id = oi.order_item_id
+			%[3]s
+			CROSS JOIN params p
+			WHERE o.created_at >= p.from_ts AND o.created_at < p.to_ts
+				AND (p.location_id IS NULL OR o.location_id = p.location_id)
+			%[4]s
+		),
+		groups AS (
+			%[5]s
+		)
+		SELECT
+			to_char(b.bucket_start, 'YYYY-MM-DD"T"HH24:MI:SS'),
+			COALESCE(g.group_key, ''),
+			COALESCE(g.group_name, ''),
+			COALESCE(SUM(l.revenue), 0),
+			COUNT(DISTINCT l.order_id),
+			COALESCE(SUM(l.quantity), 0)
+		FROM buckets b
+		CROSS JOIN groups g
+		LEFT JOIN lines l
+			ON l.bucket_start = b.bucket_start
+			AND l.group_key IS NOT DISTINCT FROM g.group_key
+		GROUP BY b.bucket_start, g.group_key, g.group_name
+		ORDER BY b.bucket_start, g.group_name, g.group_key;`,
+		bucket,
+		grouping.expr,
+		grouping.join,
+		statusFilter,
+		groups,
+		name,
+	)
+
+	rows, err := ar.db.QueryContext(ctx, query, from, to, timezone, reportLocation(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []models.SalesBucket
+	for rows.Next() {
+		var item models.SalesBucket
+		err = rows.Scan(&item.BucketStart, &item.Group, &item.GroupName, &item.GrossRevenue, &item.OrderCount, &item.ItemCount)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return buckets, nil
+}
+
+// GetHeatmap returns one cell per ISO weekday (1 = Monday) and hour, with
+// empty cells zero-filled. Preparation time runs from order creation to the
+// first COMPLETED entry in order_status_history.
+func (ar *AggregationRepo) GetHeatmap(ctx context.Context, from time.Time, to time.Time, timezone string) ([]models.HeatmapCell, error) {
+	rows, err := ar.db.QueryContext(ctx,
+		`WITH completed AS (
+			SELECT
+				o.order_id,
+				o.total_price,
+				o.created_at AT TIME ZONE $3 AS local_ts,
+				(
+					SELECT MIN(h.updated_at)
+					FROM order_status_history h
+					WHERE h.order_id = o.order_id AND h.order_status = 'COMPLETED'
+				) - o.created_at AS prep_time
+			FROM orders o
+			WHERE o.order_status = 'COMPLETED'
+				AND o.created_at >= $1 AND o.created_at < $2
+				AND ($4::uuid IS NULL OR o.location_id = $4)
+		),
+		grid AS (
+			SELECT d AS weekday, h AS hour
+			FROM generate_series(1, 7) AS d
+			CROSS JOIN generate_series(0, 23) AS h
+		)
+		SELECT
+			g.weekday,
+			g.hour,
+			COUNT(c.order_id),
+			COALESCE(SUM(c.total_price), 0),
+			AVG(EXTRACT(EPOCH FROM c.prep_time))
+		FROM grid g
+		LEFT JOIN completed c
+			ON EXTRACT(ISODOW FROM c.local_ts) = g.weekday
+			AND EXTRACT(HOUR FROM c.local_ts) = g.hour
+		GROUP BY g.weekday, g.hour
+		ORDER BY g.weekday, g.hour;`,
+		from,
+		to,
+		timezone,
+		reportLocation(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cells []models.HeatmapCell
+	for rows.Next() {
+		var cell models.HeatmapCell
+		var avgPrep sql.NullFloat64
+
+		err = rows.Scan(&cell.Weekday, &cell.Hour, &cell.OrderCount, &cell.Revenue, &avgPrep)
+		if err != nil {
+			return nil, err
+		}
+		if avgPrep.Valid {
+			cell.AvgPrepSeconds = &avgPrep.Float64
+		}
+		cells = append(cells, cell)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return cells, nil
+}
+
+// GetTipReport sums the tips of payments taken in [from, to) per local day
+// and staff member. Tips taken with an API key are credited to whoever took
+// the order; tips of cancelled orders are left out.
+func (ar *AggregationRepo) GetTipReport(ctx context.Context, from time.Time, to time.Time, timezone string) ([]models.TipReportRow, error) {
+	rows, err := ar.db.QueryContext(ctx,
+		`SELECT
+			to_char(p.created_at AT TIME ZONE $3, 'YYYY-MM-DD') AS day,
+			COALESCE(st.staff_id::text, '') AS staff_id,
+			COALESCE(NULLIF(st.full_name, ''), st.username, 'Unattributed') AS staff_name,
+			SUM(p.tip),
+			COUNT(*)
+		FROM payments p
+		JOIN orders o ON o.order_id = p.order_id
+		LEFT JOIN staff st ON st.staff_id = COALESCE(p.staff_id, o.staff_id)
+		WHERE p.created_at >= $1 AND p.created_at < $2
+			AND p.tip > 0
+			AND o.order_status <> 'CANCELLED'
+			AND ($4::uuid IS NULL OR o.location_id = $4)
+		GROUP BY 1, 2, 3
+		ORDER BY 1, 3;`,
+		from,
+		to,
+		timezone,
+		reportLocation(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var report []models.TipReportRow
+	for rows.Next() {
+		var row models.TipReportRow
+		err = rows.Scan(&row.Day, &row.StaffId, &row.StaffName, &row.Tips, &row.PaymentCount)
+		if err != nil {
+			return nil, err
+		}
+		report = append(report, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// prepDurationsCTE measures, per order created in [$1, $2), the time between
+// the first entry of status $3 and the first entry of status $4 in
+// order_status_history, optionally restricted to location $5. Orders created
+// before the initial status was logged fall back to created_at for PENDING.
+const prepDurationsCTE = `WITH durations AS (
+			SELECT o.order_id, o.customer_id, o.created_at,
+				EXTRACT(EPOCH FROM (t.reached_at - COALESCE(s.started_at, CASE WHEN $3::all_order_status = 'PENDING' THEN o.created_at END))) AS seconds
+			FROM orders o
+			CROSS JOIN LATERAL (
+				SELECT MIN(h.updated_at) AS started_at
+				FROM order_status_history h
+				WHERE h.order_id = o.order_id AND h.order_status = $3::all_order_status
+			) s
+			CROSS JOIN LATERAL (
+				SELECT MIN(h.updated_at) AS reached_at
+				FROM order_status_history h
+				WHERE h.order_id = o.order_id AND h.order_status = $4::all_order_status
+			) t
+			WHERE o.created_at >= $1 AND o.created_at < $2
+				AND ($5::uuid IS NULL OR o.location_id = $5)
+				AND t.reached_at IS NOT NULL
+				AND COALESCE(s.started_at, CASE WHEN $3::all_order_status = 'PENDING' THEN o.created_at END) <= t.reached_at
+		)`
+
+// GetPrepTimeStats returns duration percentiles keyed by dimension: overall,
+// menu_item, hour and day. Hours and days are taken in the given timezone.
+func (ar *AggregationRepo) GetPrepTimeStats(ctx context.Context, from time.Time, to time.Time, timezone string, fromStatus string, toStatus string) (map[string][]models.PrepTimeStats, error) {
+	rows, err := ar.db.QueryContext(ctx,
+		prepDurationsCTE+`,
+		order_menu_items AS (
+			SELECT DISTINCT d.order_id, oi.menu_item_id, d.seconds
+			FROM durations d
+			JOIN order_items oi ON oi.order_id = d.order_id
+		),
+		keyed AS (
+			SELECT 'overall' AS dimension, '' AS key, seconds FROM durations
+			UNION ALL
+			SELECT 'menu_item', mi.item_name, omi.seconds
+			FROM order_menu_items omi
+			JOIN menu_items mi ON mi.menu_item_id = omi.menu_item_id
+			UNION ALL
+			SELECT 'hour', to_char(created_at AT TIME ZONE $6, 'HH24'), seconds FROM durations
+			UNION ALL
+			SELECT 'day', to_char(created_at AT TIME ZONE $6, 'YYYY-MM-DD'), seconds FROM durations
+		)
+		SELECT
+			dimension,
+			key,
+			COUNT(*),
+			AVG(seconds),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY seconds),
+			percentile_cont(0.9) WITHIN GROUP (ORDER BY seconds),
+			percentile_cont(0.99) WITHIN GROUP (ORDER BY seconds)
+		FROM keyed
+		GROUP BY dimension, key
+		ORDER BY dimension, key;`,
+		from,
+		to,
+		fromStatus,
+		toStatus,
+		reportLocation(ctx),
+		timezone,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make(map[string][]models.PrepTimeStats)
+	for rows.Next() {
+		var dimension string
+		var item models.PrepTimeStats
+
+		err = rows.Scan(&dimension, &item.Key, &item.OrderCount, &item.AvgSeconds, &item.P50Seconds, &item.P90Seconds, &item.P99Seconds)
+		if err != nil {
+			return nil, err
+		}
+		stats[dimension] = append(stats[dimension], item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (ar *AggregationRepo) GetPrepTimeOutliers(ctx context.Context, from time.Time, to time.Time, fromStatus string, toStatus string, threshold time.Duration, limit int) ([]models.PrepTimeOutlier, error) {
+	rows, err := ar.db.QueryContext(ctx,
+		prepDurationsCTE+`
+		SELECT order_id, customer_id, created_at, seconds
+		FROM durations
+		WHERE seconds > $6
+		ORDER BY seconds DESC
+		LIMIT $7;`,
+		from,
+		to,
+		fromStatus,
+		toStatus,
+		reportLocation(ctx),
+		threshold.Seconds(),
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var outliers []models.PrepTimeOutlier
+	for rows.Next() {
+		var outlier models.PrepTimeOutlier
+		var createdAt time.Time
+
+		err = rows.Scan(&outlier.OrderId, &outlier.CustomerId, &createdAt, &outlier.DurationSeconds)
+		if err != nil {
+			return nil, err
+		}
+		outlier.CreatedAt = createdAt.Format(time.RFC3339)
+		outliers = append(outliers, outlier)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return outliers, nil
+}
+
+// GetInventoryVariance compares the recipe-based consumption of completed
+// orders, refunded ones included since their stock was drawn too, with the
+// ledger movements of each ingredient in [from, to). Outflows
+// are stored as negative quantities, ADJUST rows keep their sign. Of the
+// adjustments only stock count corrections and downward ones are counted:
+// a quantity raised through the inventory API is a delivery, not a gain.
+func (ar *AggregationRepo) GetInventoryVariance(ctx context.Context, from time.Time, to time.Time) ([]models.IngredientVariance, error) {
+	rows, err := ar.db.QueryContext(ctx,
+		`WITH theoretical AS (
+			SELECT mii.ingredient_id, SUM(mii.quantity * oi.quantity) AS quantity
+			FROM orders o
+			JOIN order_items oi ON oi.order_id = o.order_id
+			JOIN menu_item_ingredients mii ON mii.menu_item_id = oi.menu_item_id
+			WHERE o.order_status IN ('COMPLETED', 'REFUNDED')
+				AND o.created_at >= $1 AND o.created_at < $2
+				AND ($3::uuid IS NULL OR o.location_id = $3)
+			GROUP BY mii.ingredient_id
+		),
+		ledger AS (
+			SELECT
+				ingredient_id,
+				SUM(ABS(quantity)) FILTER (WHERE inventory_transaction_action = 'REMOVE') AS removed,
+				SUM(ABS(quantity)) FILTER (WHERE inventory_transaction_action = 'WASTE') AS waste,
+				SUM(ABS(quantity)) FILTER (WHERE inventory_transaction_action = 'SPOILAGE') AS spoilage,
+				SUM(ABS(quantity)) FILTER (WHERE inventory_transaction_action = 'THEFT') AS theft,
+				SUM(quantity) FILTER (
+					WHERE inventory_transaction_action = 'ADJUST'
+						AND (notes = 'Stock count' OR quantity < 0)
+				) AS adjusted
+			FROM inventory_transactions
+			WHERE created_at >= $1 AND created_at < $2
+				AND ($3::uuid IS NULL OR location_id = $3)
+			GROUP BY ingredient_id
+		)
+		SELECT
+			i.ingredient_id,
+			i.ingredient_name,
+			i.unit,
+			i.unit_cost,
+			COALESCE(t.quantity, 0),
+			COALESCE(l.removed, 0),
+			COALESCE(l.waste, 0),
+			COALESCE(l.spoilage, 0),
+			COALESCE(l.theft, 0),
+			COALESCE(l.adjusted, 0)
+		FROM inventory i
+		LEFT JOIN theoretical t ON t.ingredient_id = i.ingredient_id
+		LEFT JOIN ledger l ON l.ingredient_id = i.ingredient_id
+		WHERE t.ingredient_id IS NOT NULL OR l.ingredient_id IS NOT NULL
+		ORDER BY i.ingredient_name;`,
+		from,
+		to,
+		reportLocation(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.IngredientVariance
+	for rows.Next() {
+		var item models.IngredientVariance
+		err = rows.Scan(
+			&item.IngredientId,
+			&item.IngredientName,
+			&item.Unit,
+			&item.UnitCost,
+			&item.TheoreticalUsage,
+			&item.RecordedSales,
+			&item.Waste,
+			&item.Spoilage,
+			&item.Theft,
+			&item.Adjustments,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
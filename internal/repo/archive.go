@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"frappuccino/utils"
+
+	"github.com/lib/pq"
+)
+
+// Customers, menu items, ingredients and orders are never removed: deleting
+// one only sets deleted_at so that order history and reports keep resolving
+// it. The helpers below take table and column names from the repositories,
+// never from user input.
+
+// archiveRow marks an active row as deleted.
+func archiveRow(ctx context.Context, db *sql.DB, table string, idColumn string, id string) error {
+	tx, err := beginTx(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkVersion(ctx, tx.Tx, table, idColumn, id); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx,
+		fmt.Sprintf(`UPDATE %s SET deleted_at = now(), updated_at = now() WHERE %s = $1 AND deleted_at IS NULL`, table, idColumn),
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return utils.ErrIdNotFound
+	}
+
+	return tx.Commit()
+}
+
+// restoreRow brings an archived row back. Restoring an active row fails with
+// ErrNotArchived, an unknown one with ErrIdNotFound.
+func restoreRow(ctx context.Context, db *sql.DB, table string, idColumn string, id string) error {
+	res, err := conn(ctx, db).ExecContext(ctx,
+		fmt.Sprintf(`UPDATE %s SET deleted_at = NULL, updated_at = now() WHERE %s = $1 AND deleted_at IS NOT NULL`, table, idColumn),
+		id,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return utils.ErrConflictFields
+		}
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	var exists bool
+	err = db.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE %s = $1)`, table, idColumn),
+		id,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return utils.ErrNotArchived
+	}
+	return utils.ErrIdNotFound
+}
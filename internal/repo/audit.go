@@ -0,0 +1,98 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"frappuccino/models"
+)
+
+type AuditRepoIfc interface {
+	Record(ctx context.Context, entry *models.AuditEntry) error
+	GetByResource(ctx context.Context, resource string, resourceId string, limit int) ([]models.AuditEntry, error)
+}
+
+type AuditRepo struct {
+	db *sql.DB
+}
+
+func NewAuditRepo(db *sql.DB) *AuditRepo {
+	return &AuditRepo{db: db}
+}
+
+func (ar *AuditRepo) Record(ctx context.Context, entry *models.AuditEntry) error {
+	return conn(ctx, ar.db).QueryRowContext(ctx,
+		`INSERT INTO audit_log (actor_staff_id, actor_api_key_id, actor_name, method, route, resource, resource_id, before, after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING audit_id, created_at`,
+		entry.ActorStaffId,
+		entry.ActorApiKeyId,
+		entry.ActorName,
+		entry.Method,
+		entry.Route,
+		entry.Resource,
+		entry.ResourceId,
+		snapshot(entry.Before),
+		snapshot(entry.After),
+	).Scan(&entry.AuditId, &entry.CreatedAt)
+}
+
+// GetByResource returns the newest entries first. An empty resourceId lists
+// the history of every resource of that kind.
+func (ar *AuditRepo) GetByResource(ctx context.Context, resource string, resourceId string, limit int) ([]models.AuditEntry, error) {
+	var id any
+	if resourceId != "" {
+		id = resourceId
+	}
+
+	rows, err := ar.db.QueryContext(ctx,
+		`SELECT audit_id, actor_staff_id, actor_api_key_id, actor_name, method, route, resource, resource_id, before, after, created_at
+		FROM audit_log
+		WHERE resource = $1 AND ($2::uuid IS NULL OR resource_id = $2)
+		ORDER BY created_at DESC
+		LIMIT $3`,
+		resource,
+		id,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+		err := rows.Scan(
+			&entry.AuditId,
+			&entry.ActorStaffId,
+			&entry.ActorApiKeyId,
+			&entry.ActorName,
+			&entry.Method,
+			&entry.Route,
+			&entry.Resource,
+			&entry.ResourceId,
+			&entry.Before,
+			&entry.After,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// snapshot stores a missing document as NULL rather than the {} JSONB
+// columns default to.
+func snapshot(document []byte) any {
+	if len(document) == 0 {
+		return nil
+	}
+	return document
+}
@@ -0,0 +1,192 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"time"
+)
+
+type AuthRepoIfc interface {
+	CreateSession(ctx context.Context, staffId string, tokenHash string, expiresAt time.Time) error
+	GetSessionStaff(ctx context.Context, tokenHash string) (models.Staff, error)
+	DeleteSession(ctx context.Context, tokenHash string) error
+	CreateAPIKey(ctx context.Context, key *models.APIKey, keyHash string) (*models.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, apiKeyId string) error
+}
+
+type AuthRepo struct {
+	db *sql.DB
+}
+
+func NewAuthRepo(db *sql.DB) *AuthRepo {
+	return &AuthRepo{db: db}
+}
+
+// CreateSession stores a new login and drops the sessions that expired.
+func (ar *AuthRepo) CreateSession(ctx context.Context, staffId string, tokenHash string, expiresAt time.Time) error {
+	tx, err := ar.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM auth_sessions WHERE expires_at <= now()`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO auth_sessions (token_hash, staff_id, expires_at)
+		VALUES ($1, $2, $3)`,
+		tokenHash,
+		staffId,
+		expiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetSessionStaff resolves a live session of an active account.
+func (ar *AuthRepo) GetSessionStaff(ctx context.Context, tokenHash string) (models.Staff, error) {
+	var staff models.Staff
+	err := ar.db.QueryRowContext(ctx,
+		`SELECT s.staff_id, s.full_name, s.username, s.staff_role, s.is_active, s.created_at, s.updated_at
+		FROM auth_sessions a
+		JOIN staff s ON s.staff_id = a.staff_id
+		WHERE a.token_hash = $1 AND a.expires_at > now() AND s.is_active`,
+		tokenHash,
+	).Scan(
+		&staff.StaffId,
+		&staff.FullName,
+		&staff.Username,
+		&staff.StaffRole,
+		&staff.IsActive,
+		&staff.CreatedAt,
+		&staff.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Staff{}, utils.ErrUnauthorized
+		}
+		return models.Staff{}, err
+	}
+
+	return staff, nil
+}
+
+func (ar *AuthRepo) DeleteSession(ctx context.Context, tokenHash string) error {
+	_, err := ar.db.ExecContext(ctx, `DELETE FROM auth_sessions WHERE token_hash = $1`, tokenHash)
+	return err
+}
+
+func (ar *AuthRepo) CreateAPIKey(ctx context.Context, key *models.APIKey, keyHash string) (*models.APIKey, error) {
+	err := ar.db.QueryRowContext(ctx,
+		`INSERT INTO api_keys (key_name, key_prefix, key_hash, key_role, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING api_key_id, created_at`,
+		key.KeyName,
+		key.KeyPrefix,
+		keyHash,
+		key.KeyRole,
+		key.CreatedBy,
+	).Scan(&key.ApiKeyId, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// GetAPIKeyByHash resolves a key that has not been revoked and records that
+// it was used.
+func (ar *AuthRepo) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	var key models.APIKey
+	err := ar.db.QueryRowContext(ctx,
+		`UPDATE api_keys
+		SET last_used_at = now()
+		WHERE key_hash = $1 AND revoked_at IS NULL
+		RETURNING api_key_id, key_name, key_prefix, key_role, created_by, created_at, last_used_at, revoked_at`,
+		keyHash,
+	).Scan(
+		&key.ApiKeyId,
+		&key.KeyName,
+		&key.KeyPrefix,
+		&key.KeyRole,
+		&key.CreatedBy,
+		&key.CreatedAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.APIKey{}, utils.ErrUnauthorized
+		}
+		return models.APIKey{}, err
+	}
+
+	return key, nil
+}
+
+func (ar *AuthRepo) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := ar.db.QueryContext(ctx,
+		`SELECT api_key_id, key_name, key_prefix, key_role, created_by, created_at, last_used_at, revoked_at
+		FROM api_keys
+		ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var key models.APIKey
+		err := rows.Scan(
+			&key.ApiKeyId,
+			&key.KeyName,
+			&key.KeyPrefix,
+			&key.KeyRole,
+			&key.CreatedBy,
+			&key.CreatedAt,
+			&key.LastUsedAt,
+			&key.RevokedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (ar *AuthRepo) RevokeAPIKey(ctx context.Context, apiKeyId string) error {
+	res, err := ar.db.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = now() WHERE api_key_id = $1 AND revoked_at IS NULL`,
+		apiKeyId,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return utils.ErrIdNotFound
+	}
+
+	return nil
+}
@@ -8,6 +8,21 @@ type Repo struct {
 	MenuRepo        MenuRepoIfc
 	OrderRepo       OrderRepoIfc
 	AggregationRepo AggregationRepoIfc
+	StockCountRepo  StockCountRepoIfc
+	LocationRepo    LocationRepoIfc
+	TransferRepo    StockTransferRepoIfc
+	StaffRepo       StaffRepoIfc
+	AuthRepo        AuthRepoIfc
+	AuditRepo       AuditRepoIfc
+	ShiftRepo       ShiftRepoIfc
+	PaymentRepo     PaymentRepoIfc
+	RefundRepo      RefundRepoIfc
+	IdempotencyRepo IdempotencyRepoIfc
+	OrderEventRepo  OrderEventRepoIfc
+	WebhookRepo     WebhookRepoIfc
+	OutboxRepo      OutboxRepoIfc
+	TxRepo          TxRepoIfc
+	StationRepo     StationRepoIfc
 }
 
 func New(db *sql.DB) *Repo {
@@ -17,5 +32,20 @@ func New(db *sql.DB) *Repo {
 		MenuRepo:        NewMenuRepo(db),
 		OrderRepo:       NewOrderRepo(db),
 		AggregationRepo: NewAggregationRepo(db),
+		StockCountRepo:  NewStockCountRepo(db),
+		LocationRepo:    NewLocationRepo(db),
+		TransferRepo:    NewStockTransferRepo(db),
+		StaffRepo:       NewStaffRepo(db),
+		AuthRepo:        NewAuthRepo(db),
+		AuditRepo:       NewAuditRepo(db),
+		ShiftRepo:       NewShiftRepo(db),
+		PaymentRepo:     NewPaymentRepo(db),
+		RefundRepo:      NewRefundRepo(db),
+		IdempotencyRepo: NewIdempotencyRepo(db),
+		OrderEventRepo:  NewOrderEventRepo(db),
+		WebhookRepo:     NewWebhookRepo(db),
+		OutboxRepo:      NewOutboxRepo(db),
+		TxRepo:          NewTxRepo(db),
+		StationRepo:     NewStationRepo(db),
 	}
 }
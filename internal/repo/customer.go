@@ -13,7 +13,9 @@ type CustomerRepoIfc interface {
 	GetAll(ctx context.Context) ([]models.Customer, error)
 	GetByID(ctx context.Context, customerId string) (models.Customer, error)
 	UpdateById(ctx context.Context, customer *models.Customer) error
+	Patch(ctx context.Context, customerId string, set map[string]any) error
 	DeleteById(ctx context.Context, customerId string) error
+	Restore(ctx context.Context, customerId string) error
 	GetByFullNameAndPhone(ctx context.Context, fullname string, phonenumber string) (string, error)
 }
 
@@ -26,13 +28,13 @@ func NewCustomerRepo(db *sql.DB) *CustomerRepo {
 }
 
 func (cr *CustomerRepo) Create(ctx context.Context, customer *models.Customer) (*models.Customer, error) {
-	tx, err := cr.db.BeginTx(ctx, nil)
+	tx, err := beginTx(ctx, cr.db)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	err = cr.db.QueryRowContext(ctx,
+	err = tx.QueryRowContext(ctx,
 		`INSERT INTO customers (full_name, phone_number, email, preferences)
 	     VALUES ($1, $2, $3, $4)
 		 RETURNING customer_id, created_at, updated_at`,
@@ -54,7 +56,10 @@ func (cr *CustomerRepo) Create(ctx context.Context, customer *models.Customer) (
 }
 
 func (cr *CustomerRepo) GetAll(ctx context.Context) ([]models.Customer, error) {
-	rows, err := cr.db.QueryContext(ctx, `SELECT * FROM customers`)
+	rows, err := cr.db.QueryContext(ctx,
+		`SELECT customer_id, full_name, phone_number, email, preferences, created_at, updated_at
+		FROM customers
+		WHERE deleted_at IS NULL`)
 	if err != nil {
 		return nil, err
 	}
@@ -83,8 +88,10 @@ func (cr *CustomerRepo) GetAll(ctx context.Context) ([]models.Customer, error) {
 
 func (cr *CustomerRepo) GetByID(ctx context.Context, customerId string) (models.Customer, error) {
 	var customer models.Customer
-	err := cr.db.QueryRowContext(ctx,
-		`SELECT * FROM customers WHERE customer_id = $1`,
+	err := conn(ctx, cr.db).QueryRowContext(ctx,
+		`SELECT customer_id, full_name, phone_number, email, preferences, created_at, updated_at
+		FROM customers
+		WHERE customer_id = $1 AND deleted_at IS NULL`,
 		customerId,
 	).Scan(
 		&customer.CustomerId,
@@ -105,12 +112,16 @@ func (cr *CustomerRepo) GetByID(ctx context.Context, customerId string) (models.
 }
 
 func (cr *CustomerRepo) UpdateById(ctx context.Context, customer *models.Customer) error {
-	tx, err := cr.db.BeginTx(ctx, nil)
+	tx, err := beginTx(ctx, cr.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if err := checkVersion(ctx, tx.Tx, "customers", "customer_id", string(customer.CustomerId)); err != nil {
+		return err
+	}
+
 	res, err := tx.ExecContext(ctx,
 		`UPDATE customers 
 		 SET 
@@ -119,7 +130,7 @@ func (cr *CustomerRepo) UpdateById(ctx context.Context, customer *models.Custome
 			 email = $3,
 			 preferences = $4,
 			 updated_at = NOW()
-		 WHERE customer_id = $5`,
+		 WHERE customer_id = $5 AND deleted_at IS NULL`,
 		customer.FullName,
 		customer.PhoneNumber,
 		customer.Email,
@@ -145,37 +156,46 @@ func (cr *CustomerRepo) UpdateById(ctx context.Context, customer *models.Custome
 	return tx.Commit()
 }
 
-func (cr *CustomerRepo) DeleteById(ctx context.Context, customerId string) error {
-	tx, err := cr.db.BeginTx(ctx, nil)
+// customerPatchColumns lists the columns a merge patch may change.
+var customerPatchColumns = map[string]bool{
+	"full_name":    true,
+	"phone_number": true,
+	"email":        true,
+	"preferences":  true,
+}
+
+func (cr *CustomerRepo) Patch(ctx context.Context, customerId string, set map[string]any) error {
+	tx, err := beginTx(ctx, cr.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	res, err := tx.ExecContext(ctx, `DELETE FROM customers WHERE customer_id = $1`, customerId)
-	if err != nil {
+	if err := checkVersion(ctx, tx.Tx, "customers", "customer_id", customerId); err != nil {
 		return err
 	}
-
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
+	if err := patchRow(ctx, tx.Tx, "customers", "customer_id", customerId, set, customerPatchColumns); err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return utils.ErrIdNotFound
-	}
-
 	return tx.Commit()
 }
 
+func (cr *CustomerRepo) DeleteById(ctx context.Context, customerId string) error {
+	return archiveRow(ctx, cr.db, "customers", "customer_id", customerId)
+}
+
+func (cr *CustomerRepo) Restore(ctx context.Context, customerId string) error {
+	return restoreRow(ctx, cr.db, "customers", "customer_id", customerId)
+}
+
 func (cr *CustomerRepo) GetByFullNameAndPhone(ctx context.Context, fullname string, phonenumber string) (string, error) {
 	var customerId string
 	err := cr.db.QueryRowContext(ctx,
 		`WITH existing AS (
 			SELECT customer_id 
 			FROM customers 
-			WHERE full_name = $1 AND phone_number = $2
+			WHERE full_name = $1 AND phone_number = $2 AND deleted_at IS NULL
 		),
 		inserted AS (
 			INSERT INTO customers (full_name, phone_number) 
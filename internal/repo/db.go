@@ -15,10 +15,12 @@ var (
 	port, exist = os.LookupEnv("DB_PORT")
 )
 
-func ConnectDB() *sql.DB {
-	psqlInfo := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, name)
+func connInfo() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, name)
+}
 
-	db, err := sql.Open("postgres", psqlInfo)
+func ConnectDB() *sql.DB {
+	db, err := sql.Open("postgres", connInfo())
 	if err != nil {
 		log.Fatalf("Failed to connect to db: %v", err)
 	}
@@ -0,0 +1,122 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"time"
+)
+
+type IdempotencyRepoIfc interface {
+	Reserve(ctx context.Context, key *models.IdempotencyKey, ttl time.Duration, lockTimeout time.Duration) (bool, error)
+	Get(ctx context.Context, scope string, key string) (models.IdempotencyKey, error)
+	Complete(ctx context.Context, scope string, key string, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, scope string, key string) error
+}
+
+type IdempotencyRepo struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepo(db *sql.DB) *IdempotencyRepo {
+	return &IdempotencyRepo{db: db}
+}
+
+// Reserve claims the key for a new request and drops the keys that expired.
+// An expired key is claimed again, and so is a request with the same body
+// that has been in progress for longer than lockTimeout, which means the
+// server gave up on it. It returns false when the key is held by another
+// request.
+func (ir *IdempotencyRepo) Reserve(ctx context.Context, key *models.IdempotencyKey, ttl time.Duration, lockTimeout time.Duration) (bool, error) {
+	tx, err := ir.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= now()`)
+	if err != nil {
+		return false, err
+	}
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO idempotency_keys (scope, idempotency_key, route, request_hash, expires_at)
+		VALUES ($1, $2, $3, $4, now() + $5 * interval '1 second')
+		ON CONFLICT (scope, idempotency_key) DO UPDATE
+		SET created_at = now(), expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.status_code IS NULL
+			AND idempotency_keys.request_hash = EXCLUDED.request_hash
+			AND idempotency_keys.route = EXCLUDED.route
+			AND idempotency_keys.created_at < now() - $6 * interval '1 second'
+		RETURNING created_at, expires_at`,
+		key.Scope,
+		key.Key,
+		key.Route,
+		key.RequestHash,
+		ttl.Seconds(),
+		lockTimeout.Seconds(),
+	).Scan(&key.CreatedAt, &key.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (ir *IdempotencyRepo) Get(ctx context.Context, scope string, key string) (models.IdempotencyKey, error) {
+	var stored models.IdempotencyKey
+	err := ir.db.QueryRowContext(ctx,
+		`SELECT scope, idempotency_key, route, request_hash, status_code, content_type, response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE scope = $1 AND idempotency_key = $2 AND expires_at > now()`,
+		scope,
+		key,
+	).Scan(
+		&stored.Scope,
+		&stored.Key,
+		&stored.Route,
+		&stored.RequestHash,
+		&stored.StatusCode,
+		&stored.ContentType,
+		&stored.ResponseBody,
+		&stored.CreatedAt,
+		&stored.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.IdempotencyKey{}, utils.ErrIdNotFound
+		}
+		return models.IdempotencyKey{}, err
+	}
+	return stored, nil
+}
+
+// Complete stores the response that retries of the request will receive.
+func (ir *IdempotencyRepo) Complete(ctx context.Context, scope string, key string, statusCode int, contentType string, body []byte) error {
+	_, err := ir.db.ExecContext(ctx,
+		`UPDATE idempotency_keys
+		SET status_code = $3, content_type = $4, response_body = $5
+		WHERE scope = $1 AND idempotency_key = $2`,
+		scope,
+		key,
+		statusCode,
+		contentType,
+		body,
+	)
+	return err
+}
+
+// Release forgets a request that failed so that it can be retried.
+func (ir *IdempotencyRepo) Release(ctx context.Context, scope string, key string) error {
+	_, err := ir.db.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE scope = $1 AND idempotency_key = $2 AND status_code IS NULL`,
+		scope,
+		key,
+	)
+	return err
+}
@@ -7,16 +7,28 @@ import (
 	"fmt"
 	"frappuccino/models"
 	"frappuccino/utils"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type InventoryRepoIfc interface {
 	Create(ctx context.Context, ingredient *models.Inventory) (*models.Inventory, error)
 	GetAll(ctx context.Context) ([]models.Inventory, error)
 	GetByID(ctx context.Context, ingredientId string) (models.Inventory, error)
+	GetStockLevel(ctx context.Context, locationId string, ingredientId string) (models.StockChange, error)
 	UpdateByID(ctx context.Context, ingredient *models.Inventory) error
+	Patch(ctx context.Context, ingredientId string, set map[string]any, quantity *utils.DEC) error
 	DeleteByID(ctx context.Context, ingerdientID string) error
+	Restore(ctx context.Context, ingredientId string) error
 	CreateTransaction(ctx context.Context, inventoryItem *models.Inventory, status string) error
 	GetLeftOvers(ctx context.Context, pagenum int, pagesize int) (models.Page, error)
+	GetUsageByWeekday(ctx context.Context, from time.Time, to time.Time) ([]models.IngredientUsage, error)
+	RecordWaste(ctx context.Context, ingredientId string, waste *models.WasteRecord) (models.InventoryTransactions, error)
+	CreateBatch(ctx context.Context, batch *models.InventoryBatch) (*models.InventoryBatch, error)
+	GetBatches(ctx context.Context, ingredientId string) ([]models.InventoryBatch, error)
+	GetExpiring(ctx context.Context, before time.Time) ([]models.InventoryBatch, error)
+	WriteOffExpired(ctx context.Context) ([]models.InventoryBatch, error)
 }
 
 type InventoryRepo struct {
@@ -28,20 +40,20 @@ func NewInventoryRepo(db *sql.DB) *InventoryRepo {
 }
 
 func (ir *InventoryRepo) Create(ctx context.Context, ingredient *models.Inventory) (*models.Inventory, error) {
-	tx, err := ir.db.BeginTx(ctx, nil)
+	tx, err := beginTx(ctx, ir.db)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	err = ir.db.QueryRowContext(ctx,
-		`INSERT INTO inventory (ingredient_name, unit, quantity, reorder_level)
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO inventory (ingredient_name, unit, reorder_level, unit_cost)
         VALUES ($1, $2, $3, $4)
         RETURNING ingredient_id, created_at, updated_at`,
 		ingredient.IngredientName,
 		ingredient.Unit,
-		ingredient.Quantity,
 		ingredient.ReorderLevel,
+		ingredient.UnitCost,
 	).Scan(
 		&ingredient.IngredientId,
 		&ingredient.CreatedAt,
@@ -51,11 +63,23 @@ func (ir *InventoryRepo) Create(ctx context.Context, ingredient *models.Inventor
 		return nil, err
 	}
 
+	// The initial quantity is stocked at the selected location
+	err = setStockLevel(ctx, tx.Tx, utils.LocationOrDefault(ctx), string(ingredient.IngredientId), ingredient.Quantity)
+	if err != nil {
+		return nil, err
+	}
+
 	return ingredient, tx.Commit()
 }
 
 func (ir *InventoryRepo) GetAll(ctx context.Context) ([]models.Inventory, error) {
-	rows, err := ir.db.QueryContext(ctx, `SELECT * FROM inventory`)
+	rows, err := ir.db.QueryContext(ctx,
+		`SELECT i.ingredient_id, i.ingredient_name, i.unit, COALESCE(l.quantity, 0), i.reorder_level, i.unit_cost, i.created_at, i.updated_at
+		FROM inventory i
+		LEFT JOIN inventory_levels l ON l.ingredient_id = i.ingredient_id AND l.location_id = $1
+		WHERE i.deleted_at IS NULL`,
+		utils.LocationOrDefault(ctx),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -64,7 +88,7 @@ func (ir *InventoryRepo) GetAll(ctx context.Context) ([]models.Inventory, error)
 	var inventory []models.Inventory
 	for rows.Next() {
 		var ingredient models.Inventory
-		err := rows.Scan(&ingredient.IngredientId, &ingredient.IngredientName, &ingredient.Unit, &ingredient.Quantity, &ingredient.ReorderLevel, &ingredient.CreatedAt, &ingredient.UpdatedAt)
+		err := rows.Scan(&ingredient.IngredientId, &ingredient.IngredientName, &ingredient.Unit, &ingredient.Quantity, &ingredient.ReorderLevel, &ingredient.UnitCost, &ingredient.CreatedAt, &ingredient.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -75,10 +99,17 @@ func (ir *InventoryRepo) GetAll(ctx context.Context) ([]models.Inventory, error)
 
 func (ir *InventoryRepo) GetByID(ctx context.Context, ingredientId string) (models.Inventory, error) {
 	var ingredient models.Inventory
-	err := ir.db.QueryRowContext(ctx, `SELECT * FROM inventory WHERE ingredient_id=$1`, ingredientId).Scan(&ingredient.IngredientId, &ingredient.IngredientName, &ingredient.Unit, &ingredient.Quantity, &ingredient.ReorderLevel, &ingredient.CreatedAt, &ingredient.UpdatedAt)
+	err := conn(ctx, ir.db).QueryRowContext(ctx,
+		`SELECT i.ingredient_id, i.ingredient_name, i.unit, COALESCE(l.quantity, 0), i.reorder_level, i.unit_cost, i.created_at, i.updated_at
+		FROM inventory i
+		LEFT JOIN inventory_levels l ON l.ingredient_id = i.ingredient_id AND l.location_id = $2
+		WHERE i.ingredient_id=$1 AND i.deleted_at IS NULL`,
+		ingredientId,
+		utils.LocationOrDefault(ctx),
+	).Scan(&ingredient.IngredientId, &ingredient.IngredientName, &ingredient.Unit, &ingredient.Quantity, &ingredient.ReorderLevel, &ingredient.UnitCost, &ingredient.CreatedAt, &ingredient.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return models.Inventory{}, err
+			return models.Inventory{}, utils.ErrIdNotFound
 		}
 		return models.Inventory{}, err
 	}
@@ -86,25 +117,53 @@ func (ir *InventoryRepo) GetByID(ctx context.Context, ingredientId string) (mode
 	return ingredient, nil
 }
 
-func (ir *InventoryRepo) UpdateByID(ctx context.Context, ingredient models.Inventory) error {
-	tx, err := ir.db.BeginTx(ctx, nil)
+// GetStockLevel returns the stock of an ingredient at a location, zero when
+// the location never held any. Change is left for the caller to fill in.
+func (ir *InventoryRepo) GetStockLevel(ctx context.Context, locationId string, ingredientId string) (models.StockChange, error) {
+	level := models.StockChange{LocationId: utils.TEXT(locationId)}
+	err := conn(ctx, ir.db).QueryRowContext(ctx,
+		`SELECT i.ingredient_id, i.ingredient_name, i.unit, COALESCE(l.quantity, 0), i.reorder_level
+		FROM inventory i
+		LEFT JOIN inventory_levels l ON l.ingredient_id = i.ingredient_id AND l.location_id = $2
+		WHERE i.ingredient_id = $1`,
+		ingredientId,
+		locationId,
+	).Scan(&level.IngredientId, &level.IngredientName, &level.Unit, &level.Quantity, &level.ReorderLevel)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.StockChange{}, utils.ErrIdNotFound
+		}
+		return models.StockChange{}, err
+	}
+
+	return level, nil
+}
+
+// UpdateByID writes the ingredient and sets its stock at the selected
+// location, see setStockLevel.
+func (ir *InventoryRepo) UpdateByID(ctx context.Context, ingredient *models.Inventory) error {
+	tx, err := beginTx(ctx, ir.db)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	if err := checkVersion(ctx, tx.Tx, "inventory", "ingredient_id", string(ingredient.IngredientId)); err != nil {
+		return err
+	}
+
 	res, err := tx.ExecContext(ctx,
 		`UPDATE inventory
 	SET ingredient_name = $1,
 		unit = $2,
-		quantity= $3,
-		reorder_level =$4,
-	WHERE ingredient_id =$5
+		reorder_level =$3,
+		unit_cost = $4
+	WHERE ingredient_id =$5 AND deleted_at IS NULL
 	`,
 		ingredient.IngredientName,
 		ingredient.Unit,
-		ingredient.Quantity,
 		ingredient.ReorderLevel,
+		ingredient.UnitCost,
 		ingredient.IngredientId,
 	)
 	if err != nil {
@@ -123,45 +182,143 @@ func (ir *InventoryRepo) UpdateByID(ctx context.Context, ingredient models.Inven
 		return utils.ErrIdNotFound
 	}
 
+	err = setStockLevel(ctx, tx.Tx, utils.LocationOrDefault(ctx), string(ingredient.IngredientId), ingredient.Quantity)
+	if err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
-func (ir *InventoryRepo) DeleteByID(ctx context.Context, ingerdientID string) error {
-	tx, err := ir.db.BeginTx(ctx, nil)
+// inventoryPatchColumns lists the columns a merge patch may change.
+var inventoryPatchColumns = map[string]bool{
+	"ingredient_name": true,
+	"unit":            true,
+	"reorder_level":   true,
+	"unit_cost":       true,
+}
+
+// Patch writes the patched columns and, when quantity is given, sets the
+// stock of the selected location, see setStockLevel.
+func (ir *InventoryRepo) Patch(ctx context.Context, ingredientId string, set map[string]any, quantity *utils.DEC) error {
+	tx, err := beginTx(ctx, ir.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	res, err := tx.ExecContext(ctx,
-		`DELETE FROM inventory WHERE ingredient_id= $1`,
-		ingerdientID,
+	if err := checkVersion(ctx, tx.Tx, "inventory", "ingredient_id", ingredientId); err != nil {
+		return err
+	}
+	err = patchRow(ctx, tx.Tx, "inventory", "ingredient_id", ingredientId, set, inventoryPatchColumns)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return utils.ErrConflictFields
+		}
+		return err
+	}
+
+	if quantity != nil {
+		if err := setStockLevel(ctx, tx.Tx, utils.LocationOrDefault(ctx), ingredientId, *quantity); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// setStockLevel sets the stock of an ingredient at a location the way a
+// stock count does: the difference is booked as an ADJUST transaction, and
+// the batches are drawn down for a loss or given a batch without expiry for
+// a gain, so that they keep adding up to the stock.
+func setStockLevel(ctx context.Context, tx *sql.Tx, locationId string, ingredientId string, quantity utils.DEC) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO inventory_levels (location_id, ingredient_id)
+		VALUES ($1, $2)
+		ON CONFLICT (location_id, ingredient_id) DO NOTHING`,
+		locationId,
+		ingredientId,
 	)
 	if err != nil {
 		return err
 	}
 
-	rowsAffected, err := res.RowsAffected()
+	var current utils.DEC
+	err = tx.QueryRowContext(ctx,
+		`SELECT quantity FROM inventory_levels WHERE location_id = $1 AND ingredient_id = $2 FOR UPDATE`,
+		locationId,
+		ingredientId,
+	).Scan(&current)
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
-		return utils.ErrIdNotFound
+	variance := quantity - current
+	if variance == 0 {
+		return nil
 	}
 
-	return tx.Commit()
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO inventory_transactions (location_id, ingredient_id, quantity, inventory_transaction_action, notes)
+		VALUES ($1, $2, $3, 'ADJUST', 'Quantity set')`,
+		locationId,
+		ingredientId,
+		variance,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE inventory_levels SET quantity = $3 WHERE location_id = $1 AND ingredient_id = $2`,
+		locationId,
+		ingredientId,
+		quantity,
+	)
+	if err != nil {
+		return err
+	}
+
+	if variance < 0 {
+		_, err = tx.ExecContext(ctx,
+			`SELECT consume_inventory_batches($1, $2, $3)`,
+			locationId,
+			ingredientId,
+			-variance,
+		)
+	} else {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO inventory_batches (location_id, ingredient_id, quantity_received, quantity_remaining, notes)
+			VALUES ($1, $2, $3, $3, 'Quantity set')`,
+			locationId,
+			ingredientId,
+			variance,
+		)
+	}
+	return err
+}
+
+func (ir *InventoryRepo) DeleteByID(ctx context.Context, ingerdientID string) error {
+	return archiveRow(ctx, ir.db, "inventory", "ingredient_id", ingerdientID)
+}
+
+// Restore fails with ErrConflictFields when an active ingredient has taken
+// the name in the meantime.
+func (ir *InventoryRepo) Restore(ctx context.Context, ingredientId string) error {
+	return restoreRow(ctx, ir.db, "inventory", "ingredient_id", ingredientId)
 }
 
 func (ir *InventoryRepo) CreateTransaction(ctx context.Context, inventoryItem *models.Inventory, status string) error {
-	tx, err := ir.db.BeginTx(ctx, nil)
+	tx, err := beginTx(ctx, ir.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
 	_, err = tx.ExecContext(ctx,
-		`INSERT INTO inventory_transactions (ingredient_id, quantity, inventory_transaction_action)
-        VALUES($1, $2, $3)`,
+		`INSERT INTO inventory_transactions (location_id, ingredient_id, quantity, inventory_transaction_action)
+        VALUES($1, $2, $3, $4)`,
+		utils.LocationOrDefault(ctx),
 		inventoryItem.IngredientId,
 		inventoryItem.Quantity,
 		status,
@@ -177,16 +334,20 @@ func (ir *InventoryRepo) CreateTransaction(ctx context.Context, inventoryItem *m
 func (ir *InventoryRepo) GetLeftOvers(ctx context.Context, pagenum int, pagesize int) (models.Page, error) {
 	rows, err := ir.db.QueryContext(ctx,
 		`WITH total AS (
-		SELECT COUNT(*) AS total_count FROM inventory
+		SELECT COUNT(*) AS total_count FROM inventory WHERE deleted_at IS NULL
 		)
 		SELECT 
-			i.ingredient_name, i.quantity, total.total_count
-		FROM inventory i, total
-		ORDER BY i.quantity DESC
+			i.ingredient_name, COALESCE(l.quantity, 0) AS quantity, total.total_count
+		FROM inventory i
+		CROSS JOIN total
+		LEFT JOIN inventory_levels l ON l.ingredient_id = i.ingredient_id AND l.location_id = $3
+		WHERE i.deleted_at IS NULL
+		ORDER BY quantity DESC
 		LIMIT $1 OFFSET $2;
 	`,
 		pagesize,
 		(pagenum-1)*pagesize,
+		utils.LocationOrDefault(ctx),
 	)
 	if err != nil {
 		return models.Page{}, err
@@ -216,3 +377,330 @@ func (ir *InventoryRepo) GetLeftOvers(ctx context.Context, pagenum int, pagesize
 
 	return response, nil
 }
+
+// GetUsageByWeekday sums REMOVE transactions of the selected location in
+// [from, to) per ingredient and UTC weekday. Ingredients without usage are
+// returned with zero totals.
+func (ir *InventoryRepo) GetUsageByWeekday(ctx context.Context, from time.Time, to time.Time) ([]models.IngredientUsage, error) {
+	rows, err := ir.db.QueryContext(ctx,
+		`SELECT
+			i.ingredient_id,
+			i.ingredient_name,
+			i.unit,
+			COALESCE(l.quantity, 0),
+			i.reorder_level,
+			COALESCE(EXTRACT(ISODOW FROM t.created_at AT TIME ZONE 'UTC')::int, 0),
+			COALESCE(SUM(ABS(t.quantity)), 0)
+		FROM inventory i
+		LEFT JOIN inventory_levels l
+			ON l.ingredient_id = i.ingredient_id
+			AND l.location_id = $3
+		LEFT JOIN inventory_transactions t
+			ON t.ingredient_id = i.ingredient_id
+			AND t.location_id = $3
+			AND t.inventory_transaction_action = 'REMOVE'
+			AND t.created_at >= $1 AND t.created_at < $2
+		WHERE i.deleted_at IS NULL
+		GROUP BY i.ingredient_id, l.quantity, 6
+		ORDER BY i.ingredient_name;`,
+		from,
+		to,
+		utils.LocationOrDefault(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usage []models.IngredientUsage
+	for rows.Next() {
+		var item models.IngredientUsage
+		var weekday int
+		var total float64
+
+		err := rows.Scan(&item.IngredientId, &item.IngredientName, &item.Unit, &item.Quantity, &item.ReorderLevel, &weekday, &total)
+		if err != nil {
+			return nil, err
+		}
+		if len(usage) == 0 || usage[len(usage)-1].IngredientId != item.IngredientId {
+			usage = append(usage, item)
+		}
+		if weekday >= 1 && weekday <= 7 {
+			usage[len(usage)-1].WeekdayTotals[weekday-1] += total
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+// RecordWaste writes off stock of the selected location for a non-sale reason.
+// The transaction stores the outflow as a negative quantity, like the
+// automatic REMOVE rows.
+func (ir *InventoryRepo) RecordWaste(ctx context.Context, ingredientId string, waste *models.WasteRecord) (models.InventoryTransactions, error) {
+	tx, err := beginTx(ctx, ir.db)
+	if err != nil {
+		return models.InventoryTransactions{}, err
+	}
+	defer tx.Rollback()
+
+	locationId := utils.LocationOrDefault(ctx)
+	res, err := tx.ExecContext(ctx,
+		`UPDATE inventory_levels
+		SET quantity = quantity - $1
+		WHERE location_id = $2 AND ingredient_id = $3 AND quantity >= $1`,
+		waste.Quantity,
+		locationId,
+		ingredientId,
+	)
+	if err != nil {
+		return models.InventoryTransactions{}, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return models.InventoryTransactions{}, err
+	}
+	if rowsAffected == 0 {
+		var exists bool
+		err = tx.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM inventory WHERE ingredient_id = $1)`,
+			ingredientId,
+		).Scan(&exists)
+		if err != nil {
+			return models.InventoryTransactions{}, err
+		}
+		if !exists {
+			return models.InventoryTransactions{}, utils.ErrIdNotFound
+		}
+		return models.InventoryTransactions{}, utils.ErrInsufficientStock
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`SELECT consume_inventory_batches($1, $2, $3)`,
+		locationId,
+		ingredientId,
+		waste.Quantity,
+	)
+	if err != nil {
+		return models.InventoryTransactions{}, err
+	}
+
+	transaction := models.InventoryTransactions{
+		IngredientId:               utils.TEXT(ingredientId),
+		InventoryTransactionAction: waste.Reason,
+		Quantity:                   -waste.Quantity,
+		Notes:                      waste.Notes,
+	}
+	var createdAt time.Time
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO inventory_transactions (location_id, ingredient_id, quantity, inventory_transaction_action, notes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING inventory_transactions_id, created_at`,
+		locationId,
+		ingredientId,
+		transaction.Quantity,
+		waste.Reason,
+		waste.Notes,
+	).Scan(&transaction.InventoryTransactionId, &createdAt)
+	if err != nil {
+		return models.InventoryTransactions{}, err
+	}
+	transaction.CreatedAt = utils.TIME(createdAt)
+
+	return transaction, tx.Commit()
+}
+
+// CreateBatch receives a new lot of an ingredient at the selected location:
+// the batch, its ADD transaction and the stock increase are written together.
+func (ir *InventoryRepo) CreateBatch(ctx context.Context, batch *models.InventoryBatch) (*models.InventoryBatch, error) {
+	tx, err := beginTx(ctx, ir.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		`SELECT ingredient_name FROM inventory WHERE ingredient_id = $1 AND deleted_at IS NULL`,
+		batch.IngredientId,
+	).Scan(&batch.IngredientName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrIdNotFound
+		}
+		return nil, err
+	}
+
+	locationId := utils.LocationOrDefault(ctx)
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO inventory_levels (location_id, ingredient_id, quantity)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (location_id, ingredient_id) DO UPDATE SET quantity = inventory_levels.quantity + EXCLUDED.quantity`,
+		locationId,
+		batch.IngredientId,
+		batch.QuantityReceived,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	batch.QuantityRemaining = batch.QuantityReceived
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO inventory_batches (location_id, ingredient_id, quantity_received, quantity_remaining, expires_at, notes)
+		VALUES ($1, $2, $3, $3, $4, $5)
+		RETURNING batch_id, received_at`,
+		locationId,
+		batch.IngredientId,
+		batch.QuantityReceived,
+		batch.ExpiresAt,
+		batch.Notes,
+	).Scan(&batch.BatchId, &batch.ReceivedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO inventory_transactions (location_id, ingredient_id, quantity, inventory_transaction_action, reference_id, notes)
+		VALUES ($1, $2, $3, 'ADD', $4, 'Batch received')`,
+		locationId,
+		batch.IngredientId,
+		batch.QuantityReceived,
+		batch.BatchId,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return batch, tx.Commit()
+}
+
+func (ir *InventoryRepo) GetBatches(ctx context.Context, ingredientId string) ([]models.InventoryBatch, error) {
+	rows, err := ir.db.QueryContext(ctx,
+		`SELECT b.batch_id, b.ingredient_id, i.ingredient_name, b.quantity_received, b.quantity_remaining, b.expires_at, b.notes, b.received_at
+		FROM inventory_batches b
+		JOIN inventory i ON i.ingredient_id = b.ingredient_id
+		WHERE b.ingredient_id = $1 AND b.location_id = $2
+		ORDER BY b.expires_at NULLS LAST, b.received_at`,
+		ingredientId,
+		utils.LocationOrDefault(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBatches(rows)
+}
+
+// GetExpiring lists batches with stock left that expire before the given
+// time, including the ones that already expired.
+func (ir *InventoryRepo) GetExpiring(ctx context.Context, before time.Time) ([]models.InventoryBatch, error) {
+	rows, err := conn(ctx, ir.db).QueryContext(ctx,
+		`SELECT b.batch_id, b.ingredient_id, i.ingredient_name, b.quantity_received, b.quantity_remaining, b.expires_at, b.notes, b.received_at
+		FROM inventory_batches b
+		JOIN inventory i ON i.ingredient_id = b.ingredient_id
+		WHERE b.location_id = $2 AND b.quantity_remaining > 0 AND b.expires_at <= $1
+		ORDER BY b.expires_at, i.ingredient_name`,
+		before,
+		utils.LocationOrDefault(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBatches(rows)
+}
+
+// WriteOffExpired empties every expired batch of the selected location and
+// records the lost stock as SPOILAGE transactions referencing the batch.
+func (ir *InventoryRepo) WriteOffExpired(ctx context.Context) ([]models.InventoryBatch, error) {
+	tx, err := beginTx(ctx, ir.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	locationId := utils.LocationOrDefault(ctx)
+	rows, err := tx.QueryContext(ctx,
+		`SELECT b.batch_id, b.ingredient_id, i.ingredient_name, b.quantity_received, b.quantity_remaining, b.expires_at, b.notes, b.received_at
+		FROM inventory_batches b
+		JOIN inventory i ON i.ingredient_id = b.ingredient_id
+		WHERE b.location_id = $1 AND b.quantity_remaining > 0 AND b.expires_at <= now()
+		ORDER BY b.expires_at
+		FOR UPDATE OF b`,
+		locationId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	batches, err := scanBatches(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, batch := range batches {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO inventory_transactions (location_id, ingredient_id, quantity, inventory_transaction_action, reference_id, notes)
+			VALUES ($1, $2, $3, 'SPOILAGE', $4, 'Expired batch')`,
+			locationId,
+			batch.IngredientId,
+			-batch.QuantityRemaining,
+			batch.BatchId,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE inventory_levels SET quantity = GREATEST(quantity - $1, 0) WHERE location_id = $2 AND ingredient_id = $3`,
+			batch.QuantityRemaining,
+			locationId,
+			batch.IngredientId,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE inventory_batches SET quantity_remaining = 0 WHERE batch_id = $1`,
+			batch.BatchId,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return batches, tx.Commit()
+}
+
+func scanBatches(rows *sql.Rows) ([]models.InventoryBatch, error) {
+	var batches []models.InventoryBatch
+	for rows.Next() {
+		var batch models.InventoryBatch
+		err := rows.Scan(
+			&batch.BatchId,
+			&batch.IngredientId,
+			&batch.IngredientName,
+			&batch.QuantityReceived,
+			&batch.QuantityRemaining,
+			&batch.ExpiresAt,
+			&batch.Notes,
+			&batch.ReceivedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, batch)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return batches, nil
+}
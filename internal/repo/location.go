@@ -0,0 +1,253 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"frappuccino/models"
+	"frappuccino/utils"
+
+	"github.com/lib/pq"
+)
+
+type LocationRepoIfc interface {
+	Create(ctx context.Context, location *models.Location) (*models.Location, error)
+	GetAll(ctx context.Context) ([]models.Location, error)
+	GetByID(ctx context.Context, locationId string) (models.Location, error)
+	UpdateByID(ctx context.Context, location *models.Location) error
+	DeleteByID(ctx context.Context, locationId string) error
+	GetMenuPrices(ctx context.Context, locationId string) ([]models.LocationMenuPrice, error)
+	SetMenuPrice(ctx context.Context, price *models.LocationMenuPrice) error
+	DeleteMenuPrice(ctx context.Context, locationId string, menuItemId string) error
+}
+
+type LocationRepo struct {
+	db *sql.DB
+}
+
+func NewLocationRepo(db *sql.DB) *LocationRepo {
+	return &LocationRepo{db: db}
+}
+
+func (lr *LocationRepo) Create(ctx context.Context, location *models.Location) (*models.Location, error) {
+	err := lr.db.QueryRowContext(ctx,
+		`INSERT INTO locations (location_name, address, timezone)
+		VALUES ($1, $2, $3)
+		RETURNING location_id, created_at, updated_at`,
+		location.LocationName,
+		location.Address,
+		location.Timezone,
+	).Scan(
+		&location.LocationId,
+		&location.CreatedAt,
+		&location.UpdatedAt,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, utils.ErrConflictFields
+		}
+		return nil, err
+	}
+
+	return location, nil
+}
+
+func (lr *LocationRepo) GetAll(ctx context.Context) ([]models.Location, error) {
+	rows, err := lr.db.QueryContext(ctx,
+		`SELECT location_id, location_name, address, timezone, created_at, updated_at
+		FROM locations
+		ORDER BY location_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locations []models.Location
+	for rows.Next() {
+		var location models.Location
+		err := rows.Scan(
+			&location.LocationId,
+			&location.LocationName,
+			&location.Address,
+			&location.Timezone,
+			&location.CreatedAt,
+			&location.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, location)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+func (lr *LocationRepo) GetByID(ctx context.Context, locationId string) (models.Location, error) {
+	var location models.Location
+	err := lr.db.QueryRowContext(ctx,
+		`SELECT location_id, location_name, address, timezone, created_at, updated_at
+		FROM locations
+		WHERE location_id = $1`,
+		locationId,
+	).Scan(
+		&location.LocationId,
+		&location.LocationName,
+		&location.Address,
+		&location.Timezone,
+		&location.CreatedAt,
+		&location.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Location{}, utils.ErrIdNotFound
+		}
+		return models.Location{}, err
+	}
+
+	return location, nil
+}
+
+func (lr *LocationRepo) UpdateByID(ctx context.Context, location *models.Location) error {
+	res, err := lr.db.ExecContext(ctx,
+		`UPDATE locations
+		SET location_name = $1,
+			address = $2,
+			timezone = $3
+		WHERE location_id = $4`,
+		location.LocationName,
+		location.Address,
+		location.Timezone,
+		location.LocationId,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return utils.ErrConflictFields
+		}
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return utils.ErrIdNotFound
+	}
+
+	return nil
+}
+
+// DeleteByID removes a location that never held stock or took orders; the
+// foreign keys refuse to drop any history.
+func (lr *LocationRepo) DeleteByID(ctx context.Context, locationId string) error {
+	res, err := lr.db.ExecContext(ctx,
+		`DELETE FROM locations WHERE location_id = $1`,
+		locationId,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return utils.ErrLocationInUse
+		}
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return utils.ErrIdNotFound
+	}
+
+	return nil
+}
+
+// GetMenuPrices lists the whole menu as offered at the location, with the
+// override of each item when one is set.
+func (lr *LocationRepo) GetMenuPrices(ctx context.Context, locationId string) ([]models.LocationMenuPrice, error) {
+	rows, err := lr.db.QueryContext(ctx,
+		`SELECT m.menu_item_id, m.item_name, m.price, p.price, COALESCE(p.is_available, true)
+		FROM menu_items m
+		LEFT JOIN location_menu_prices p ON p.menu_item_id = m.menu_item_id AND p.location_id = $1
+		WHERE m.deleted_at IS NULL
+		ORDER BY m.item_name`,
+		locationId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []models.LocationMenuPrice
+	for rows.Next() {
+		price := models.LocationMenuPrice{LocationId: utils.TEXT(locationId)}
+		var override sql.NullFloat64
+		err := rows.Scan(&price.MenuItemId, &price.ItemName, &price.BasePrice, &override, &price.IsAvailable)
+		if err != nil {
+			return nil, err
+		}
+		if override.Valid {
+			value := utils.DEC(override.Float64)
+			price.Price = &value
+		}
+		prices = append(prices, price)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+func (lr *LocationRepo) SetMenuPrice(ctx context.Context, price *models.LocationMenuPrice) error {
+	_, err := lr.db.ExecContext(ctx,
+		`INSERT INTO location_menu_prices (location_id, menu_item_id, price, is_available)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (location_id, menu_item_id) DO UPDATE
+		SET price = EXCLUDED.price,
+			is_available = EXCLUDED.is_available,
+			updated_at = now()`,
+		price.LocationId,
+		price.MenuItemId,
+		price.Price,
+		price.IsAvailable,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return utils.ErrIdNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (lr *LocationRepo) DeleteMenuPrice(ctx context.Context, locationId string, menuItemId string) error {
+	res, err := lr.db.ExecContext(ctx,
+		`DELETE FROM location_menu_prices WHERE location_id = $1 AND menu_item_id = $2`,
+		locationId,
+		menuItemId,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return utils.ErrIdNotFound
+	}
+
+	return nil
+}
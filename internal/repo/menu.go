@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"frappuccino/models"
 	"frappuccino/utils"
 
@@ -15,11 +16,15 @@ type MenuRepoIfc interface {
 	GetAll(ctx context.Context) ([]models.MenuItems, error)
 	GetByID(ctx context.Context, menuItemId string) (models.MenuItems, error)
 	UpdateByID(ctx context.Context, menuItem models.MenuItems) error
+	Patch(ctx context.Context, menuItemId string, set map[string]any) error
 	DeleteByID(ctx context.Context, menuItemId string) error
+	Restore(ctx context.Context, menuItemId string) error
 
 	CreatePriceHistory(ctx context.Context, menuItemId string, Price float64) error
 	CreateIngredient(ctx context.Context, Ingredient *models.MenuItemsIngredients, menuItemName string) error
 	GetMenuItemPriceByName(ctx context.Context, menuItemName string) (float64, error)
+	GetBasePrice(ctx context.Context, menuItemId string) (utils.DEC, error)
+	Suggest(ctx context.Context, q string, limit int) ([]models.MenuSuggestion, error)
 }
 
 type MenuRepo struct {
@@ -31,27 +36,22 @@ func NewMenuRepo(db *sql.DB) *MenuRepo {
 }
 
 func (mr *MenuRepo) Create(ctx context.Context, menuItem models.MenuItems) (models.MenuItems, error) {
-	tx, err := mr.db.BeginTx(ctx, nil)
+	tx, err := beginTx(ctx, mr.db)
 	if err != nil {
 		return models.MenuItems{}, err
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-		} else {
-			err = tx.Commit()
-		}
-	}()
+	defer tx.Rollback()
 
 	// Вставка элемента меню
-	err = mr.db.QueryRowContext(ctx,
-		`INSERT INTO menu_items (item_name, item_description, price, categories)
-	     VALUES ($1, $2, $3, $4)
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO menu_items (item_name, item_description, price, categories, station_id)
+	     VALUES ($1, $2, $3, $4, $5)
 		 RETURNING menu_item_id, created_at, updated_at`,
 		menuItem.ItemName,
 		menuItem.ItemDescription,
 		menuItem.Price,
 		pq.Array(menuItem.Categories),
+		menuItem.StationId,
 	).Scan(
 		&menuItem.MenuItemId,
 		&menuItem.CreatedAt,
@@ -59,14 +59,27 @@ func (mr *MenuRepo) Create(ctx context.Context, menuItem models.MenuItems) (mode
 	)
 
 	if err != nil {
-		return models.MenuItems{}, err
+		return models.MenuItems{}, stationError(err)
 	}
 
+	// Ингредиенты ссылаются на склад по имени
 	for _, ingredient := range menuItem.Ingredients {
+		var ingredientId string
+		err = tx.QueryRowContext(ctx,
+			`SELECT ingredient_id FROM inventory WHERE ingredient_name = $1 AND deleted_at IS NULL`,
+			ingredient.IngredientName,
+		).Scan(&ingredientId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.MenuItems{}, fmt.Errorf("%w: %s", utils.ErrUnknownIngredient, ingredient.IngredientName)
+		}
+		if err != nil {
+			return models.MenuItems{}, err
+		}
+
 		_, err = tx.ExecContext(ctx,
-			`INSERT INTO ingredients (menu_item_id, ingredient_name, quantity)
-			 VALUES ($1, $2, $3)`,
-			menuItem.MenuItemId, ingredient.IngredientName, ingredient.Quantity,
+			`INSERT INTO menu_item_ingredients (menu_item_id, ingredient_id, ingredient_name, quantity)
+			 VALUES ($1, $2, $3, $4)`,
+			menuItem.MenuItemId, ingredientId, ingredient.IngredientName, ingredient.Quantity,
 		)
 		if err != nil {
 			return models.MenuItems{}, err
@@ -74,11 +87,17 @@ func (mr *MenuRepo) Create(ctx context.Context, menuItem models.MenuItems) (mode
 	}
 
 	// Завершаем транзакцию
-	return menuItem, err
+	return menuItem, tx.Commit()
 }
 
 func (mr *MenuRepo) GetAll(ctx context.Context) ([]models.MenuItems, error) {
-	rows, err := mr.db.QueryContext(ctx, `SELECT * FROM menu_items`)
+	rows, err := mr.db.QueryContext(ctx,
+		`SELECT m.menu_item_id, m.item_name, m.item_description, COALESCE(p.price, m.price), m.categories, m.station_id, m.created_at, m.updated_at
+		FROM menu_items m
+		LEFT JOIN location_menu_prices p ON p.menu_item_id = m.menu_item_id AND p.location_id = $1
+		WHERE m.deleted_at IS NULL AND COALESCE(p.is_available, true)`,
+		utils.LocationOrDefault(ctx),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -93,6 +112,7 @@ func (mr *MenuRepo) GetAll(ctx context.Context) ([]models.MenuItems, error) {
 			&menuItem.ItemDescription,
 			&menuItem.Price,
 			pq.Array(&menuItem.Categories),
+			&menuItem.StationId,
 			&menuItem.CreatedAt,
 			&menuItem.UpdatedAt,
 		)
@@ -101,7 +121,7 @@ func (mr *MenuRepo) GetAll(ctx context.Context) ([]models.MenuItems, error) {
 		}
 
 		ingredientRows, err := mr.db.QueryContext(ctx,
-			`SELECT ingredient_name, quantity FROM ingredients WHERE menu_item_id = $1`, menuItem.MenuItemId)
+			`SELECT ingredient_name, quantity FROM menu_item_ingredients WHERE menu_item_id = $1`, menuItem.MenuItemId)
 		if err != nil {
 			return nil, err
 		}
@@ -130,15 +150,20 @@ func (mr *MenuRepo) GetAll(ctx context.Context) ([]models.MenuItems, error) {
 
 func (mr *MenuRepo) GetByID(ctx context.Context, menuItemId string) (models.MenuItems, error) {
 	var menuItem models.MenuItems
-	err := mr.db.QueryRowContext(ctx,
-		`SELECT * FROM menu_items WHERE menu_item_id = $1`,
+	err := conn(ctx, mr.db).QueryRowContext(ctx,
+		`SELECT m.menu_item_id, m.item_name, m.item_description, COALESCE(p.price, m.price), m.categories, m.station_id, m.created_at, m.updated_at
+		FROM menu_items m
+		LEFT JOIN location_menu_prices p ON p.menu_item_id = m.menu_item_id AND p.location_id = $2
+		WHERE m.menu_item_id = $1 AND m.deleted_at IS NULL`,
 		menuItemId,
+		utils.LocationOrDefault(ctx),
 	).Scan(
 		&menuItem.MenuItemId,
 		&menuItem.ItemName,
 		&menuItem.ItemDescription,
 		&menuItem.Price,
 		pq.Array(&menuItem.Categories),
+		&menuItem.StationId,
 		&menuItem.CreatedAt,
 		&menuItem.UpdatedAt,
 	)
@@ -150,8 +175,8 @@ func (mr *MenuRepo) GetByID(ctx context.Context, menuItemId string) (models.Menu
 	}
 
 	// Получаем ингредиенты для данного элемента меню
-	ingredientRows, err := mr.db.QueryContext(ctx,
-		`SELECT ingredient_name, quantity FROM ingredients WHERE menu_item_id = $1`, menuItemId)
+	ingredientRows, err := conn(ctx, mr.db).QueryContext(ctx,
+		`SELECT ingredient_name, quantity FROM menu_item_ingredients WHERE menu_item_id = $1`, menuItemId)
 	if err != nil {
 		return models.MenuItems{}, err
 	}
@@ -173,12 +198,16 @@ func (mr *MenuRepo) GetByID(ctx context.Context, menuItemId string) (models.Menu
 }
 
 func (mr *MenuRepo) UpdateByID(ctx context.Context, menuItem models.MenuItems) error {
-	tx, err := mr.db.BeginTx(ctx, nil)
+	tx, err := beginTx(ctx, mr.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if err := checkVersion(ctx, tx.Tx, "menu_items", "menu_item_id", string(menuItem.MenuItemId)); err != nil {
+		return err
+	}
+
 	// Обновление данных меню
 	res, err := tx.ExecContext(ctx,
 		`UPDATE menu_items
@@ -187,16 +216,18 @@ func (mr *MenuRepo) UpdateByID(ctx context.Context, menuItem models.MenuItems) e
 			item_description = $2,
 			price = $3,
 			categories = $4,
+			station_id = $5,
 			updated_at = NOW()
-		WHERE menu_item_id = $5`,
+		WHERE menu_item_id = $6 AND deleted_at IS NULL`,
 		menuItem.ItemName,
 		menuItem.ItemDescription,
 		menuItem.Price,
 		pq.Array(menuItem.Categories), // Если используется pq.Array для массивов
+		menuItem.StationId,
 		menuItem.MenuItemId,
 	)
 	if err != nil {
-		return err // Непосредственно возвращаем ошибку
+		return stationError(err) // Непосредственно возвращаем ошибку
 	}
 
 	rowsAffected, err := res.RowsAffected()
@@ -247,30 +278,46 @@ func (mr *MenuRepo) UpdateByID(ctx context.Context, menuItem models.MenuItems) e
 	return tx.Commit()
 }
 
-func (mr *MenuRepo) DeleteByID(ctx context.Context, menuItemId string) error {
-	tx, err := mr.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
+// menuPatchColumns lists the columns a merge patch may change.
+var menuPatchColumns = map[string]bool{
+	"item_name":        true,
+	"item_description": true,
+	"price":            true,
+	"categories":       true,
+	"station_id":       true,
+}
+
+func (mr *MenuRepo) Patch(ctx context.Context, menuItemId string, set map[string]any) error {
+	if categories, ok := set["categories"]; ok {
+		set["categories"] = pq.Array(categories)
 	}
-	defer tx.Rollback()
 
-	res, err := tx.ExecContext(ctx, `DELETE FROM menu_item_id WHERE id= $1`, menuItemId)
+	tx, err := beginTx(ctx, mr.db)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
+	if err := checkVersion(ctx, tx.Tx, "menu_items", "menu_item_id", menuItemId); err != nil {
 		return err
 	}
-
-	if rowsAffected == 0 {
-		return utils.ErrIdNotFound
+	if err := patchRow(ctx, tx.Tx, "menu_items", "menu_item_id", menuItemId, set, menuPatchColumns); err != nil {
+		return stationError(err)
 	}
 
 	return tx.Commit()
 }
 
+// DeleteByID archives the item: order_items keep referencing it, so it can
+// no longer be ordered but still shows up in past orders and reports.
+func (mr *MenuRepo) DeleteByID(ctx context.Context, menuItemId string) error {
+	return archiveRow(ctx, mr.db, "menu_items", "menu_item_id", menuItemId)
+}
+
+func (mr *MenuRepo) Restore(ctx context.Context, menuItemId string) error {
+	return restoreRow(ctx, mr.db, "menu_items", "menu_item_id", menuItemId)
+}
+
 func (mr *MenuRepo) CreatePriceHistory(ctx context.Context, menuItemId string, price float64) error {
 	tx, err := mr.db.Begin()
 
@@ -296,8 +343,8 @@ func (mr *MenuRepo) CreateIngredient(ctx context.Context, ingredient *models.Men
 	_, err = tx.ExecContext(ctx,
 		`INSERT INTO menu_item_ingredients(menu_item_id, ingredient_id, ingredient_name,quantity)
         VALUES(
-			(SELECT menu_item_id FROM menu_items WHERE item_name = $1), 
-			(SELECT ingredient_id FROM inventory WHERE ingredient_name = $2), 
+			(SELECT menu_item_id FROM menu_items WHERE item_name = $1 AND deleted_at IS NULL), 
+			(SELECT ingredient_id FROM inventory WHERE ingredient_name = $2 AND deleted_at IS NULL), 
 			$3, 
 			$4
 		);`,
@@ -317,8 +364,13 @@ func (mr *MenuRepo) CreateIngredient(ctx context.Context, ingredient *models.Men
 func (mr *MenuRepo) GetMenuItemPriceByName(ctx context.Context, menuItemName string) (float64, error) {
 	var menuItemPrice float64
 
-	err := mr.db.QueryRowContext(ctx, `SELECT price FROM menu_items WHERE item_name=$1`,
+	err := mr.db.QueryRowContext(ctx,
+		`SELECT COALESCE(p.price, m.price)
+		FROM menu_items m
+		LEFT JOIN location_menu_prices p ON p.menu_item_id = m.menu_item_id AND p.location_id = $2
+		WHERE m.item_name=$1 AND m.deleted_at IS NULL`,
 		menuItemName,
+		utils.LocationOrDefault(ctx),
 	).Scan(
 		&menuItemPrice,
 	)
@@ -328,3 +380,76 @@ func (mr *MenuRepo) GetMenuItemPriceByName(ctx context.Context, menuItemName str
 
 	return menuItemPrice, nil
 }
+
+// stationError reports a station_id naming no station as such.
+func stationError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" && pqErr.Constraint == "menu_items_station_id_fkey" {
+		return utils.ErrInvalidStationId
+	}
+	return err
+}
+
+// GetBasePrice returns the price of an item before location overrides. Within
+// WithinTx it locks the item until the transaction ends.
+func (mr *MenuRepo) GetBasePrice(ctx context.Context, menuItemId string) (utils.DEC, error) {
+	var price utils.DEC
+	err := conn(ctx, mr.db).QueryRowContext(ctx,
+		`SELECT price FROM menu_items WHERE menu_item_id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		menuItemId,
+	).Scan(&price)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, utils.ErrIdNotFound
+	}
+	return price, err
+}
+
+// Suggest matches q against item names and categories. The name match is a
+// branch of its own so that it can use the trigram index on item_name; an
+// item matching both ways keeps its better score.
+func (mr *MenuRepo) Suggest(ctx context.Context, q string, limit int) ([]models.MenuSuggestion, error) {
+	rows, err := mr.db.QueryContext(ctx,
+		`SELECT menu_item_id, item_name, categories, MAX(score) AS score
+		FROM (
+			SELECT menu_item_id, item_name, categories, word_similarity($1, item_name) AS score
+			FROM menu_items
+			WHERE deleted_at IS NULL AND $1 <% item_name
+			UNION ALL
+			SELECT m.menu_item_id, m.item_name, m.categories, MAX(similarity(c, $1))
+			FROM menu_items m
+			CROSS JOIN LATERAL unnest(m.categories) AS c
+			WHERE m.deleted_at IS NULL AND c % $1
+			GROUP BY m.menu_item_id
+		) AS matches
+		GROUP BY menu_item_id, item_name, categories
+		ORDER BY score DESC, item_name
+		LIMIT $2`,
+		q,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suggestions []models.MenuSuggestion
+	for rows.Next() {
+		var suggestion models.MenuSuggestion
+		err := rows.Scan(
+			&suggestion.MenuItemId,
+			&suggestion.ItemName,
+			pq.Array(&suggestion.Categories),
+			&suggestion.Score,
+		)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
@@ -3,9 +3,13 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"frappuccino/models"
 	"frappuccino/utils"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type OrderRepoIfc interface {
@@ -13,8 +17,11 @@ type OrderRepoIfc interface {
 	GetAll(ctx context.Context) ([]models.Orders, error)
 	GetOrderByID(ctx context.Context, orderId string) (models.Orders, error)
 	UpdateItemByID(ctx context.Context, order *models.Orders) error
+	Patch(ctx context.Context, orderId string, set map[string]any, items []models.OrderItems) error
 	DeleteItemByID(ctx context.Context, orderId string) error
-	checkAndUpdateInventory(ctx context.Context, tx *sql.Tx, orderItems []models.OrderItems) error
+	NumberOfOrderedItems(ctx context.Context, from *time.Time, to *time.Time) ([]models.OrderedItem, error)
+	Restore(ctx context.Context, orderId string) error
+	GetIngredientUsage(ctx context.Context, orderId string) ([]models.StockChange, error)
 	getOrderItemsByOrderID(ctx context.Context, orderId string) ([]models.OrderItems, error)
 }
 
@@ -26,24 +33,27 @@ func NewOrderRepo(db *sql.DB) *OrderRepo {
 	return &OrderRepo{db: db}
 }
 
+// Create inserts the order with its lines and the payments given with it.
+// Lines are named and priced from the menu at the order's location; names
+// and prices sent by the client are ignored. The location must have the
+// ingredients of every line in stock.
 func (or *OrderRepo) Create(ctx context.Context, order *models.Orders) (*models.Orders, error) {
-	// Начало транзакции
-	tx, err := or.db.BeginTx(ctx, nil)
+	tx, err := beginTx(ctx, or.db)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback() // Откатить транзакцию в случае ошибки
-		} else {
-			err = tx.Commit() // Подтвердить транзакцию в случае успеха
-		}
-	}()
+	defer tx.Rollback()
 
 	var totalPrice utils.DEC
 
 	// Суммируем стоимость всех элементов заказа
-	for _, item := range order.OrderItems {
+	locationId := utils.LocationOrDefault(ctx)
+	for i := range order.OrderItems {
+		item := &order.OrderItems[i]
+		item.ItemName, item.UnitPrice, err = menuItemPrice(ctx, tx.Tx, string(item.MenuItemId), locationId)
+		if err != nil {
+			return nil, err
+		}
 		totalPrice += item.Quantity * item.UnitPrice
 	}
 
@@ -52,57 +62,111 @@ func (or *OrderRepo) Create(ctx context.Context, order *models.Orders) (*models.
 
 	// Вставка данных заказа в таблицу orders
 	err = tx.QueryRowContext(ctx,
-		`INSERT INTO orders (customer_id, special_instructions, total_price, order_status, payment_method)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING order_id, created_at, updated_at`,
+		`INSERT INTO orders (location_id, customer_id, special_instructions, total_price, order_status, order_payment_method, staff_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING order_id, location_id, created_at, updated_at`,
+		locationId,
 		order.CustomerId,
 		order.SpecialInstructions,
 		order.TotalPrice,
 		order.OrderStatus,
 		order.PaymentMethod,
-	).Scan(&order.OrderId, &order.CreatedAt, &order.UpdatedAt)
-	if err != nil {
-		return nil, err
-	}
-
-	// Проверка и обновление остатков в инвентаре для каждого элемента заказа
-	err = or.checkAndUpdateInventory(ctx, tx, order.OrderItems)
+		order.StaffId,
+	).Scan(&order.OrderId, &order.LocationId, &order.CreatedAt, &order.UpdatedAt)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return nil, utils.ErrInvalidCustomerId
+		}
 		return nil, err
 	}
 
 	// Вставка данных элементов заказа (OrderItems)
-	for _, item := range order.OrderItems {
-		_, err := tx.ExecContext(ctx,
+	for i, item := range order.OrderItems {
+		err = tx.QueryRowContext(ctx,
 			`INSERT INTO order_items (order_id, menu_item_id, customizations, item_name, quantity, unit_price)
-			VALUES ($1, $2, $3, $4, $5, $6)`,
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING order_item_id`,
 			order.OrderId, // Привязка к заказу
 			item.MenuItemId,
 			item.Customizations,
 			item.ItemName,
 			item.Quantity,
 			item.UnitPrice,
-		)
+		).Scan(&order.OrderItems[i].OrderItemId)
 		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && (pqErr.Code == "23503" || pqErr.Code == "22P02") {
+				return nil, utils.ErrMenuItem
+			}
 			return nil, err
 		}
+		order.OrderItems[i].OrderId = order.OrderId
+	}
+
+	err = checkOrderStock(ctx, tx.Tx, string(order.OrderId), string(order.LocationId))
+	if err != nil {
+		return nil, err
+	}
+
+	// Оплаты, переданные вместе с заказом (раздельный счёт)
+	err = insertPayments(ctx, tx.Tx, string(order.OrderId), order.StaffId, order.Payments)
+	if err != nil {
+		return nil, err
+	}
+	if order.OrderStatus == "COMPLETED" {
+		err = checkPaymentsCover(ctx, tx.Tx, string(order.OrderId), order.TotalPrice)
+	} else {
+		err = checkNotOverpaid(ctx, tx.Tx, string(order.OrderId), order.TotalPrice)
+	}
+	if err != nil {
+		return nil, err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return order, nil
 }
 
+// menuItemPrice returns the name of a menu item and its price at locationId.
+// Archived items can no longer be ordered.
+func menuItemPrice(ctx context.Context, tx *sql.Tx, menuItemId string, locationId string) (utils.TEXT, utils.DEC, error) {
+	var name utils.TEXT
+	var price utils.DEC
+	err := tx.QueryRowContext(ctx,
+		`SELECT m.item_name, COALESCE(lmp.price, m.price)
+		FROM menu_items m
+		LEFT JOIN location_menu_prices lmp ON lmp.menu_item_id = m.menu_item_id AND lmp.location_id = $2
+		WHERE m.menu_item_id = $1 AND m.deleted_at IS NULL`,
+		menuItemId,
+		locationId,
+	).Scan(&name, &price)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.Is(err, sql.ErrNoRows) || (errors.As(err, &pqErr) && pqErr.Code == "22P02") {
+			return "", 0, utils.ErrMenuItem
+		}
+		return "", 0, err
+	}
+	return name, price, nil
+}
+
 func (or *OrderRepo) GetAll(ctx context.Context) ([]models.Orders, error) {
 	rows, err := or.db.QueryContext(ctx, `
 		SELECT o.order_id, 
+		       o.location_id, 
 		       o.customer_id, 
 		       o.total_price, 
 		       o.order_status, 
 		       o.order_payment_method, 
+		       o.staff_id, 
 		       o.created_at, 
 		       o.updated_at
 		FROM orders o
+		WHERE o.location_id = $1 AND o.deleted_at IS NULL
 		ORDER BY o.created_at DESC;
-	`)
+	`, utils.LocationOrDefault(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -111,7 +175,7 @@ func (or *OrderRepo) GetAll(ctx context.Context) ([]models.Orders, error) {
 	var orders []models.Orders
 	for rows.Next() {
 		var order models.Orders
-		if err := rows.Scan(&order.OrderId, &order.CustomerId, &order.TotalPrice, &order.OrderStatus, &order.PaymentMethod, &order.CreatedAt, &order.UpdatedAt); err != nil {
+		if err := rows.Scan(&order.OrderId, &order.LocationId, &order.CustomerId, &order.TotalPrice, &order.OrderStatus, &order.PaymentMethod, &order.StaffId, &order.CreatedAt, &order.UpdatedAt); err != nil {
 			return nil, err
 		}
 		orders = append(orders, order)
@@ -122,11 +186,17 @@ func (or *OrderRepo) GetAll(ctx context.Context) ([]models.Orders, error) {
 
 func (or *OrderRepo) UpdateItemByID(ctx context.Context, order *models.Orders) error {
 	// Начинаем транзакцию
-	tx, err := or.db.BeginTx(ctx, nil)
+	tx, err := beginTx(ctx, or.db)
 	if err != nil {
 		return err
 	}
 
+	err = checkVersion(ctx, tx.Tx, "orders", "order_id", string(order.OrderId))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// Обновление позиций заказа (если необходимо, можно сделать по отдельности для каждой позиции)
 	for _, item := range order.OrderItems {
 		_, err = tx.ExecContext(ctx, `
@@ -157,13 +227,21 @@ SET special_instructions = $1,
 	order_status = $3, 
 	order_payment_method = $4,
 	updated_at = NOW()
-WHERE order_id = $5;
+WHERE order_id = $5 AND deleted_at IS NULL;
 `, order.SpecialInstructions, order.TotalPrice, order.OrderStatus, order.PaymentMethod, order.OrderId)
 	if err != nil { // Функция для проверки остатков и обновления инвентаря
 
 		tx.Rollback()
 		return err
 	}
+	// Завершить можно только оплаченный заказ
+	if order.OrderStatus == "COMPLETED" {
+		err = checkPaymentsCover(ctx, tx.Tx, string(order.OrderId), order.TotalPrice)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
 	// Подтверждаем транзакцию
 	err = tx.Commit()
 	if err != nil {
@@ -173,39 +251,214 @@ WHERE order_id = $5;
 	return nil
 }
 
-func (or *OrderRepo) DeleteItemByID(ctx context.Context, orderId string) error {
-	// Начинаем транзакцию
-	tx, err := or.db.BeginTx(ctx, nil)
+// orderPatchColumns lists the columns a merge patch may change.
+var orderPatchColumns = map[string]bool{
+	"special_instructions": true,
+	"order_status":         true,
+	"order_payment_method": true,
+}
+
+// Patch writes the patched columns of an order and the patched lines, if
+// any. As with UpdateItemByID, only paid orders can be completed.
+func (or *OrderRepo) Patch(ctx context.Context, orderId string, set map[string]any, items []models.OrderItems) error {
+	tx, err := beginTx(ctx, or.db)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	// Удаляем все позиции заказа
-	_, err = tx.ExecContext(ctx, `DELETE FROM order_items WHERE order_id = $1`, orderId)
-	if err != nil {
-		tx.Rollback()
+	if err := checkVersion(ctx, tx.Tx, "orders", "order_id", orderId); err != nil {
 		return err
 	}
 
-	// Удаляем заказ
-	_, err = tx.ExecContext(ctx, `DELETE FROM orders WHERE order_id = $1`, orderId)
-	if err != nil {
-		tx.Rollback()
+	if len(items) > 0 {
+		if err := patchOrderItems(ctx, tx.Tx, orderId, items); err != nil {
+			return err
+		}
+	}
+
+	if err := patchRow(ctx, tx.Tx, "orders", "order_id", orderId, set, orderPatchColumns); err != nil {
 		return err
 	}
 
-	// Подтверждаем транзакцию
-	err = tx.Commit()
+	_, statusChanged := set["order_status"]
+	if statusChanged || len(items) > 0 {
+		var status string
+		var totalPrice utils.DEC
+		err = tx.QueryRowContext(ctx,
+			`SELECT order_status, total_price FROM orders WHERE order_id = $1`,
+			orderId,
+		).Scan(&status, &totalPrice)
+		if err != nil {
+			return err
+		}
+		if status == "COMPLETED" {
+			err = checkPaymentsCover(ctx, tx.Tx, orderId, totalPrice)
+		} else {
+			err = checkNotOverpaid(ctx, tx.Tx, orderId, totalPrice)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// patchOrderItems writes the patched lines of a PENDING order, priced again
+// from the menu at the order's location, and the total they add up to.
+func patchOrderItems(ctx context.Context, tx *sql.Tx, orderId string, items []models.OrderItems) error {
+	var status string
+	var locationId string
+	err := tx.QueryRowContext(ctx,
+		`SELECT order_status, location_id FROM orders
+		WHERE order_id = $1 AND deleted_at IS NULL
+		FOR UPDATE`,
+		orderId,
+	).Scan(&status, &locationId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return utils.ErrIdNotFound
+	}
 	if err != nil {
 		return err
 	}
+	if status != "PENDING" {
+		return utils.ErrOrderItemsLocked
+	}
 
-	return nil
+	var totalPrice utils.DEC
+	for _, item := range items {
+		_, unitPrice, err := menuItemPrice(ctx, tx, string(item.MenuItemId), locationId)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE order_items
+			SET quantity = $1, unit_price = $2, customizations = $3
+			WHERE order_item_id = $4 AND order_id = $5`,
+			item.Quantity,
+			unitPrice,
+			item.Customizations,
+			item.OrderItemId,
+			orderId,
+		)
+		if err != nil {
+			return err
+		}
+		totalPrice += item.Quantity * unitPrice
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE orders SET total_price = $1 WHERE order_id = $2`,
+		totalPrice,
+		orderId,
+	)
+	return err
+}
+
+// DeleteItemByID archives the order. Its items, payments and refunds are
+// kept so that sales reports for the period do not change.
+func (or *OrderRepo) DeleteItemByID(ctx context.Context, orderId string) error {
+	return archiveRow(ctx, or.db, "orders", "order_id", orderId)
+}
+
+func (or *OrderRepo) Restore(ctx context.Context, orderId string) error {
+	return restoreRow(ctx, or.db, "orders", "order_id", orderId)
+}
+
+// NumberOfOrderedItems sums the ordered units of every menu item over the
+// orders of the selected location created in [from, to); nil leaves that end
+// open. Cancelled orders are left out; active items never ordered count 0.
+func (or *OrderRepo) NumberOfOrderedItems(ctx context.Context, from *time.Time, to *time.Time) ([]models.OrderedItem, error) {
+	rows, err := or.db.QueryContext(ctx,
+		`SELECT m.item_name, COALESCE(SUM(oi.quantity), 0)
+		FROM menu_items m
+		LEFT JOIN order_items oi ON oi.menu_item_id = m.menu_item_id
+			AND EXISTS (
+				SELECT 1 FROM orders o
+				WHERE o.order_id = oi.order_id
+					AND o.location_id = $1
+					AND o.deleted_at IS NULL
+					AND o.order_status <> 'CANCELLED'
+					AND ($2::timestamptz IS NULL OR o.created_at >= $2)
+					AND ($3::timestamptz IS NULL OR o.created_at < $3)
+			)
+		WHERE m.deleted_at IS NULL OR oi.order_item_id IS NOT NULL
+		GROUP BY m.menu_item_id, m.item_name
+		ORDER BY m.item_name`,
+		utils.LocationOrDefault(ctx),
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []models.OrderedItem
+	for rows.Next() {
+		var count models.OrderedItem
+		if err := rows.Scan(&count.Name, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// GetIngredientUsage returns what completing the order drew from the stock
+// of its location, see update_inventory_on_order_complete, together with the
+// stock left.
+func (or *OrderRepo) GetIngredientUsage(ctx context.Context, orderId string) ([]models.StockChange, error) {
+	rows, err := conn(ctx, or.db).QueryContext(ctx,
+		`SELECT i.ingredient_id, i.ingredient_name, i.unit, o.location_id, -SUM(mii.quantity * oi.quantity), COALESCE(l.quantity, 0), i.reorder_level
+		FROM orders o
+		JOIN order_items oi ON oi.order_id = o.order_id
+		JOIN menu_item_ingredients mii ON mii.menu_item_id = oi.menu_item_id
+		JOIN inventory i ON i.ingredient_id = mii.ingredient_id
+		LEFT JOIN inventory_levels l ON l.ingredient_id = i.ingredient_id AND l.location_id = o.location_id
+		WHERE o.order_id = $1
+		GROUP BY i.ingredient_id, i.ingredient_name, i.unit, o.location_id, l.quantity, i.reorder_level
+		ORDER BY i.ingredient_name`,
+		orderId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usage []models.StockChange
+	for rows.Next() {
+		var change models.StockChange
+		err := rows.Scan(
+			&change.IngredientId,
+			&change.IngredientName,
+			&change.Unit,
+			&change.LocationId,
+			&change.Change,
+			&change.Quantity,
+			&change.ReorderLevel,
+		)
+		if err != nil {
+			return nil, err
+		}
+		usage = append(usage, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return usage, nil
 }
 
 func (or *OrderRepo) getOrderItemsByOrderID(ctx context.Context, orderId string) ([]models.OrderItems, error) {
+	return getOrderItems(ctx, conn(ctx, or.db), orderId)
+}
+
+func getOrderItems(ctx context.Context, q queryer, orderId string) ([]models.OrderItems, error) {
 	// Выполняем запрос на получение всех позиций заказа
-	rows, err := or.db.QueryContext(ctx,
+	rows, err := q.QueryContext(ctx,
 		`SELECT order_item_id, menu_item_id, order_id, customizations, item_name, quantity, unit_price 
 		FROM order_items WHERE order_id = $1`, orderId)
 	if err != nil {
@@ -242,73 +495,47 @@ func (or *OrderRepo) getOrderItemsByOrderID(ctx context.Context, orderId string)
 	return orderItems, nil
 }
 
-// Функция для проверки остатков и обновления инвентаря
-func (or *OrderRepo) checkAndUpdateInventory(ctx context.Context, tx *sql.Tx, orderItems []models.OrderItems) error {
-	for _, item := range orderItems {
-		// Получаем ингредиенты для данного блюда
-		var ingredients []models.Inventory
-		rows, err := tx.QueryContext(ctx,
-			`SELECT i.ingredient_id, i.ingredient_name, i.unit, i.quantity
-			FROM menu_item_ingredients mi
-			JOIN ingredients i ON mi.ingredient_id = i.ingredient_id
-			WHERE mi.menu_item_id = $1`,
-			item.MenuItemId,
-		)
-		if err != nil {
-			return err
-		}
-		defer rows.Close()
-
-		// Составляем список ингредиентов для текущего блюда
-		for rows.Next() {
-			var ingredient models.Inventory
-			err := rows.Scan(&ingredient.IngredientId, &ingredient.IngredientName, &ingredient.Unit, &ingredient.Quantity)
-			if err != nil {
-				return err
-			}
-			ingredients = append(ingredients, ingredient)
-		}
-
-		// Проверка, достаточно ли остатков в инвентаре для каждого ингредиента
-		for _, ingredient := range ingredients {
-			requiredQuantity := ingredient.Quantity * item.Quantity // Количество, необходимое для всех позиций этого блюда
-			if requiredQuantity > ingredient.Quantity {
-				return fmt.Errorf("недостаточно ингредиента %s для выполнения заказа", ingredient.IngredientName)
-			}
-		}
-
-		// Обновляем инвентарь, уменьшая количество ингредиентов
-		for _, ingredient := range ingredients {
-			requiredQuantity := ingredient.Quantity * item.Quantity // Количество, необходимое для всех позиций этого блюда
-
-			_, err := tx.ExecContext(ctx,
-				`UPDATE ingredients
-				SET quantity = quantity - $1, updated_at = NOW()
-				WHERE ingredient_id = $2`,
-				requiredQuantity, ingredient.IngredientId,
-			)
-			if err != nil {
-				return err
-			}
-		}
+// checkOrderStock fails with ErrInsufficientStock when the location lacks
+// an ingredient the lines of the order need. The stock itself is drawn when
+// the order is completed, see update_inventory_on_order_complete.
+func checkOrderStock(ctx context.Context, tx *sql.Tx, orderId string, locationId string) error {
+	var ingredientName string
+	err := tx.QueryRowContext(ctx,
+		`SELECT i.ingredient_name
+		FROM order_items oi
+		JOIN menu_item_ingredients mii ON mii.menu_item_id = oi.menu_item_id
+		JOIN inventory i ON i.ingredient_id = mii.ingredient_id
+		LEFT JOIN inventory_levels l ON l.ingredient_id = i.ingredient_id AND l.location_id = $2
+		WHERE oi.order_id = $1
+		GROUP BY i.ingredient_id, i.ingredient_name, l.quantity
+		HAVING COALESCE(l.quantity, 0) < SUM(mii.quantity * oi.quantity)
+		ORDER BY i.ingredient_name
+		LIMIT 1`,
+		orderId,
+		locationId,
+	).Scan(&ingredientName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
 	}
-
-	return nil
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", utils.ErrInsufficientStock, ingredientName)
 }
 
 func (or *OrderRepo) GetOrderByID(ctx context.Context, orderId string) (models.Orders, error) {
 	// Запрос для получения заказа по его ID
 	var order models.Orders
-	err := or.db.QueryRowContext(ctx, `
-		SELECT order_id, customer_id, total_price, order_status, order_payment_method, created_at, updated_at
+	err := conn(ctx, or.db).QueryRowContext(ctx, `
+		SELECT order_id, location_id, customer_id, special_instructions, total_price, order_status, order_payment_method, staff_id, created_at, updated_at
 		FROM orders
-		WHERE order_id = $1
-	`, orderId).Scan(&order.OrderId, &order.CustomerId, &order.TotalPrice, &order.OrderStatus, &order.PaymentMethod, &order.CreatedAt, &order.UpdatedAt)
+		WHERE order_id = $1 AND deleted_at IS NULL
+	`, orderId).Scan(&order.OrderId, &order.LocationId, &order.CustomerId, &order.SpecialInstructions, &order.TotalPrice, &order.OrderStatus, &order.PaymentMethod, &order.StaffId, &order.CreatedAt, &order.UpdatedAt)
 	// Обработка ошибок
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// Если заказ не найден
-			return models.Orders{}, fmt.Errorf("order with id %s not found", orderId)
+			return models.Orders{}, fmt.Errorf("order with id %s not found: %w", orderId, utils.ErrIdNotFound)
 		}
 		return models.Orders{}, err // другие ошибки (например, проблемы с подключением к базе)
 	}
@@ -321,5 +548,10 @@ func (or *OrderRepo) GetOrderByID(ctx context.Context, orderId string) (models.O
 
 	order.OrderItems = orderItems
 
+	order.Payments, err = getOrderPayments(ctx, conn(ctx, or.db), orderId)
+	if err != nil {
+		return models.Orders{}, err
+	}
+
 	return order, nil
 }
@@ -0,0 +1,192 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"frappuccino/models"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// orderEventsChannel is notified by log_order_status_change.
+	orderEventsChannel = "order_events"
+	// listenCatchUp is how many missed events Listen reads back after the
+	// listener reconnects.
+	listenCatchUp = 1000
+)
+
+type OrderEventRepoIfc interface {
+	GetAfter(ctx context.Context, afterId int64, locationId string, limit int) ([]models.OrderEvent, error)
+	GetLastId(ctx context.Context) (int64, error)
+	Listen(ctx context.Context, afterId int64) (<-chan models.OrderEvent, error)
+}
+
+type OrderEventRepo struct {
+	db *sql.DB
+}
+
+func NewOrderEventRepo(db *sql.DB) *OrderEventRepo {
+	return &OrderEventRepo{db: db}
+}
+
+// GetAfter returns the events following afterId, oldest first, optionally
+// restricted to one location (empty for all).
+//
+// event_ids are taken in insert order but become visible in commit order, so
+// an event before afterId may commit after it. Such an event was written by a
+// transaction still running when afterId was written, at or above its
+// snapshot_xmin; those events are returned again, and a client may get some
+// of them twice.
+func (or *OrderEventRepo) GetAfter(ctx context.Context, afterId int64, locationId string, limit int) ([]models.OrderEvent, error) {
+	var location any
+	if locationId != "" {
+		location = locationId
+	}
+	rows, err := or.db.QueryContext(ctx,
+		`SELECT
+			h.event_id,
+			CASE WHEN h.previous_status IS NULL THEN 'order.created' ELSE 'order.status_changed' END,
+			h.order_id,
+			o.location_id,
+			h.order_status,
+			h.previous_status,
+			h.notes,
+			h.updated_at
+		FROM order_status_history h
+		JOIN orders o ON o.order_id = h.order_id
+		WHERE (h.event_id > $1 OR (h.event_id < $1 AND h.tx_id >= (
+				SELECT snapshot_xmin FROM order_status_history WHERE event_id = $1
+			)))
+			AND ($2::uuid IS NULL OR o.location_id = $2)
+		ORDER BY h.event_id
+		LIMIT $3`,
+		afterId,
+		location,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.OrderEvent
+	for rows.Next() {
+		var event models.OrderEvent
+		err := rows.Scan(
+			&event.EventId,
+			&event.EventType,
+			&event.OrderId,
+			&event.LocationId,
+			&event.OrderStatus,
+			&event.PreviousStatus,
+			&event.Notes,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetLastId returns the id of the newest event, 0 when there is none.
+func (or *OrderEventRepo) GetLastId(ctx context.Context) (int64, error) {
+	var lastId int64
+	err := or.db.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(event_id), 0) FROM order_status_history`,
+	).Scan(&lastId)
+	return lastId, err
+}
+
+// Listen delivers the events announced on the order_events channel until
+// ctx is done, starting with those after afterId when it is not 0. The
+// listener reconnects by itself; the events announced while it was
+// disconnected are then read back with GetAfter, so some may be delivered
+// twice.
+func (or *OrderEventRepo) Listen(ctx context.Context, afterId int64) (<-chan models.OrderEvent, error) {
+	lastId := afterId
+	if lastId == 0 {
+		var err error
+		if lastId, err = or.GetLastId(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	listener := pq.NewListener(connInfo(), time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Order event listener: %v", err)
+		}
+	})
+	if err := listener.Listen(orderEventsChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	events := make(chan models.OrderEvent)
+	go func() {
+		defer close(events)
+		defer listener.Close()
+		send := func(event models.OrderEvent) bool {
+			select {
+			case events <- event:
+				lastId = max(lastId, event.EventId)
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		catchUp := func() bool {
+			missed, err := or.GetAfter(ctx, lastId, "", listenCatchUp)
+			if err != nil {
+				log.Printf("Error reading order events missed by the listener: %v", err)
+				return false
+			}
+			for _, event := range missed {
+				if !send(event) {
+					return false
+				}
+			}
+			return true
+		}
+
+		if afterId != 0 && !catchUp() {
+			return
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case notification := <-listener.Notify:
+				// nil signals a reconnect
+				if notification == nil {
+					if !catchUp() {
+						return
+					}
+					continue
+				}
+				var event models.OrderEvent
+				if err := json.Unmarshal([]byte(notification.Extra), &event); err != nil {
+					log.Printf("Malformed order event: %v", err)
+					continue
+				}
+				if !send(event) {
+					return
+				}
+			case <-time.After(90 * time.Second):
+				go listener.Ping()
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,152 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"frappuccino/models"
+)
+
+type OutboxRepoIfc interface {
+	Append(ctx context.Context, eventType string, locationId string, payload []byte) error
+	Consume(ctx context.Context, subscriber string, limit int, handle func(ctx context.Context, event models.OutboxEvent) error) (int, error)
+}
+
+type OutboxRepo struct {
+	db *sql.DB
+}
+
+func NewOutboxRepo(db *sql.DB) *OutboxRepo {
+	return &OutboxRepo{db: db}
+}
+
+// Append writes an event. Called within WithinTx it commits, or not, together
+// with the change it describes.
+func (or *OutboxRepo) Append(ctx context.Context, eventType string, locationId string, payload []byte) error {
+	var location any
+	if locationId != "" {
+		location = locationId
+	}
+	_, err := conn(ctx, or.db).ExecContext(ctx,
+		`INSERT INTO outbox_events (event_type, location_id, payload)
+		VALUES ($1, $2, $3)`,
+		eventType,
+		location,
+		payload,
+	)
+	return err
+}
+
+// Consume hands the next events of a subscriber to handle, in order, and
+// moves its cursor past those handled. It stops at the first event handle
+// fails on, which is offered again on the next call.
+//
+// handle runs in the transaction that moves the cursor, so what it writes
+// through the repos commits exactly once per event. While one worker consumes
+// for a subscriber, other workers skip it.
+func (or *OutboxRepo) Consume(ctx context.Context, subscriber string, limit int, handle func(ctx context.Context, event models.OutboxEvent) error) (int, error) {
+	_, err := or.db.ExecContext(ctx,
+		`INSERT INTO outbox_cursors (subscriber) VALUES ($1) ON CONFLICT (subscriber) DO NOTHING`,
+		subscriber,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := or.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var lastTxId, lastEventId int64
+	err = tx.QueryRowContext(ctx,
+		`SELECT last_tx_id, last_event_id
+		FROM outbox_cursors
+		WHERE subscriber = $1
+		FOR UPDATE SKIP LOCKED`,
+		subscriber,
+	).Scan(&lastTxId, &lastEventId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Only events of transactions older than every running one: no event
+	// can appear before them any more.
+	rows, err := tx.QueryContext(ctx,
+		`SELECT event_id, tx_id, event_type, location_id, payload, created_at
+		FROM outbox_events
+		WHERE (tx_id, event_id) > ($1, $2)
+			AND tx_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+		ORDER BY tx_id, event_id
+		LIMIT $3`,
+		lastTxId,
+		lastEventId,
+		limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+	type pending struct {
+		event models.OutboxEvent
+		txId  int64
+	}
+	var events []pending
+	for rows.Next() {
+		var p pending
+		err := rows.Scan(&p.event.EventId, &p.txId, &p.event.EventType, &p.event.LocationId, &p.event.Payload, &p.event.CreatedAt)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		events = append(events, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	txCtx := context.WithValue(ctx, txKey{}, tx)
+	handled := 0
+	var handleErr error
+	for _, p := range events {
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT outbox_event`); err != nil {
+			return 0, err
+		}
+		if err := handle(txCtx, p.event); err != nil {
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT outbox_event`); err != nil {
+				return 0, err
+			}
+			handleErr = fmt.Errorf("event %d: %w", p.event.EventId, err)
+			break
+		}
+		if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT outbox_event`); err != nil {
+			return 0, err
+		}
+		lastTxId, lastEventId = p.txId, p.event.EventId
+		handled++
+	}
+
+	if handled > 0 {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE outbox_cursors
+			SET last_tx_id = $1, last_event_id = $2, updated_at = now()
+			WHERE subscriber = $3`,
+			lastTxId,
+			lastEventId,
+			subscriber,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return handled, handleErr
+}
@@ -0,0 +1,54 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"frappuccino/utils"
+	"sort"
+	"strings"
+)
+
+// patchRow writes only the given columns of an active row, as merge patches
+// (PATCH requests) require. Column names come from the services and are
+// checked against allowed, so no user input ends up in the statement.
+func patchRow(ctx context.Context, tx *sql.Tx, table string, idColumn string, id string, set map[string]any, allowed map[string]bool) error {
+	if len(set) == 0 {
+		return nil
+	}
+
+	columns := make([]string, 0, len(set))
+	for column := range set {
+		if !allowed[column] {
+			return fmt.Errorf("column %s of %s cannot be patched", column, table)
+		}
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	assignments := make([]string, len(columns))
+	args := make([]any, 0, len(columns)+1)
+	for i, column := range columns {
+		assignments[i] = fmt.Sprintf("%s = $%d", column, i+1)
+		args = append(args, set[column])
+	}
+	args = append(args, id)
+
+	res, err := tx.ExecContext(ctx,
+		fmt.Sprintf(`UPDATE %s SET %s WHERE %s = $%d AND deleted_at IS NULL`,
+			table, strings.Join(assignments, ", "), idColumn, len(args)),
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return utils.ErrIdNotFound
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"frappuccino/models"
+	"frappuccino/utils"
+
+	"github.com/lib/pq"
+)
+
+type PaymentRepoIfc interface {
+	AddPayments(ctx context.Context, orderId string, payments []models.Payment) error
+	GetByOrderID(ctx context.Context, orderId string) (models.OrderPayments, error)
+}
+
+type PaymentRepo struct {
+	db *sql.DB
+}
+
+func NewPaymentRepo(db *sql.DB) *PaymentRepo {
+	return &PaymentRepo{db: db}
+}
+
+// AddPayments records payments against an order. The order row is locked so
+// concurrent payments cannot together exceed its total.
+func (pr *PaymentRepo) AddPayments(ctx context.Context, orderId string, payments []models.Payment) error {
+	tx, err := beginTx(ctx, pr.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var totalPrice utils.DEC
+	var status string
+	var staffId *utils.TEXT
+	err = tx.QueryRowContext(ctx,
+		`SELECT total_price, order_status, staff_id FROM orders WHERE order_id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		orderId,
+	).Scan(&totalPrice, &status, &staffId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.ErrIdNotFound
+		}
+		return err
+	}
+	if status == "CANCELLED" {
+		return utils.ErrOrderNotPayable
+	}
+
+	if err := insertPayments(ctx, tx.Tx, orderId, staffId, payments); err != nil {
+		return err
+	}
+	if err := checkNotOverpaid(ctx, tx.Tx, orderId, totalPrice); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (pr *PaymentRepo) GetByOrderID(ctx context.Context, orderId string) (models.OrderPayments, error) {
+	summary := models.OrderPayments{OrderId: utils.TEXT(orderId)}
+	db := conn(ctx, pr.db)
+	err := db.QueryRowContext(ctx,
+		`SELECT o.total_price,
+			COALESCE(SUM(p.amount), 0),
+			o.total_price - COALESCE(SUM(p.amount), 0),
+			COALESCE(SUM(p.tip), 0)
+		FROM orders o
+		LEFT JOIN payments p ON p.order_id = o.order_id
+		WHERE o.order_id = $1
+		GROUP BY o.order_id`,
+		orderId,
+	).Scan(&summary.TotalPrice, &summary.Paid, &summary.Balance, &summary.Tips)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.OrderPayments{}, utils.ErrIdNotFound
+		}
+		return models.OrderPayments{}, err
+	}
+
+	summary.Payments, err = getOrderPayments(ctx, db, orderId)
+	if err != nil {
+		return models.OrderPayments{}, err
+	}
+	if summary.Payments == nil {
+		summary.Payments = []models.Payment{}
+	}
+
+	return summary, nil
+}
+
+// insertPayments credits each payment to the caller, falling back to the
+// staff member who took the order when an API key is used.
+func insertPayments(ctx context.Context, tx *sql.Tx, orderId string, orderStaffId *utils.TEXT, payments []models.Payment) error {
+	var staffId any
+	if orderStaffId != nil {
+		staffId = *orderStaffId
+	}
+	if principal, ok := utils.PrincipalFrom(ctx); ok && principal.StaffId != "" {
+		staffId = principal.StaffId
+	}
+
+	for _, payment := range payments {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO payments (order_id, payment_method, amount, tip, staff_id)
+			VALUES ($1, $2, $3, $4, $5)`,
+			orderId,
+			payment.PaymentMethod,
+			payment.Amount,
+			payment.Tip,
+			staffId,
+		)
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23514" {
+				return utils.ErrInvalidPaymentAmount
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func paidAmount(ctx context.Context, tx *sql.Tx, orderId string) (utils.DEC, error) {
+	var paid utils.DEC
+	err := tx.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(amount), 0) FROM payments WHERE order_id = $1`,
+		orderId,
+	).Scan(&paid)
+	return paid, err
+}
+
+func checkNotOverpaid(ctx context.Context, tx *sql.Tx, orderId string, totalPrice utils.DEC) error {
+	paid, err := paidAmount(ctx, tx, orderId)
+	if err != nil {
+		return err
+	}
+	if paid > totalPrice {
+		return utils.ErrOverpayment
+	}
+	return nil
+}
+
+// checkPaymentsCover keeps an order from being completed before it is paid.
+func checkPaymentsCover(ctx context.Context, tx *sql.Tx, orderId string, totalPrice utils.DEC) error {
+	paid, err := paidAmount(ctx, tx, orderId)
+	if err != nil {
+		return err
+	}
+	if paid < totalPrice {
+		return utils.ErrOrderUnpaid
+	}
+	return nil
+}
+
+func getOrderPayments(ctx context.Context, q queryer, orderId string) ([]models.Payment, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT payment_id, order_id, payment_method, amount, tip, staff_id, created_at
+		FROM payments
+		WHERE order_id = $1
+		ORDER BY created_at, payment_id`,
+		orderId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []models.Payment
+	for rows.Next() {
+		var payment models.Payment
+		err := rows.Scan(
+			&payment.PaymentId,
+			&payment.OrderId,
+			&payment.PaymentMethod,
+			&payment.Amount,
+			&payment.Tip,
+			&payment.StaffId,
+			&payment.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
@@ -0,0 +1,344 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"frappuccino/models"
+	"frappuccino/utils"
+)
+
+type RefundRepoIfc interface {
+	Create(ctx context.Context, orderId string, request *models.RefundRequest) (models.Refund, error)
+	GetByOrderID(ctx context.Context, orderId string) ([]models.Refund, error)
+	GetRemaining(ctx context.Context, orderId string) (utils.DEC, error)
+	GetRestock(ctx context.Context, refundId string) ([]models.StockChange, error)
+}
+
+type RefundRepo struct {
+	db *sql.DB
+}
+
+func NewRefundRepo(db *sql.DB) *RefundRepo {
+	return &RefundRepo{db: db}
+}
+
+// refundableLine is an order line with the quantity still open for refund.
+type refundableLine struct {
+	unitPrice utils.DEC
+	remaining utils.DEC
+}
+
+// Create refunds order lines of a completed order in one transaction. With
+// restock the recipe quantities of the refunded lines are booked back into
+// the order's location as ADD transactions and batches. The order status is
+// left to the caller, see GetRemaining.
+func (rr *RefundRepo) Create(ctx context.Context, orderId string, request *models.RefundRequest) (models.Refund, error) {
+	tx, err := beginTx(ctx, rr.db)
+	if err != nil {
+		return models.Refund{}, err
+	}
+	defer tx.Rollback()
+
+	var status, locationId string
+	err = tx.QueryRowContext(ctx,
+		`SELECT order_status, location_id FROM orders WHERE order_id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		orderId,
+	).Scan(&status, &locationId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Refund{}, utils.ErrIdNotFound
+		}
+		return models.Refund{}, err
+	}
+	if status != "COMPLETED" {
+		return models.Refund{}, utils.ErrRefundState
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT oi.order_item_id, oi.unit_price, oi.quantity - COALESCE(SUM(rl.quantity), 0)
+		FROM order_items oi
+		LEFT JOIN refund_lines rl ON rl.order_item_id = oi.order_item_id
+		WHERE oi.order_id = $1
+		GROUP BY oi.order_item_id
+		ORDER BY oi.order_item_id`,
+		orderId,
+	)
+	if err != nil {
+		return models.Refund{}, err
+	}
+	lines := map[utils.TEXT]*refundableLine{}
+	var lineOrder []utils.TEXT
+	for rows.Next() {
+		var id utils.TEXT
+		var line refundableLine
+		if err := rows.Scan(&id, &line.unitPrice, &line.remaining); err != nil {
+			rows.Close()
+			return models.Refund{}, err
+		}
+		lines[id] = &line
+		lineOrder = append(lineOrder, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.Refund{}, err
+	}
+
+	// A request without items refunds everything that is left
+	items := request.Items
+	if len(items) == 0 {
+		for _, id := range lineOrder {
+			if lines[id].remaining > 0 {
+				items = append(items, models.RefundItem{OrderItemId: id, Quantity: lines[id].remaining})
+			}
+		}
+		if len(items) == 0 {
+			return models.Refund{}, utils.ErrRefundQuantity
+		}
+	}
+
+	var refundAmount utils.DEC
+	for _, item := range items {
+		line, ok := lines[item.OrderItemId]
+		if !ok {
+			return models.Refund{}, utils.ErrRefundItemNotFound
+		}
+		if item.Quantity > line.remaining {
+			return models.Refund{}, utils.ErrRefundQuantity
+		}
+		line.remaining -= item.Quantity
+		refundAmount += item.Quantity * line.unitPrice
+	}
+
+	var staffId any
+	if principal, ok := utils.PrincipalFrom(ctx); ok && principal.StaffId != "" {
+		staffId = principal.StaffId
+	}
+
+	var refundId string
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO refunds (order_id, reason, amount, restocked, staff_id)
+		VALUES ($1, $2, ROUND($3::numeric, 2), $4, $5)
+		RETURNING refund_id`,
+		orderId,
+		request.Reason,
+		refundAmount,
+		request.Restock,
+		staffId,
+	).Scan(&refundId)
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	for _, item := range items {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO refund_lines (refund_id, order_item_id, quantity, amount)
+			VALUES ($1, $2, $3, ROUND($3::numeric * $4::numeric, 2))`,
+			refundId,
+			item.OrderItemId,
+			item.Quantity,
+			lines[item.OrderItemId].unitPrice,
+		)
+		if err != nil {
+			return models.Refund{}, err
+		}
+	}
+
+	if request.Restock {
+		if err := restockRefund(ctx, tx.Tx, refundId, locationId, orderId); err != nil {
+			return models.Refund{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Refund{}, err
+	}
+
+	refunds, err := rr.getRefunds(ctx, `r.refund_id = $1`, refundId)
+	if err != nil {
+		return models.Refund{}, err
+	}
+	if len(refunds) == 0 {
+		return models.Refund{}, utils.ErrIdNotFound
+	}
+	return refunds[0], nil
+}
+
+func (rr *RefundRepo) GetByOrderID(ctx context.Context, orderId string) ([]models.Refund, error) {
+	var exists bool
+	err := rr.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM orders WHERE order_id = $1)`,
+		orderId,
+	).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, utils.ErrIdNotFound
+	}
+
+	return rr.getRefunds(ctx, `r.order_id = $1`, orderId)
+}
+
+// GetRemaining returns the quantity of the order's lines not yet refunded.
+func (rr *RefundRepo) GetRemaining(ctx context.Context, orderId string) (utils.DEC, error) {
+	var remaining utils.DEC
+	err := conn(ctx, rr.db).QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(oi.quantity), 0) - COALESCE((
+			SELECT SUM(rl.quantity)
+			FROM refund_lines rl
+			JOIN order_items ri ON ri.order_item_id = rl.order_item_id
+			WHERE ri.order_id = $1
+		), 0)
+		FROM order_items oi
+		WHERE oi.order_id = $1`,
+		orderId,
+	).Scan(&remaining)
+	return remaining, err
+}
+
+// GetRestock returns what restocking a refund booked back into the stock of
+// the order's location, together with the stock now held.
+func (rr *RefundRepo) GetRestock(ctx context.Context, refundId string) ([]models.StockChange, error) {
+	rows, err := conn(ctx, rr.db).QueryContext(ctx,
+		`SELECT i.ingredient_id, i.ingredient_name, i.unit, o.location_id, SUM(mii.quantity * rl.quantity), COALESCE(l.quantity, 0), i.reorder_level
+		FROM refunds r
+		JOIN orders o ON o.order_id = r.order_id
+		JOIN refund_lines rl ON rl.refund_id = r.refund_id
+		JOIN order_items oi ON oi.order_item_id = rl.order_item_id
+		JOIN menu_item_ingredients mii ON mii.menu_item_id = oi.menu_item_id
+		JOIN inventory i ON i.ingredient_id = mii.ingredient_id
+		LEFT JOIN inventory_levels l ON l.ingredient_id = i.ingredient_id AND l.location_id = o.location_id
+		WHERE r.refund_id = $1 AND r.restocked
+		GROUP BY i.ingredient_id, i.ingredient_name, i.unit, o.location_id, l.quantity, i.reorder_level
+		ORDER BY i.ingredient_name`,
+		refundId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var restock []models.StockChange
+	for rows.Next() {
+		var change models.StockChange
+		err := rows.Scan(
+			&change.IngredientId,
+			&change.IngredientName,
+			&change.Unit,
+			&change.LocationId,
+			&change.Change,
+			&change.Quantity,
+			&change.ReorderLevel,
+		)
+		if err != nil {
+			return nil, err
+		}
+		restock = append(restock, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return restock, nil
+}
+
+// getRefunds loads the refunds matching filter, oldest first, with their lines.
+func (rr *RefundRepo) getRefunds(ctx context.Context, filter string, arg string) ([]models.Refund, error) {
+	rows, err := conn(ctx, rr.db).QueryContext(ctx,
+		`SELECT r.refund_id, r.order_id, r.reason, r.amount, r.restocked, r.staff_id, r.created_at,
+			rl.refund_line_id, rl.order_item_id, oi.item_name, rl.quantity, rl.amount
+		FROM refunds r
+		JOIN refund_lines rl ON rl.refund_id = r.refund_id
+		JOIN order_items oi ON oi.order_item_id = rl.order_item_id
+		WHERE `+filter+`
+		ORDER BY r.created_at, r.refund_id, oi.item_name`,
+		arg,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refunds []models.Refund
+	for rows.Next() {
+		var refund models.Refund
+		var line models.RefundLine
+		err := rows.Scan(
+			&refund.RefundId,
+			&refund.OrderId,
+			&refund.Reason,
+			&refund.Amount,
+			&refund.Restocked,
+			&refund.StaffId,
+			&refund.CreatedAt,
+			&line.RefundLineId,
+			&line.OrderItemId,
+			&line.ItemName,
+			&line.Quantity,
+			&line.Amount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(refunds); n > 0 && refunds[n-1].RefundId == refund.RefundId {
+			refunds[n-1].Lines = append(refunds[n-1].Lines, line)
+			continue
+		}
+		refund.Lines = []models.RefundLine{line}
+		refunds = append(refunds, refund)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return refunds, nil
+}
+
+// restockRefund books the recipe quantities of a refund's lines back into
+// stock at the order's location, as a batch without expiry per ingredient so
+// that the batches keep adding up to the stock.
+func restockRefund(ctx context.Context, tx *sql.Tx, refundId string, locationId string, orderId string) error {
+	const restocked = `
+		SELECT mii.ingredient_id, SUM(mii.quantity * rl.quantity) AS quantity
+		FROM refund_lines rl
+		JOIN order_items oi ON oi.order_item_id = rl.order_item_id
+		JOIN menu_item_ingredients mii ON mii.menu_item_id = oi.menu_item_id
+		WHERE rl.refund_id = $1
+		GROUP BY mii.ingredient_id`
+
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO inventory_levels (location_id, ingredient_id, quantity)
+		SELECT $2, r.ingredient_id, r.quantity FROM (`+restocked+`) r
+		ON CONFLICT (location_id, ingredient_id) DO UPDATE SET quantity = inventory_levels.quantity + EXCLUDED.quantity`,
+		refundId,
+		locationId,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO inventory_transactions (location_id, ingredient_id, quantity, inventory_transaction_action, reference_id, notes)
+		SELECT $2, r.ingredient_id, r.quantity, 'ADD', $1, 'Restock for refund of order ' || $3
+		FROM (`+restocked+`) r`,
+		refundId,
+		locationId,
+		orderId,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO inventory_batches (location_id, ingredient_id, quantity_received, quantity_remaining, notes)
+		SELECT $2, r.ingredient_id, r.quantity, r.quantity, 'Restock for refund of order ' || $3
+		FROM (`+restocked+`) r`,
+		refundId,
+		locationId,
+		orderId,
+	)
+	return err
+}
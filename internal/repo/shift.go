@@ -0,0 +1,276 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"time"
+)
+
+type ShiftRepoIfc interface {
+	Create(ctx context.Context, shift *models.Shift) (models.Shift, error)
+	GetAll(ctx context.Context, staffId string, from time.Time, to time.Time) ([]models.Shift, error)
+	GetByID(ctx context.Context, shiftId string) (models.Shift, error)
+	UpdateByID(ctx context.Context, shift *models.Shift) error
+	DeleteByID(ctx context.Context, shiftId string) error
+	ClockIn(ctx context.Context, shiftId string) error
+	ClockOut(ctx context.Context, shiftId string) error
+}
+
+type ShiftRepo struct {
+	db *sql.DB
+}
+
+func NewShiftRepo(db *sql.DB) *ShiftRepo {
+	return &ShiftRepo{db: db}
+}
+
+const shiftColumns = `s.shift_id, s.staff_id, st.full_name, s.location_id, s.starts_at, s.ends_at, s.clock_in_at, s.clock_out_at,
+	ROUND(COALESCE(EXTRACT(EPOCH FROM COALESCE(s.clock_out_at, now()) - s.clock_in_at) / 3600, 0)::numeric, 2),
+	s.notes, s.created_at, s.updated_at`
+
+func scanShift(row interface{ Scan(dest ...any) error }, shift *models.Shift) error {
+	return row.Scan(
+		&shift.ShiftId,
+		&shift.StaffId,
+		&shift.StaffName,
+		&shift.LocationId,
+		&shift.StartsAt,
+		&shift.EndsAt,
+		&shift.ClockInAt,
+		&shift.ClockOutAt,
+		&shift.WorkedHours,
+		&shift.Notes,
+		&shift.CreatedAt,
+		&shift.UpdatedAt,
+	)
+}
+
+func (sr *ShiftRepo) Create(ctx context.Context, shift *models.Shift) (models.Shift, error) {
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Shift{}, err
+	}
+	defer tx.Rollback()
+
+	if err := checkShiftOverlap(ctx, tx, shift); err != nil {
+		return models.Shift{}, err
+	}
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO shifts (staff_id, location_id, starts_at, ends_at, notes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING shift_id`,
+		shift.StaffId,
+		utils.LocationOrDefault(ctx),
+		shift.StartsAt,
+		shift.EndsAt,
+		shift.Notes,
+	).Scan(&shift.ShiftId)
+	if err != nil {
+		return models.Shift{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Shift{}, err
+	}
+
+	return sr.GetByID(ctx, string(shift.ShiftId))
+}
+
+// GetAll lists shifts in start order. Empty filters are ignored; with a
+// location selected only its shifts are returned.
+func (sr *ShiftRepo) GetAll(ctx context.Context, staffId string, from time.Time, to time.Time) ([]models.Shift, error) {
+	var fromTs, toTs any
+	if !from.IsZero() {
+		fromTs = from
+	}
+	if !to.IsZero() {
+		toTs = to
+	}
+
+	rows, err := sr.db.QueryContext(ctx,
+		`SELECT `+shiftColumns+`
+		FROM shifts s
+		JOIN staff st ON st.staff_id = s.staff_id
+		WHERE ($1::text = '' OR s.staff_id::text = $1)
+			AND ($2::timestamptz IS NULL OR s.ends_at > $2)
+			AND ($3::timestamptz IS NULL OR s.starts_at < $3)
+			AND ($4::uuid IS NULL OR s.location_id = $4)
+		ORDER BY s.starts_at`,
+		staffId,
+		fromTs,
+		toTs,
+		reportLocation(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shifts []models.Shift
+	for rows.Next() {
+		var shift models.Shift
+		if err := scanShift(rows, &shift); err != nil {
+			return nil, err
+		}
+		shifts = append(shifts, shift)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shifts, nil
+}
+
+func (sr *ShiftRepo) GetByID(ctx context.Context, shiftId string) (models.Shift, error) {
+	var shift models.Shift
+	err := scanShift(sr.db.QueryRowContext(ctx,
+		`SELECT `+shiftColumns+`
+		FROM shifts s
+		JOIN staff st ON st.staff_id = s.staff_id
+		WHERE s.shift_id = $1`,
+		shiftId,
+	), &shift)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Shift{}, utils.ErrIdNotFound
+		}
+		return models.Shift{}, err
+	}
+
+	return shift, nil
+}
+
+// UpdateByID reschedules a shift. Clock times are only changed by ClockIn
+// and ClockOut.
+func (sr *ShiftRepo) UpdateByID(ctx context.Context, shift *models.Shift) error {
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkShiftOverlap(ctx, tx, shift); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE shifts
+		SET staff_id = $1, starts_at = $2, ends_at = $3, notes = $4
+		WHERE shift_id = $5`,
+		shift.StaffId,
+		shift.StartsAt,
+		shift.EndsAt,
+		shift.Notes,
+		shift.ShiftId,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return utils.ErrIdNotFound
+	}
+
+	return tx.Commit()
+}
+
+// DeleteByID removes a scheduled shift. Shifts that were clocked in are kept
+// as the record of hours worked.
+func (sr *ShiftRepo) DeleteByID(ctx context.Context, shiftId string) error {
+	result, err := sr.db.ExecContext(ctx,
+		`DELETE FROM shifts WHERE shift_id = $1 AND clock_in_at IS NULL`,
+		shiftId,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		if _, err := sr.GetByID(ctx, shiftId); err != nil {
+			return err
+		}
+		return utils.ErrShiftState
+	}
+	return nil
+}
+
+func (sr *ShiftRepo) ClockIn(ctx context.Context, shiftId string) error {
+	return sr.clock(ctx, shiftId,
+		`UPDATE shifts SET clock_in_at = now()
+		WHERE shift_id = $1 AND clock_in_at IS NULL`)
+}
+
+func (sr *ShiftRepo) ClockOut(ctx context.Context, shiftId string) error {
+	return sr.clock(ctx, shiftId,
+		`UPDATE shifts SET clock_out_at = now()
+		WHERE shift_id = $1 AND clock_in_at IS NOT NULL AND clock_out_at IS NULL`)
+}
+
+// clock runs a clock-in or clock-out update that only applies to a shift in
+// the right state, so concurrent requests cannot both succeed.
+func (sr *ShiftRepo) clock(ctx context.Context, shiftId string, query string) error {
+	result, err := sr.db.ExecContext(ctx, query, shiftId)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		if _, err := sr.GetByID(ctx, shiftId); err != nil {
+			return err
+		}
+		return utils.ErrShiftState
+	}
+	return nil
+}
+
+// checkShiftOverlap locks the staff member for the rest of the transaction,
+// so two shifts cannot be scheduled concurrently, and rejects a shift that
+// overlaps another one of theirs.
+func checkShiftOverlap(ctx context.Context, tx *sql.Tx, shift *models.Shift) error {
+	var staffId string
+	err := tx.QueryRowContext(ctx,
+		`SELECT staff_id FROM staff WHERE staff_id::text = $1 FOR UPDATE`,
+		shift.StaffId,
+	).Scan(&staffId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.ErrInvalidStaffId
+		}
+		return err
+	}
+
+	var overlaps bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM shifts
+			WHERE staff_id = $1
+				AND shift_id::text <> $2
+				AND tstzrange(starts_at, ends_at) && tstzrange($3::timestamptz, $4::timestamptz)
+		)`,
+		shift.StaffId,
+		shift.ShiftId,
+		shift.StartsAt,
+		shift.EndsAt,
+	).Scan(&overlaps)
+	if err != nil {
+		return err
+	}
+	if overlaps {
+		return utils.ErrShiftOverlap
+	}
+	return nil
+}
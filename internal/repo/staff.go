@@ -0,0 +1,185 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"frappuccino/models"
+	"frappuccino/utils"
+
+	"github.com/lib/pq"
+)
+
+type StaffRepoIfc interface {
+	Create(ctx context.Context, staff *models.Staff, passwordHash string) (*models.Staff, error)
+	GetAll(ctx context.Context) ([]models.Staff, error)
+	GetByID(ctx context.Context, staffId string) (models.Staff, error)
+	GetByUsername(ctx context.Context, username string) (models.Staff, string, error)
+	UpdateByID(ctx context.Context, staff *models.Staff, passwordHash string) error
+	Count(ctx context.Context) (int, error)
+}
+
+type StaffRepo struct {
+	db *sql.DB
+}
+
+func NewStaffRepo(db *sql.DB) *StaffRepo {
+	return &StaffRepo{db: db}
+}
+
+func (sr *StaffRepo) Create(ctx context.Context, staff *models.Staff, passwordHash string) (*models.Staff, error) {
+	err := sr.db.QueryRowContext(ctx,
+		`INSERT INTO staff (full_name, username, password_hash, staff_role, is_active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING staff_id, created_at, updated_at`,
+		staff.FullName,
+		staff.Username,
+		passwordHash,
+		staff.StaffRole,
+		staff.IsActive,
+	).Scan(
+		&staff.StaffId,
+		&staff.CreatedAt,
+		&staff.UpdatedAt,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, utils.ErrConflictFields
+		}
+		return nil, err
+	}
+
+	staff.Password = ""
+	return staff, nil
+}
+
+func (sr *StaffRepo) GetAll(ctx context.Context) ([]models.Staff, error) {
+	rows, err := sr.db.QueryContext(ctx,
+		`SELECT staff_id, full_name, username, staff_role, is_active, created_at, updated_at
+		FROM staff
+		ORDER BY username`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var staffList []models.Staff
+	for rows.Next() {
+		var staff models.Staff
+		err := rows.Scan(
+			&staff.StaffId,
+			&staff.FullName,
+			&staff.Username,
+			&staff.StaffRole,
+			&staff.IsActive,
+			&staff.CreatedAt,
+			&staff.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		staffList = append(staffList, staff)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return staffList, nil
+}
+
+func (sr *StaffRepo) GetByID(ctx context.Context, staffId string) (models.Staff, error) {
+	var staff models.Staff
+	err := sr.db.QueryRowContext(ctx,
+		`SELECT staff_id, full_name, username, staff_role, is_active, created_at, updated_at
+		FROM staff
+		WHERE staff_id = $1`,
+		staffId,
+	).Scan(
+		&staff.StaffId,
+		&staff.FullName,
+		&staff.Username,
+		&staff.StaffRole,
+		&staff.IsActive,
+		&staff.CreatedAt,
+		&staff.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Staff{}, utils.ErrIdNotFound
+		}
+		return models.Staff{}, err
+	}
+
+	return staff, nil
+}
+
+// GetByUsername returns the account and its password hash for login.
+func (sr *StaffRepo) GetByUsername(ctx context.Context, username string) (models.Staff, string, error) {
+	var staff models.Staff
+	var passwordHash string
+	err := sr.db.QueryRowContext(ctx,
+		`SELECT staff_id, full_name, username, password_hash, staff_role, is_active, created_at, updated_at
+		FROM staff
+		WHERE username = $1`,
+		username,
+	).Scan(
+		&staff.StaffId,
+		&staff.FullName,
+		&staff.Username,
+		&passwordHash,
+		&staff.StaffRole,
+		&staff.IsActive,
+		&staff.CreatedAt,
+		&staff.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Staff{}, "", utils.ErrIdNotFound
+		}
+		return models.Staff{}, "", err
+	}
+
+	return staff, passwordHash, nil
+}
+
+// UpdateByID changes the profile, role and status of an account. An empty
+// passwordHash keeps the current password.
+func (sr *StaffRepo) UpdateByID(ctx context.Context, staff *models.Staff, passwordHash string) error {
+	result, err := sr.db.ExecContext(ctx,
+		`UPDATE staff
+		SET full_name = $1,
+			staff_role = $2,
+			is_active = $3,
+			password_hash = COALESCE(NULLIF($4, ''), password_hash)
+		WHERE staff_id = $5`,
+		staff.FullName,
+		staff.StaffRole,
+		staff.IsActive,
+		passwordHash,
+		staff.StaffId,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return utils.ErrIdNotFound
+	}
+
+	// A deactivated account or a changed password signs out everywhere
+	if !staff.IsActive || passwordHash != "" {
+		_, err = sr.db.ExecContext(ctx, `DELETE FROM auth_sessions WHERE staff_id = $1`, staff.StaffId)
+	}
+	return err
+}
+
+func (sr *StaffRepo) Count(ctx context.Context) (int, error) {
+	var count int
+	err := sr.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM staff`).Scan(&count)
+	return count, err
+}
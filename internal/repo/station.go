@@ -0,0 +1,384 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"frappuccino/models"
+	"frappuccino/utils"
+
+	"github.com/lib/pq"
+)
+
+// maxBumpedTickets caps the listing of bumped tickets, which is only used to
+// look back at the most recent ones.
+const maxBumpedTickets = 100
+
+type StationRepoIfc interface {
+	GetAll(ctx context.Context) ([]models.Station, error)
+	CreateTickets(ctx context.Context, orderId string) error
+	GetTickets(ctx context.Context, stationId string, status string) ([]models.StationTicket, error)
+	GetTicket(ctx context.Context, stationId string, ticketId string) (models.StationTicket, error)
+	BumpTicket(ctx context.Context, stationId string, ticketId string, staffId *utils.TEXT) (string, error)
+	BumpItem(ctx context.Context, stationId string, ticketId string, orderItemId string, staffId *utils.TEXT) (string, error)
+	MarkOrderReady(ctx context.Context, orderId string) (bool, error)
+}
+
+type StationRepo struct {
+	db *sql.DB
+}
+
+func NewStationRepo(db *sql.DB) *StationRepo {
+	return &StationRepo{db: db}
+}
+
+func (sr *StationRepo) GetAll(ctx context.Context) ([]models.Station, error) {
+	rows, err := sr.db.QueryContext(ctx,
+		`SELECT station_id, station_name, created_at, updated_at
+		FROM stations
+		ORDER BY station_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stations []models.Station
+	for rows.Next() {
+		var station models.Station
+		if err := rows.Scan(&station.StationId, &station.StationName, &station.CreatedAt, &station.UpdatedAt); err != nil {
+			return nil, err
+		}
+		stations = append(stations, station)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stations, nil
+}
+
+// CreateTickets splits the lines of an order into one ticket per station of
+// their menu items. Lines of items without a station get no ticket; lines
+// of archived items fail with ErrMenuItem.
+func (sr *StationRepo) CreateTickets(ctx context.Context, orderId string) error {
+	db := conn(ctx, sr.db)
+	var archived bool
+	err := db.QueryRowContext(ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM order_items oi
+			JOIN menu_items m ON m.menu_item_id = oi.menu_item_id
+			WHERE oi.order_id = $1 AND m.deleted_at IS NOT NULL
+		)`,
+		orderId,
+	).Scan(&archived)
+	if err != nil {
+		return err
+	}
+	if archived {
+		return utils.ErrMenuItem
+	}
+
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO station_tickets (order_id, station_id, location_id)
+		SELECT DISTINCT o.order_id, m.station_id, o.location_id
+		FROM orders o
+		JOIN order_items oi ON oi.order_id = o.order_id
+		JOIN menu_items m ON m.menu_item_id = oi.menu_item_id
+		WHERE o.order_id = $1 AND m.station_id IS NOT NULL
+		ON CONFLICT (order_id, station_id) DO NOTHING`,
+		orderId,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO station_ticket_items (order_item_id, ticket_id)
+		SELECT oi.order_item_id, t.ticket_id
+		FROM order_items oi
+		JOIN menu_items m ON m.menu_item_id = oi.menu_item_id
+		JOIN station_tickets t ON t.order_id = oi.order_id AND t.station_id = m.station_id
+		WHERE oi.order_id = $1
+		ON CONFLICT (order_item_id) DO NOTHING`,
+		orderId,
+	)
+	return err
+}
+
+const ticketColumns = `t.ticket_id, t.order_id, t.station_id, t.location_id, t.ticket_status, o.special_instructions, t.bumped_at, t.bumped_by, t.created_at`
+
+func scanTicket(row interface{ Scan(dest ...any) error }, ticket *models.StationTicket) error {
+	return row.Scan(
+		&ticket.TicketId,
+		&ticket.OrderId,
+		&ticket.StationId,
+		&ticket.LocationId,
+		&ticket.TicketStatus,
+		&ticket.SpecialInstructions,
+		&ticket.BumpedAt,
+		&ticket.BumpedBy,
+		&ticket.CreatedAt,
+	)
+}
+
+// GetTickets lists the tickets of a station at the selected location. OPEN
+// tickets come oldest first and only while their order is still PENDING;
+// BUMPED tickets come most recently bumped first.
+func (sr *StationRepo) GetTickets(ctx context.Context, stationId string, status string) ([]models.StationTicket, error) {
+	if err := sr.checkStation(ctx, stationId); err != nil {
+		return nil, err
+	}
+
+	filter := ` AND t.ticket_status = 'OPEN' AND o.order_status = 'PENDING'
+		ORDER BY t.created_at`
+	var limit any
+	if status == "BUMPED" {
+		filter = ` AND t.ticket_status = 'BUMPED'
+		ORDER BY t.bumped_at DESC`
+		limit = maxBumpedTickets
+	}
+	query := `SELECT ` + ticketColumns + `
+		FROM station_tickets t
+		JOIN orders o ON o.order_id = t.order_id
+		WHERE t.station_id = $1 AND t.location_id = $2 AND o.deleted_at IS NULL` + filter + `
+		LIMIT $3`
+	rows, err := sr.db.QueryContext(ctx, query, stationId, utils.LocationOrDefault(ctx), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tickets []models.StationTicket
+	for rows.Next() {
+		var ticket models.StationTicket
+		if err := scanTicket(rows, &ticket); err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, ticket)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := sr.loadTicketItems(ctx, tickets); err != nil {
+		return nil, err
+	}
+	return tickets, nil
+}
+
+func (sr *StationRepo) GetTicket(ctx context.Context, stationId string, ticketId string) (models.StationTicket, error) {
+	var ticket models.StationTicket
+	err := scanTicket(conn(ctx, sr.db).QueryRowContext(ctx,
+		`SELECT `+ticketColumns+`
+		FROM station_tickets t
+		JOIN orders o ON o.order_id = t.order_id
+		WHERE t.ticket_id = $1 AND t.station_id = $2`,
+		ticketId,
+		stationId,
+	), &ticket)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.StationTicket{}, utils.ErrIdNotFound
+		}
+		return models.StationTicket{}, err
+	}
+
+	tickets := []models.StationTicket{ticket}
+	if err := sr.loadTicketItems(ctx, tickets); err != nil {
+		return models.StationTicket{}, err
+	}
+	return tickets[0], nil
+}
+
+// loadTicketItems fills in the lines of the tickets.
+func (sr *StationRepo) loadTicketItems(ctx context.Context, tickets []models.StationTicket) error {
+	if len(tickets) == 0 {
+		return nil
+	}
+	index := make(map[utils.TEXT]int, len(tickets))
+	ticketIds := make([]string, len(tickets))
+	for i, ticket := range tickets {
+		index[ticket.TicketId] = i
+		ticketIds[i] = string(ticket.TicketId)
+		tickets[i].Items = []models.StationTicketItem{}
+	}
+
+	rows, err := conn(ctx, sr.db).QueryContext(ctx,
+		`SELECT ti.ticket_id, oi.order_item_id, oi.menu_item_id, oi.item_name, oi.quantity, oi.customizations, ti.bumped_at
+		FROM station_ticket_items ti
+		JOIN order_items oi ON oi.order_item_id = ti.order_item_id
+		WHERE ti.ticket_id = ANY($1::uuid[])
+		ORDER BY oi.item_name, oi.order_item_id`,
+		pq.Array(ticketIds),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ticketId utils.TEXT
+		var item models.StationTicketItem
+		err := rows.Scan(&ticketId, &item.OrderItemId, &item.MenuItemId, &item.ItemName, &item.Quantity, &item.Customizations, &item.BumpedAt)
+		if err != nil {
+			return err
+		}
+		i := index[ticketId]
+		tickets[i].Items = append(tickets[i].Items, item)
+	}
+
+	return rows.Err()
+}
+
+// BumpTicket marks a ticket, and its lines not bumped yet, as done and
+// returns the order of the ticket.
+func (sr *StationRepo) BumpTicket(ctx context.Context, stationId string, ticketId string, staffId *utils.TEXT) (string, error) {
+	tx, err := beginTx(ctx, sr.db)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	orderId, err := lockTicketOrder(ctx, tx.Tx, stationId, ticketId)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE station_tickets
+		SET ticket_status = 'BUMPED', bumped_at = now(), bumped_by = $2
+		WHERE ticket_id = $1 AND ticket_status = 'OPEN'`,
+		ticketId,
+		staffId,
+	)
+	if err != nil {
+		return "", err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+	if rowsAffected == 0 {
+		return "", utils.ErrTicketBumped
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE station_ticket_items SET bumped_at = now() WHERE ticket_id = $1 AND bumped_at IS NULL`,
+		ticketId,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return orderId, tx.Commit()
+}
+
+// BumpItem marks one line of a ticket as done. Bumping the last open line
+// bumps the ticket as well. It returns the order of the ticket.
+func (sr *StationRepo) BumpItem(ctx context.Context, stationId string, ticketId string, orderItemId string, staffId *utils.TEXT) (string, error) {
+	tx, err := beginTx(ctx, sr.db)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	orderId, err := lockTicketOrder(ctx, tx.Tx, stationId, ticketId)
+	if err != nil {
+		return "", err
+	}
+
+	var bumpedAt *utils.TIME
+	err = tx.QueryRowContext(ctx,
+		`SELECT bumped_at FROM station_ticket_items WHERE ticket_id = $1 AND order_item_id = $2`,
+		ticketId,
+		orderItemId,
+	).Scan(&bumpedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", utils.ErrIdNotFound
+		}
+		return "", err
+	}
+	if bumpedAt != nil {
+		return "", utils.ErrTicketItemBumped
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE station_ticket_items SET bumped_at = now() WHERE order_item_id = $1`,
+		orderItemId,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE station_tickets
+		SET ticket_status = 'BUMPED', bumped_at = now(), bumped_by = $2
+		WHERE ticket_id = $1 AND ticket_status = 'OPEN'
+			AND NOT EXISTS (SELECT 1 FROM station_ticket_items WHERE ticket_id = $1 AND bumped_at IS NULL)`,
+		ticketId,
+		staffId,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return orderId, tx.Commit()
+}
+
+// lockTicketOrder locks the order of a ticket for the rest of the
+// transaction, so that bumps of its tickets are serialized and the last one
+// sees all the others when MarkOrderReady checks them.
+func lockTicketOrder(ctx context.Context, tx *sql.Tx, stationId string, ticketId string) (string, error) {
+	var orderId string
+	err := tx.QueryRowContext(ctx,
+		`SELECT o.order_id
+		FROM station_tickets t
+		JOIN orders o ON o.order_id = t.order_id
+		WHERE t.ticket_id = $1 AND t.station_id = $2
+		FOR UPDATE OF o`,
+		ticketId,
+		stationId,
+	).Scan(&orderId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", utils.ErrIdNotFound
+	}
+	return orderId, err
+}
+
+// MarkOrderReady moves a PENDING order whose tickets are all bumped to READY
+// and reports whether it did.
+func (sr *StationRepo) MarkOrderReady(ctx context.Context, orderId string) (bool, error) {
+	res, err := conn(ctx, sr.db).ExecContext(ctx,
+		`UPDATE orders
+		SET order_status = 'READY'
+		WHERE order_id = $1 AND order_status = 'PENDING' AND deleted_at IS NULL
+			AND EXISTS (SELECT 1 FROM station_tickets WHERE order_id = $1)
+			AND NOT EXISTS (SELECT 1 FROM station_tickets WHERE order_id = $1 AND ticket_status = 'OPEN')`,
+		orderId,
+	)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func (sr *StationRepo) checkStation(ctx context.Context, stationId string) error {
+	var exists bool
+	err := sr.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM stations WHERE station_id = $1)`,
+		stationId,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return utils.ErrIdNotFound
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"frappuccino/models"
+	"frappuccino/utils"
+
+	"github.com/lib/pq"
+)
+
+type StockCountRepoIfc interface {
+	Create(ctx context.Context, stockCount *models.StockCount) (*models.StockCount, error)
+	GetAll(ctx context.Context) ([]models.StockCount, error)
+	GetByID(ctx context.Context, stockCountId string) (models.StockCount, error)
+	SubmitLines(ctx context.Context, stockCountId string, submission *models.StockCountSubmission) error
+	Commit(ctx context.Context, stockCountId string) error
+	Cancel(ctx context.Context, stockCountId string) error
+}
+
+type StockCountRepo struct {
+	db *sql.DB
+}
+
+func NewStockCountRepo(db *sql.DB) *StockCountRepo {
+	return &StockCountRepo{db: db}
+}
+
+func (sr *StockCountRepo) Create(ctx context.Context, stockCount *models.StockCount) (*models.StockCount, error) {
+	err := sr.db.QueryRowContext(ctx,
+		`INSERT INTO stock_counts (location_id, opened_by, notes)
+		VALUES ($1, $2, $3)
+		RETURNING stock_count_id, location_id, stock_count_status, created_at`,
+		utils.LocationOrDefault(ctx),
+		stockCount.OpenedBy,
+		stockCount.Notes,
+	).Scan(
+		&stockCount.StockCountId,
+		&stockCount.LocationId,
+		&stockCount.StockCountStatus,
+		&stockCount.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return stockCount, nil
+}
+
+func (sr *StockCountRepo) GetAll(ctx context.Context) ([]models.StockCount, error) {
+	rows, err := sr.db.QueryContext(ctx,
+		`SELECT stock_count_id, location_id, stock_count_status, opened_by, notes, created_at, closed_at
+		FROM stock_counts
+		WHERE location_id = $1
+		ORDER BY created_at DESC`,
+		utils.LocationOrDefault(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stockCounts []models.StockCount
+	for rows.Next() {
+		var stockCount models.StockCount
+		err := rows.Scan(
+			&stockCount.StockCountId,
+			&stockCount.LocationId,
+			&stockCount.StockCountStatus,
+			&stockCount.OpenedBy,
+			&stockCount.Notes,
+			&stockCount.CreatedAt,
+			&stockCount.ClosedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		stockCounts = append(stockCounts, stockCount)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stockCounts, nil
+}
+
+func (sr *StockCountRepo) GetByID(ctx context.Context, stockCountId string) (models.StockCount, error) {
+	var stockCount models.StockCount
+	err := conn(ctx, sr.db).QueryRowContext(ctx,
+		`SELECT stock_count_id, location_id, stock_count_status, opened_by, notes, created_at, closed_at
+		FROM stock_counts
+		WHERE stock_count_id = $1`,
+		stockCountId,
+	).Scan(
+		&stockCount.StockCountId,
+		&stockCount.LocationId,
+		&stockCount.StockCountStatus,
+		&stockCount.OpenedBy,
+		&stockCount.Notes,
+		&stockCount.CreatedAt,
+		&stockCount.ClosedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.StockCount{}, utils.ErrIdNotFound
+		}
+		return models.StockCount{}, err
+	}
+
+	rows, err := conn(ctx, sr.db).QueryContext(ctx,
+		`SELECT
+			l.stock_count_line_id,
+			l.ingredient_id,
+			i.ingredient_name,
+			l.counted_quantity,
+			COALESCE(l.expected_quantity, v.quantity, 0),
+			l.counted_by,
+			l.counted_at
+		FROM stock_count_lines l
+		JOIN inventory i ON i.ingredient_id = l.ingredient_id
+		LEFT JOIN inventory_levels v ON v.ingredient_id = l.ingredient_id AND v.location_id = $2
+		WHERE l.stock_count_id = $1
+		ORDER BY i.ingredient_name`,
+		stockCountId,
+		stockCount.LocationId,
+	)
+	if err != nil {
+		return models.StockCount{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line models.StockCountLine
+		err := rows.Scan(
+			&line.StockCountLineId,
+			&line.IngredientId,
+			&line.IngredientName,
+			&line.CountedQuantity,
+			&line.ExpectedQuantity,
+			&line.CountedBy,
+			&line.CountedAt,
+		)
+		if err != nil {
+			return models.StockCount{}, err
+		}
+		line.Variance = line.CountedQuantity - line.ExpectedQuantity
+		stockCount.Lines = append(stockCount.Lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.StockCount{}, err
+	}
+
+	return stockCount, nil
+}
+
+// SubmitLines records counted quantities. Several staff members may count
+// the same session; the latest count of an ingredient wins.
+func (sr *StockCountRepo) SubmitLines(ctx context.Context, stockCountId string, submission *models.StockCountSubmission) error {
+	tx, err := beginTx(ctx, sr.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockOpenStockCount(ctx, tx.Tx, stockCountId); err != nil {
+		return err
+	}
+
+	for _, item := range submission.Items {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO stock_count_lines (stock_count_id, ingredient_id, counted_quantity, counted_by)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (stock_count_id, ingredient_id) DO UPDATE
+			SET counted_quantity = EXCLUDED.counted_quantity,
+				counted_by = EXCLUDED.counted_by,
+				counted_at = now()`,
+			stockCountId,
+			item.IngredientId,
+			item.CountedQuantity,
+			submission.CountedBy,
+		)
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+				return utils.ErrInvalidIngredientId
+			}
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Commit brings the inventory of the counted location in line with the
+// counted quantities. Every difference is written as an ADJUST transaction
+// referencing the session; as with setStockLevel, a shortfall is drawn from
+// the batches and a surplus becomes a batch without expiry.
+func (sr *StockCountRepo) Commit(ctx context.Context, stockCountId string) error {
+	tx, err := beginTx(ctx, sr.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	locationId, err := lockOpenStockCount(ctx, tx.Tx, stockCountId)
+	if err != nil {
+		return err
+	}
+
+	// Ingredients never stocked at this location start from zero
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO inventory_levels (location_id, ingredient_id)
+		SELECT $2, ingredient_id FROM stock_count_lines WHERE stock_count_id = $1
+		ON CONFLICT (location_id, ingredient_id) DO NOTHING`,
+		stockCountId,
+		locationId,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT l.stock_count_line_id, l.ingredient_id, l.counted_quantity, v.quantity
+		FROM stock_count_lines l
+		JOIN inventory_levels v ON v.ingredient_id = l.ingredient_id AND v.location_id = $2
+		WHERE l.stock_count_id = $1
+		FOR UPDATE OF v`,
+		stockCountId,
+		locationId,
+	)
+	if err != nil {
+		return err
+	}
+
+	var lines []models.StockCountLine
+	for rows.Next() {
+		var line models.StockCountLine
+		err := rows.Scan(&line.StockCountLineId, &line.IngredientId, &line.CountedQuantity, &line.ExpectedQuantity)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		lines = append(lines, line)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return utils.ErrEmptyStockCount
+	}
+
+	for _, line := range lines {
+		if variance := line.CountedQuantity - line.ExpectedQuantity; variance != 0 {
+			_, err = tx.ExecContext(ctx,
+				`INSERT INTO inventory_transactions (location_id, ingredient_id, quantity, inventory_transaction_action, reference_id, notes)
+				VALUES ($1, $2, $3, 'ADJUST', $4, 'Stock count')`,
+				locationId,
+				line.IngredientId,
+				variance,
+				stockCountId,
+			)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx,
+				`UPDATE inventory_levels SET quantity = $1 WHERE location_id = $2 AND ingredient_id = $3`,
+				line.CountedQuantity,
+				locationId,
+				line.IngredientId,
+			)
+			if err != nil {
+				return err
+			}
+
+			if variance < 0 {
+				_, err = tx.ExecContext(ctx,
+					`SELECT consume_inventory_batches($1, $2, $3)`,
+					locationId,
+					line.IngredientId,
+					-variance,
+				)
+			} else {
+				_, err = tx.ExecContext(ctx,
+					`INSERT INTO inventory_batches (location_id, ingredient_id, quantity_received, quantity_remaining, notes)
+					VALUES ($1, $2, $3, $3, 'Stock count')`,
+					locationId,
+					line.IngredientId,
+					variance,
+				)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE stock_count_lines SET expected_quantity = $1 WHERE stock_count_line_id = $2`,
+			line.ExpectedQuantity,
+			line.StockCountLineId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE stock_counts
+		SET stock_count_status = 'COMMITTED', closed_at = now()
+		WHERE stock_count_id = $1`,
+		stockCountId,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (sr *StockCountRepo) Cancel(ctx context.Context, stockCountId string) error {
+	tx, err := beginTx(ctx, sr.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockOpenStockCount(ctx, tx.Tx, stockCountId); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE stock_counts
+		SET stock_count_status = 'CANCELLED', closed_at = now()
+		WHERE stock_count_id = $1`,
+		stockCountId,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockOpenStockCount locks the session row for the rest of the transaction,
+// makes sure it can still be changed and returns the counted location.
+func lockOpenStockCount(ctx context.Context, tx *sql.Tx, stockCountId string) (string, error) {
+	var locationId, status string
+	err := tx.QueryRowContext(ctx,
+		`SELECT location_id, stock_count_status FROM stock_counts WHERE stock_count_id = $1 FOR UPDATE`,
+		stockCountId,
+	).Scan(&locationId, &status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", utils.ErrIdNotFound
+		}
+		return "", err
+	}
+	if status != "OPEN" {
+		return "", utils.ErrStockCountClosed
+	}
+	return locationId, nil
+}
@@ -0,0 +1,499 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"frappuccino/models"
+	"frappuccino/utils"
+
+	"github.com/lib/pq"
+)
+
+type StockTransferRepoIfc interface {
+	Create(ctx context.Context, transfer *models.StockTransfer) (models.StockTransfer, error)
+	GetAll(ctx context.Context, status string) ([]models.StockTransfer, error)
+	GetByID(ctx context.Context, transferId string) (models.StockTransfer, error)
+	Ship(ctx context.Context, transferId string) error
+	Receive(ctx context.Context, transferId string, receipt *models.StockTransferReceipt) error
+	Cancel(ctx context.Context, transferId string) error
+}
+
+type StockTransferRepo struct {
+	db *sql.DB
+}
+
+func NewStockTransferRepo(db *sql.DB) *StockTransferRepo {
+	return &StockTransferRepo{db: db}
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func (tr *StockTransferRepo) Create(ctx context.Context, transfer *models.StockTransfer) (models.StockTransfer, error) {
+	tx, err := beginTx(ctx, tr.db)
+	if err != nil {
+		return models.StockTransfer{}, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO stock_transfers (from_location_id, to_location_id, created_by, notes)
+		VALUES ($1, $2, $3, $4)
+		RETURNING transfer_id`,
+		transfer.FromLocationId,
+		transfer.ToLocationId,
+		transfer.CreatedBy,
+		transfer.Notes,
+	).Scan(&transfer.TransferId)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return models.StockTransfer{}, utils.ErrIdNotFound
+		}
+		return models.StockTransfer{}, err
+	}
+
+	for _, item := range transfer.Items {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO stock_transfer_lines (transfer_id, ingredient_id, quantity_sent)
+			VALUES ($1, $2, $3)`,
+			transfer.TransferId,
+			item.IngredientId,
+			item.QuantitySent,
+		)
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) {
+				switch pqErr.Code {
+				case "23503":
+					return models.StockTransfer{}, utils.ErrInvalidIngredientId
+				case "23505":
+					return models.StockTransfer{}, utils.ErrConflictFields
+				}
+			}
+			return models.StockTransfer{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.StockTransfer{}, err
+	}
+
+	return tr.GetByID(ctx, string(transfer.TransferId))
+}
+
+// GetAll lists transfers, newest first. With a location selected only the
+// transfers leaving or arriving at it are returned.
+func (tr *StockTransferRepo) GetAll(ctx context.Context, status string) ([]models.StockTransfer, error) {
+	var locationId any
+	if selected, ok := utils.SelectedLocation(ctx); ok {
+		locationId = selected
+	}
+
+	rows, err := tr.db.QueryContext(ctx,
+		`SELECT transfer_id, from_location_id, to_location_id, transfer_status, created_by, received_by, notes, created_at, shipped_at, received_at
+		FROM stock_transfers
+		WHERE ($1::text = '' OR transfer_status::text = $1)
+			AND ($2::uuid IS NULL OR from_location_id = $2 OR to_location_id = $2)
+		ORDER BY created_at DESC`,
+		status,
+		locationId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []models.StockTransfer
+	for rows.Next() {
+		var transfer models.StockTransfer
+		err := rows.Scan(
+			&transfer.TransferId,
+			&transfer.FromLocationId,
+			&transfer.ToLocationId,
+			&transfer.TransferStatus,
+			&transfer.CreatedBy,
+			&transfer.ReceivedBy,
+			&transfer.Notes,
+			&transfer.CreatedAt,
+			&transfer.ShippedAt,
+			&transfer.ReceivedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+func (tr *StockTransferRepo) GetByID(ctx context.Context, transferId string) (models.StockTransfer, error) {
+	var transfer models.StockTransfer
+	err := conn(ctx, tr.db).QueryRowContext(ctx,
+		`SELECT transfer_id, from_location_id, to_location_id, transfer_status, created_by, received_by, notes, created_at, shipped_at, received_at
+		FROM stock_transfers
+		WHERE transfer_id = $1`,
+		transferId,
+	).Scan(
+		&transfer.TransferId,
+		&transfer.FromLocationId,
+		&transfer.ToLocationId,
+		&transfer.TransferStatus,
+		&transfer.CreatedBy,
+		&transfer.ReceivedBy,
+		&transfer.Notes,
+		&transfer.CreatedAt,
+		&transfer.ShippedAt,
+		&transfer.ReceivedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.StockTransfer{}, utils.ErrIdNotFound
+		}
+		return models.StockTransfer{}, err
+	}
+
+	transfer.Items, err = getTransferLines(ctx, conn(ctx, tr.db), transferId)
+	if err != nil {
+		return models.StockTransfer{}, err
+	}
+
+	return transfer, nil
+}
+
+// Ship takes the stock out of the source location. Each line is written as a
+// TRANSFER_OUT transaction referencing the transfer, and the batches it was
+// drawn from are kept with the line for Receive.
+func (tr *StockTransferRepo) Ship(ctx context.Context, transferId string) error {
+	tx, err := beginTx(ctx, tr.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	transfer, err := lockStockTransfer(ctx, tx.Tx, transferId, "DRAFT")
+	if err != nil {
+		return err
+	}
+	lines, err := getTransferLines(ctx, tx, transferId)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return utils.ErrEmptyTransfer
+	}
+
+	for _, line := range lines {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE inventory_levels
+			SET quantity = quantity - $1
+			WHERE location_id = $2 AND ingredient_id = $3 AND quantity >= $1`,
+			line.QuantitySent,
+			transfer.FromLocationId,
+			line.IngredientId,
+		)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("%w: %s", utils.ErrInsufficientStock, line.IngredientName)
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO stock_transfer_batches (transfer_line_id, expires_at, quantity)
+			SELECT $4, taken_expires_at, taken_quantity
+			FROM consume_inventory_batches($1, $2, $3)`,
+			transfer.FromLocationId,
+			line.IngredientId,
+			line.QuantitySent,
+			line.TransferLineId,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO inventory_transactions (location_id, ingredient_id, quantity, inventory_transaction_action, reference_id, notes)
+			VALUES ($1, $2, $3, 'TRANSFER_OUT', $4, 'Stock transfer shipped')`,
+			transfer.FromLocationId,
+			line.IngredientId,
+			-line.QuantitySent,
+			transferId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE stock_transfers
+		SET transfer_status = 'IN_TRANSIT', shipped_at = now()
+		WHERE transfer_id = $1`,
+		transferId,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Receive books the arrived stock at the destination as TRANSFER_IN
+// transactions and batches with the expiry dates it was shipped with. A line
+// that arrived short or over keeps the difference as its discrepancy and the
+// transaction notes what was sent.
+func (tr *StockTransferRepo) Receive(ctx context.Context, transferId string, receipt *models.StockTransferReceipt) error {
+	tx, err := beginTx(ctx, tr.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	transfer, err := lockStockTransfer(ctx, tx.Tx, transferId, "IN_TRANSIT")
+	if err != nil {
+		return err
+	}
+	lines, err := getTransferLines(ctx, tx, transferId)
+	if err != nil {
+		return err
+	}
+
+	sent := make(map[utils.TEXT]bool, len(lines))
+	for _, line := range lines {
+		sent[line.IngredientId] = true
+	}
+	received := make(map[utils.TEXT]utils.DEC, len(receipt.Items))
+	for _, item := range receipt.Items {
+		if !sent[item.IngredientId] {
+			return utils.ErrTransferItemNotFound
+		}
+		received[item.IngredientId] = item.QuantityReceived
+	}
+
+	for _, line := range lines {
+		quantity, ok := received[line.IngredientId]
+		if !ok {
+			quantity = line.QuantitySent
+		}
+
+		if quantity > 0 {
+			_, err = tx.ExecContext(ctx,
+				`INSERT INTO inventory_levels (location_id, ingredient_id, quantity)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (location_id, ingredient_id) DO UPDATE SET quantity = inventory_levels.quantity + EXCLUDED.quantity`,
+				transfer.ToLocationId,
+				line.IngredientId,
+				quantity,
+			)
+			if err != nil {
+				return err
+			}
+
+			if err := receiveTransferBatches(ctx, tx.Tx, string(transfer.ToLocationId), line, quantity); err != nil {
+				return err
+			}
+
+			notes := "Stock transfer received"
+			if quantity != line.QuantitySent {
+				notes = fmt.Sprintf("Stock transfer received with discrepancy: sent %.2f, received %.2f", line.QuantitySent, quantity)
+			}
+			_, err = tx.ExecContext(ctx,
+				`INSERT INTO inventory_transactions (location_id, ingredient_id, quantity, inventory_transaction_action, reference_id, notes)
+				VALUES ($1, $2, $3, 'TRANSFER_IN', $4, $5)`,
+				transfer.ToLocationId,
+				line.IngredientId,
+				quantity,
+				transferId,
+				notes,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE stock_transfer_lines SET quantity_received = $1 WHERE transfer_line_id = $2`,
+			quantity,
+			line.TransferLineId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE stock_transfers
+		SET transfer_status = 'RECEIVED',
+			received_at = now(),
+			received_by = $2,
+			notes = CASE WHEN $3 = '' THEN notes WHEN notes = '' THEN $3 ELSE notes || E'\n' || $3 END
+		WHERE transfer_id = $1`,
+		transferId,
+		receipt.ReceivedBy,
+		receipt.Notes,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Cancel drops a transfer that has not shipped yet. Nothing was moved, so no
+// transactions are written.
+func (tr *StockTransferRepo) Cancel(ctx context.Context, transferId string) error {
+	tx, err := beginTx(ctx, tr.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockStockTransfer(ctx, tx.Tx, transferId, "DRAFT"); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE stock_transfers SET transfer_status = 'CANCELLED' WHERE transfer_id = $1`,
+		transferId,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// receiveTransferBatches stocks the received quantity of a line at the
+// destination in batches expiring as the ones it was shipped from, the
+// earliest first. What arrived beyond the shipped batches gets no expiry.
+func receiveTransferBatches(ctx context.Context, tx *sql.Tx, locationId string, line models.StockTransferLine, quantity utils.DEC) error {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT expires_at, quantity
+		FROM stock_transfer_batches
+		WHERE transfer_line_id = $1
+		ORDER BY expires_at NULLS LAST`,
+		line.TransferLineId,
+	)
+	if err != nil {
+		return err
+	}
+	type shippedBatch struct {
+		expiresAt *utils.TIME
+		quantity  utils.DEC
+	}
+	var shipped []shippedBatch
+	for rows.Next() {
+		var batch shippedBatch
+		if err := rows.Scan(&batch.expiresAt, &batch.quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		shipped = append(shipped, batch)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	remaining := quantity
+	for _, batch := range shipped {
+		if remaining <= 0 {
+			break
+		}
+		batch.quantity = min(batch.quantity, remaining)
+		remaining -= batch.quantity
+		if err := insertTransferBatch(ctx, tx, locationId, line.IngredientId, batch.quantity, batch.expiresAt); err != nil {
+			return err
+		}
+	}
+	if remaining > 0 {
+		return insertTransferBatch(ctx, tx, locationId, line.IngredientId, remaining, nil)
+	}
+	return nil
+}
+
+func insertTransferBatch(ctx context.Context, tx *sql.Tx, locationId string, ingredientId utils.TEXT, quantity utils.DEC, expiresAt *utils.TIME) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO inventory_batches (location_id, ingredient_id, quantity_received, quantity_remaining, expires_at, notes)
+		VALUES ($1, $2, $3, $3, $4, 'Stock transfer received')`,
+		locationId,
+		ingredientId,
+		quantity,
+		expiresAt,
+	)
+	return err
+}
+
+// lockStockTransfer locks the transfer row for the rest of the transaction
+// and makes sure it is in the expected status.
+func lockStockTransfer(ctx context.Context, tx *sql.Tx, transferId string, status string) (models.StockTransfer, error) {
+	var transfer models.StockTransfer
+	err := tx.QueryRowContext(ctx,
+		`SELECT transfer_id, from_location_id, to_location_id, transfer_status
+		FROM stock_transfers
+		WHERE transfer_id = $1
+		FOR UPDATE`,
+		transferId,
+	).Scan(&transfer.TransferId, &transfer.FromLocationId, &transfer.ToLocationId, &transfer.TransferStatus)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.StockTransfer{}, utils.ErrIdNotFound
+		}
+		return models.StockTransfer{}, err
+	}
+	if string(transfer.TransferStatus) != status {
+		return models.StockTransfer{}, utils.ErrTransferState
+	}
+	return transfer, nil
+}
+
+func getTransferLines(ctx context.Context, q queryer, transferId string) ([]models.StockTransferLine, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT l.transfer_line_id, l.ingredient_id, i.ingredient_name, l.quantity_sent, l.quantity_received
+		FROM stock_transfer_lines l
+		JOIN inventory i ON i.ingredient_id = l.ingredient_id
+		WHERE l.transfer_id = $1
+		ORDER BY i.ingredient_name`,
+		transferId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []models.StockTransferLine{}
+	for rows.Next() {
+		var line models.StockTransferLine
+		var received sql.NullFloat64
+		err := rows.Scan(&line.TransferLineId, &line.IngredientId, &line.IngredientName, &line.QuantitySent, &received)
+		if err != nil {
+			return nil, err
+		}
+		if received.Valid {
+			quantity := utils.DEC(received.Float64)
+			discrepancy := line.QuantitySent - quantity
+			line.QuantityReceived = &quantity
+			line.Discrepancy = &discrepancy
+		}
+		lines = append(lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
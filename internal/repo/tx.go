@@ -0,0 +1,89 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+)
+
+type txKey struct{}
+
+type TxRepoIfc interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type TxRepo struct {
+	db *sql.DB
+}
+
+func NewTxRepo(db *sql.DB) *TxRepo {
+	return &TxRepo{db: db}
+}
+
+// WithinTx runs fn in one transaction. Repo methods called with the ctx given
+// to fn join it instead of starting their own, so that several changes, and
+// the events describing them, commit or roll back together. The transaction
+// commits when fn returns nil. Nested calls join the outer transaction.
+func (tr *TxRepo) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := tr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// dbConn is satisfied by both *sql.DB and *sql.Tx.
+type dbConn interface {
+	queryer
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn returns the transaction of WithinTx, if ctx carries one, or db.
+func conn(ctx context.Context, db *sql.DB) dbConn {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// txn is a transaction started by beginTx. Commit and Rollback do nothing
+// when it joined the transaction of WithinTx, which ends it instead.
+type txn struct {
+	*sql.Tx
+	joined bool
+}
+
+// beginTx starts a transaction, or joins the one of WithinTx.
+func beginTx(ctx context.Context, db *sql.DB) (*txn, error) {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return &txn{Tx: tx, joined: true}, nil
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &txn{Tx: tx}, nil
+}
+
+func (t *txn) Commit() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+func (t *txn) Rollback() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
@@ -0,0 +1,35 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"frappuccino/utils"
+)
+
+// checkVersion makes a write conditional on the If-Match tags in ctx, if
+// any. It locks the active row for the rest of the transaction so that it
+// cannot change between the check and the write.
+func checkVersion(ctx context.Context, tx *sql.Tx, table string, idColumn string, id string) error {
+	tags, ok := utils.IfMatchFrom(ctx)
+	if !ok {
+		return nil
+	}
+
+	var updatedAt utils.TIME
+	err := tx.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT updated_at FROM %s WHERE %s = $1 AND deleted_at IS NULL FOR UPDATE`, table, idColumn),
+		id,
+	).Scan(&updatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.ErrIdNotFound
+		}
+		return err
+	}
+	if !utils.ETagMatches(tags, updatedAt) {
+		return utils.ErrPreconditionFailed
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepoIfc interface {
+	Create(ctx context.Context, subscription *models.WebhookSubscription) (*models.WebhookSubscription, error)
+	GetAll(ctx context.Context) ([]models.WebhookSubscription, error)
+	GetByID(ctx context.Context, subscriptionId string) (models.WebhookSubscription, error)
+	UpdateByID(ctx context.Context, subscription *models.WebhookSubscription) error
+	DeleteByID(ctx context.Context, subscriptionId string) error
+	GetDeliveries(ctx context.Context, subscriptionId string, status string, limit int) ([]models.WebhookDelivery, error)
+	Enqueue(ctx context.Context, eventId int64, eventType string, payload []byte) error
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookJob, error)
+	MarkDelivered(ctx context.Context, deliveryId string, responseStatus int) error
+	MarkFailed(ctx context.Context, deliveryId string, responseStatus *int, lastError string, retryAt *time.Time) error
+}
+
+type WebhookRepo struct {
+	db *sql.DB
+}
+
+func NewWebhookRepo(db *sql.DB) *WebhookRepo {
+	return &WebhookRepo{db: db}
+}
+
+func (wr *WebhookRepo) Create(ctx context.Context, subscription *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	err := wr.db.QueryRowContext(ctx,
+		`INSERT INTO webhook_subscriptions (url, event_types, secret, is_active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING subscription_id, created_at, updated_at`,
+		subscription.Url,
+		pq.Array(subscription.EventTypes),
+		subscription.Secret,
+		subscription.IsActive,
+	).Scan(
+		&subscription.SubscriptionId,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// GetAll leaves out the secrets.
+func (wr *WebhookRepo) GetAll(ctx context.Context) ([]models.WebhookSubscription, error) {
+	rows, err := wr.db.QueryContext(ctx,
+		`SELECT subscription_id, url, event_types, is_active, created_at, updated_at
+		FROM webhook_subscriptions
+		ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []models.WebhookSubscription
+	for rows.Next() {
+		var subscription models.WebhookSubscription
+		err := rows.Scan(
+			&subscription.SubscriptionId,
+			&subscription.Url,
+			pq.Array(&subscription.EventTypes),
+			&subscription.IsActive,
+			&subscription.CreatedAt,
+			&subscription.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// GetByID leaves out the secret.
+func (wr *WebhookRepo) GetByID(ctx context.Context, subscriptionId string) (models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := wr.db.QueryRowContext(ctx,
+		`SELECT subscription_id, url, event_types, is_active, created_at, updated_at
+		FROM webhook_subscriptions
+		WHERE subscription_id = $1`,
+		subscriptionId,
+	).Scan(
+		&subscription.SubscriptionId,
+		&subscription.Url,
+		pq.Array(&subscription.EventTypes),
+		&subscription.IsActive,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.WebhookSubscription{}, utils.ErrIdNotFound
+		}
+		return models.WebhookSubscription{}, err
+	}
+
+	return subscription, nil
+}
+
+// UpdateByID keeps the current secret when none is given.
+func (wr *WebhookRepo) UpdateByID(ctx context.Context, subscription *models.WebhookSubscription) error {
+	res, err := wr.db.ExecContext(ctx,
+		`UPDATE webhook_subscriptions
+		SET url = $1,
+			event_types = $2,
+			secret = COALESCE(NULLIF($3, ''), secret),
+			is_active = $4
+		WHERE subscription_id = $5`,
+		subscription.Url,
+		pq.Array(subscription.EventTypes),
+		subscription.Secret,
+		subscription.IsActive,
+		subscription.SubscriptionId,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return utils.ErrIdNotFound
+	}
+
+	return nil
+}
+
+// DeleteByID removes the subscription together with its delivery log.
+func (wr *WebhookRepo) DeleteByID(ctx context.Context, subscriptionId string) error {
+	res, err := wr.db.ExecContext(ctx,
+		`DELETE FROM webhook_subscriptions WHERE subscription_id = $1`,
+		subscriptionId,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return utils.ErrIdNotFound
+	}
+
+	return nil
+}
+
+// GetDeliveries returns the newest deliveries of a subscription first,
+// optionally only those in one status.
+func (wr *WebhookRepo) GetDeliveries(ctx context.Context, subscriptionId string, status string, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := wr.GetByID(ctx, subscriptionId); err != nil {
+		return nil, err
+	}
+
+	var deliveryStatus any
+	if status != "" {
+		deliveryStatus = status
+	}
+	rows, err := wr.db.QueryContext(ctx,
+		`SELECT
+			d.delivery_id,
+			d.subscription_id,
+			d.event_id,
+			d.event_type,
+			d.delivery_status,
+			d.attempts,
+			CASE WHEN d.delivery_status = 'PENDING' THEN d.next_attempt_at END,
+			d.response_status,
+			d.last_error,
+			d.delivered_at,
+			d.created_at,
+			d.updated_at
+		FROM webhook_deliveries d
+		WHERE d.subscription_id = $1
+			AND ($2::all_webhook_delivery_status IS NULL OR d.delivery_status = $2)
+		ORDER BY d.created_at DESC, d.event_id DESC
+		LIMIT $3`,
+		subscriptionId,
+		deliveryStatus,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		err := rows.Scan(
+			&delivery.DeliveryId,
+			&delivery.SubscriptionId,
+			&delivery.EventId,
+			&delivery.EventType,
+			&delivery.DeliveryStatus,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.ResponseStatus,
+			&delivery.LastError,
+			&delivery.DeliveredAt,
+			&delivery.CreatedAt,
+			&delivery.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Enqueue creates a delivery of a webhook event, made from the domain event
+// eventId, for every active subscription to eventType. Enqueueing the same
+// event again does nothing.
+func (wr *WebhookRepo) Enqueue(ctx context.Context, eventId int64, eventType string, payload []byte) error {
+	_, err := conn(ctx, wr.db).ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT subscription_id, $1, $2::text, $3
+		FROM webhook_subscriptions
+		WHERE is_active AND $2::text = ANY(event_types)
+		ON CONFLICT (subscription_id, event_id, event_type) DO NOTHING`,
+		eventId,
+		eventType,
+		payload,
+	)
+	return err
+}
+
+// ClaimDeliveries takes the due deliveries of active subscriptions and counts
+// the attempt. A claimed delivery is not due again until lease has passed,
+// so another worker only retries it when this one died while sending.
+func (wr *WebhookRepo) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookJob, error) {
+	rows, err := wr.db.QueryContext(ctx,
+		`WITH due AS (
+			SELECT d.delivery_id
+			FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.subscription_id = d.subscription_id
+			WHERE d.delivery_status = 'PENDING' AND d.next_attempt_at <= now() AND s.is_active
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1,
+			next_attempt_at = now() + $2 * interval '1 second'
+		FROM due, webhook_subscriptions s, outbox_events e
+		WHERE d.delivery_id = due.delivery_id
+			AND s.subscription_id = d.subscription_id
+			AND e.event_id = d.event_id
+		RETURNING d.delivery_id, d.subscription_id, d.attempts, s.url, s.secret,
+			d.event_id, d.event_type, e.location_id, d.payload, e.created_at`,
+		limit,
+		lease.Seconds(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.WebhookJob
+	for rows.Next() {
+		var job models.WebhookJob
+		err := rows.Scan(
+			&job.Delivery.DeliveryId,
+			&job.Delivery.SubscriptionId,
+			&job.Delivery.Attempts,
+			&job.Url,
+			&job.Secret,
+			&job.Event.EventId,
+			&job.Event.EventType,
+			&job.Event.LocationId,
+			&job.Event.Payload,
+			&job.Event.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		job.Delivery.EventId = job.Event.EventId
+		job.Delivery.EventType = job.Event.EventType
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (wr *WebhookRepo) MarkDelivered(ctx context.Context, deliveryId string, responseStatus int) error {
+	_, err := wr.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries
+		SET delivery_status = 'DELIVERED',
+			response_status = $1,
+			last_error = '',
+			delivered_at = now()
+		WHERE delivery_id = $2`,
+		responseStatus,
+		deliveryId,
+	)
+	return err
+}
+
+// MarkFailed records a failed attempt. The delivery is retried at retryAt,
+// or given up when retryAt is nil.
+func (wr *WebhookRepo) MarkFailed(ctx context.Context, deliveryId string, responseStatus *int, lastError string, retryAt *time.Time) error {
+	_, err := wr.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries
+		SET delivery_status = CASE WHEN $3::timestamptz IS NULL THEN 'FAILED' ELSE 'PENDING' END::all_webhook_delivery_status,
+			response_status = $1,
+			last_error = $2,
+			next_attempt_at = COALESCE($3, next_attempt_at)
+		WHERE delivery_id = $4`,
+		responseStatus,
+		lastError,
+		retryAt,
+		deliveryId,
+	)
+	return err
+}
@@ -4,14 +4,39 @@ import (
 	"context"
 	"frappuccino/internal/repo"
 	"frappuccino/models"
+	"frappuccino/utils"
+	"sort"
 	"strings"
+	"time"
 )
 
+const (
+	maxSalesBuckets          = 1000
+	defaultPopularItemsLimit = 10
+	maxPopularItemsLimit     = 100
+	defaultPrepThreshold     = 15 * time.Minute
+	maxPrepOutliers          = 50
+)
+
+// salesBucketWidths holds the approximate width of each bucket, used to cap
+// the number of rows a single report can produce.
+var salesBucketWidths = map[string]time.Duration{
+	"hour":  time.Hour,
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 31 * 24 * time.Hour,
+}
+
 type AggregationServiceIfc interface {
 	GetTotalSales(ctx context.Context) (models.TotalSales, error)
-	GetPopularItems(ctx context.Context) (models.PopularItems, error)
+	GetPopularItems(ctx context.Context, from string, to string, category string, metric string, limit int) (models.PopularItems, error)
 	GetSearchItems(ctx context.Context, q string, filter string, maxPrice float64, minPrice float64) (models.Search, error)
 	GetListOfOrderedItems(ctx context.Context, period string, month string, year string) (models.ListOrderedItemByPeriods, error)
+	GetSalesReport(ctx context.Context, from string, to string, bucket string, timezone string, groupBy string) (models.SalesReport, error)
+	GetHeatmap(ctx context.Context, from string, to string, timezone string) (models.Heatmap, error)
+	GetTipReport(ctx context.Context, from string, to string, timezone string) (models.TipReport, error)
+	GetInventoryVariance(ctx context.Context, from string, to string) (models.InventoryVarianceReport, error)
+	GetPrepTimeReport(ctx context.Context, from string, to string, timezone string, fromStatus string, toStatus string, threshold string) (models.PrepTimeReport, error)
 }
 
 type AggregationService struct {
@@ -32,11 +57,59 @@ func (as *AggregationService) GetTotalSales(ctx context.Context) (models.TotalSa
 	return totalSales, nil
 }
 
-func (as *AggregationService) GetPopularItems(ctx context.Context) (models.PopularItems, error) {
-	popularItems, err := as.AggregationRepo.GetPopularItems(ctx)
+func (as *AggregationService) GetPopularItems(ctx context.Context, from string, to string, category string, metric string, limit int) (models.PopularItems, error) {
+	filter := models.PopularItemsFilter{
+		Category: category,
+		Metric:   metric,
+		Limit:    limit,
+	}
+	if filter.Metric == "" {
+		filter.Metric = "units"
+	}
+	switch filter.Metric {
+	case "units", "revenue", "customers":
+	default:
+		return models.PopularItems{}, utils.ErrInvalidMetric
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPopularItemsLimit
+	}
+	if filter.Limit > maxPopularItemsLimit {
+		filter.Limit = maxPopularItemsLimit
+	}
+	if from != "" {
+		fromTime, err := parseReportTime(from, time.UTC, false)
+		if err != nil {
+			return models.PopularItems{}, err
+		}
+		filter.From = &fromTime
+	}
+	if to != "" {
+		toTime, err := parseReportTime(to, time.UTC, true)
+		if err != nil {
+			return models.PopularItems{}, err
+		}
+		filter.To = &toTime
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return models.PopularItems{}, utils.ErrInvalidDateRange
+	}
+
+	popularItems, err := as.AggregationRepo.GetPopularItems(ctx, filter)
 	if err != nil {
 		return models.PopularItems{}, err
 	}
+	if popularItems.Items == nil {
+		popularItems.Items = []models.PopularItem{}
+	}
+	if filter.From != nil {
+		popularItems.From = filter.From.Format(time.RFC3339)
+	}
+	if filter.To != nil {
+		popularItems.To = filter.To.Format(time.RFC3339)
+	}
+	popularItems.Category = filter.Category
+	popularItems.Metric = filter.Metric
 	return popularItems, nil
 }
 
@@ -59,3 +132,268 @@ func (as *AggregationService) GetListOfOrderedItems(ctx context.Context, period
 	list.Year = year
 	return list, nil
 }
+
+func (as *AggregationService) GetSalesReport(ctx context.Context, from string, to string, bucket string, timezone string, groupBy string) (models.SalesReport, error) {
+	if bucket == "" {
+		bucket = "day"
+	}
+	width, ok := salesBucketWidths[bucket]
+	if !ok {
+		return models.SalesReport{}, utils.ErrInvalidBucket
+	}
+	switch groupBy {
+	case "", "menu_item", "category", "payment_method", "status", "location", "staff":
+	default:
+		return models.SalesReport{}, utils.ErrInvalidGroupBy
+	}
+	fromTime, toTime, timezone, err := parseReportWindow(from, to, timezone, 30)
+	if err != nil {
+		return models.SalesReport{}, err
+	}
+	if toTime.Sub(fromTime)/width > maxSalesBuckets {
+		return models.SalesReport{}, utils.ErrInvalidDateRange
+	}
+
+	buckets, err := as.AggregationRepo.GetSalesReport(ctx, fromTime, toTime, bucket, timezone, groupBy)
+	if err != nil {
+		return models.SalesReport{}, err
+	}
+	for i := range buckets {
+		if buckets[i].OrderCount > 0 {
+			buckets[i].AverageTicket = buckets[i].GrossRevenue / float64(buckets[i].OrderCount)
+		}
+	}
+	if buckets == nil {
+		buckets = []models.SalesBucket{}
+	}
+
+	return models.SalesReport{
+		From:     fromTime.Format(time.RFC3339),
+		To:       toTime.Format(time.RFC3339),
+		Bucket:   bucket,
+		Timezone: timezone,
+		GroupBy:  groupBy,
+		Buckets:  buckets,
+	}, nil
+}
+
+func (as *AggregationService) GetHeatmap(ctx context.Context, from string, to string, timezone string) (models.Heatmap, error) {
+	fromTime, toTime, timezone, err := parseReportWindow(from, to, timezone, 28)
+	if err != nil {
+		return models.Heatmap{}, err
+	}
+
+	cells, err := as.AggregationRepo.GetHeatmap(ctx, fromTime, toTime, timezone)
+	if err != nil {
+		return models.Heatmap{}, err
+	}
+	for i := range cells {
+		cells[i].WeekdayName = time.Weekday(cells[i].Weekday % 7).String()
+	}
+
+	return models.Heatmap{
+		From:     fromTime.Format(time.RFC3339),
+		To:       toTime.Format(time.RFC3339),
+		Timezone: timezone,
+		Cells:    cells,
+	}, nil
+}
+
+// GetTipReport lists the tips per day and staff member and totals them both
+// ways.
+func (as *AggregationService) GetTipReport(ctx context.Context, from string, to string, timezone string) (models.TipReport, error) {
+	fromTime, toTime, timezone, err := parseReportWindow(from, to, timezone, 30)
+	if err != nil {
+		return models.TipReport{}, err
+	}
+
+	rows, err := as.AggregationRepo.GetTipReport(ctx, fromTime, toTime, timezone)
+	if err != nil {
+		return models.TipReport{}, err
+	}
+
+	report := models.TipReport{
+		From:     fromTime.Format(time.RFC3339),
+		To:       toTime.Format(time.RFC3339),
+		Timezone: timezone,
+		ByStaff:  []models.TipTotal{},
+		ByDay:    []models.TipTotal{},
+		Rows:     rows,
+	}
+	if report.Rows == nil {
+		report.Rows = []models.TipReportRow{}
+	}
+
+	staffIndex := map[string]int{}
+	for _, row := range rows {
+		report.TotalTips += row.Tips
+
+		i, ok := staffIndex[row.StaffId]
+		if !ok {
+			i = len(report.ByStaff)
+			staffIndex[row.StaffId] = i
+			report.ByStaff = append(report.ByStaff, models.TipTotal{Key: row.StaffId, Name: row.StaffName})
+		}
+		report.ByStaff[i].Tips += row.Tips
+		report.ByStaff[i].PaymentCount += row.PaymentCount
+
+		// Rows are ordered by day, so a new day is always the last total
+		if n := len(report.ByDay); n == 0 || report.ByDay[n-1].Key != row.Day {
+			report.ByDay = append(report.ByDay, models.TipTotal{Key: row.Day})
+		}
+		report.ByDay[len(report.ByDay)-1].Tips += row.Tips
+		report.ByDay[len(report.ByDay)-1].PaymentCount += row.PaymentCount
+	}
+	sort.Slice(report.ByStaff, func(i, j int) bool {
+		return report.ByStaff[i].Tips > report.ByStaff[j].Tips
+	})
+
+	report.TotalTips = round2(report.TotalTips)
+	for i := range report.ByStaff {
+		report.ByStaff[i].Tips = round2(report.ByStaff[i].Tips)
+	}
+	for i := range report.ByDay {
+		report.ByDay[i].Tips = round2(report.ByDay[i].Tips)
+	}
+
+	return report, nil
+}
+
+func (as *AggregationService) GetPrepTimeReport(ctx context.Context, from string, to string, timezone string, fromStatus string, toStatus string, threshold string) (models.PrepTimeReport, error) {
+	fromTime, toTime, timezone, err := parseReportWindow(from, to, timezone, 30)
+	if err != nil {
+		return models.PrepTimeReport{}, err
+	}
+	if fromStatus == "" {
+		fromStatus = "PENDING"
+	}
+	if toStatus == "" {
+		toStatus = "COMPLETED"
+	}
+	fromStatus, toStatus = strings.ToUpper(fromStatus), strings.ToUpper(toStatus)
+	if !orderStatuses[fromStatus] || !orderStatuses[toStatus] || fromStatus == toStatus {
+		return models.PrepTimeReport{}, utils.ErrInvalidStatus
+	}
+	limit := defaultPrepThreshold
+	if threshold != "" {
+		limit, err = time.ParseDuration(threshold)
+		if err != nil || limit <= 0 {
+			return models.PrepTimeReport{}, utils.ErrInvalidThreshold
+		}
+	}
+
+	stats, err := as.AggregationRepo.GetPrepTimeStats(ctx, fromTime, toTime, timezone, fromStatus, toStatus)
+	if err != nil {
+		return models.PrepTimeReport{}, err
+	}
+	outliers, err := as.AggregationRepo.GetPrepTimeOutliers(ctx, fromTime, toTime, fromStatus, toStatus, limit, maxPrepOutliers)
+	if err != nil {
+		return models.PrepTimeReport{}, err
+	}
+
+	report := models.PrepTimeReport{
+		From:       fromTime.Format(time.RFC3339),
+		To:         toTime.Format(time.RFC3339),
+		Timezone:   timezone,
+		FromStatus: fromStatus,
+		ToStatus:   toStatus,
+		Threshold:  limit.String(),
+		ByMenuItem: []models.PrepTimeStats{},
+		ByHour:     []models.PrepTimeStats{},
+		ByDay:      []models.PrepTimeStats{},
+		Outliers:   []models.PrepTimeOutlier{},
+	}
+	if overall := stats["overall"]; len(overall) > 0 {
+		report.Overall = overall[0]
+	}
+	if len(stats["menu_item"]) > 0 {
+		report.ByMenuItem = stats["menu_item"]
+	}
+	if len(stats["hour"]) > 0 {
+		report.ByHour = stats["hour"]
+	}
+	if len(stats["day"]) > 0 {
+		report.ByDay = stats["day"]
+	}
+	if len(outliers) > 0 {
+		report.Outliers = outliers
+	}
+	return report, nil
+}
+
+// GetInventoryVariance reports, per ingredient, how far actual consumption
+// (sales, write-offs, stock count corrections and downward adjustments)
+// drifted from what the recipes of completed orders should have used.
+func (as *AggregationService) GetInventoryVariance(ctx context.Context, from string, to string) (models.InventoryVarianceReport, error) {
+	fromTime, toTime, _, err := parseReportWindow(from, to, "", 30)
+	if err != nil {
+		return models.InventoryVarianceReport{}, err
+	}
+
+	items, err := as.AggregationRepo.GetInventoryVariance(ctx, fromTime, toTime)
+	if err != nil {
+		return models.InventoryVarianceReport{}, err
+	}
+
+	report := models.InventoryVarianceReport{
+		From:  fromTime.Format(time.RFC3339),
+		To:    toTime.Format(time.RFC3339),
+		Items: []models.IngredientVariance{},
+	}
+	for _, item := range items {
+		item.ActualUsage = item.RecordedSales + item.Waste + item.Spoilage + item.Theft - item.Adjustments
+		item.VarianceUnits = item.ActualUsage - item.TheoreticalUsage
+		item.VarianceCost = item.VarianceUnits * item.UnitCost
+		report.TotalVarianceCost += item.VarianceCost
+		report.Items = append(report.Items, item)
+	}
+	return report, nil
+}
+
+// parseReportWindow resolves the from/to query values of a report in the
+// given timezone. A missing end defaults to now and a missing start to
+// defaultDays before the end.
+func parseReportWindow(from string, to string, timezone string, defaultDays int) (time.Time, time.Time, string, error) {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, time.Time{}, "", utils.ErrInvalidTimezone
+	}
+
+	toTime := time.Now().In(location)
+	if to != "" {
+		toTime, err = parseReportTime(to, location, true)
+		if err != nil {
+			return time.Time{}, time.Time{}, "", err
+		}
+	}
+	fromTime := toTime.AddDate(0, 0, -defaultDays)
+	if from != "" {
+		fromTime, err = parseReportTime(from, location, false)
+		if err != nil {
+			return time.Time{}, time.Time{}, "", err
+		}
+	}
+	if !fromTime.Before(toTime) {
+		return time.Time{}, time.Time{}, "", utils.ErrInvalidDateRange
+	}
+	return fromTime, toTime, timezone, nil
+}
+
+// parseReportTime accepts either a full RFC 3339 timestamp or a bare date in
+// the report timezone. A bare end date covers the whole day.
+func parseReportTime(value string, location *time.Location, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, location)
+	if err != nil {
+		return time.Time{}, utils.ErrInvalidDateRange
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"frappuccino/internal/repo"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"regexp"
+	"strings"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// auditResources lists the resources whose changes are written to the audit log.
+var auditResources = map[string]bool{
+	"customer":    true,
+	"menu":        true,
+	"inventory":   true,
+	"order":       true,
+	"stock_count": true,
+	"transfer":    true,
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+type AuditServiceIfc interface {
+	GetByResource(ctx context.Context, resource string, resourceId string, limit int) ([]models.AuditEntry, error)
+}
+
+type AuditService struct {
+	auditRepo repo.AuditRepoIfc
+}
+
+func NewAuditService(auditRepo repo.AuditRepoIfc) *AuditService {
+	return &AuditService{auditRepo: auditRepo}
+}
+
+func (as *AuditService) GetByResource(ctx context.Context, resource string, resourceId string, limit int) ([]models.AuditEntry, error) {
+	resource = strings.ToLower(resource)
+	if !auditResources[resource] {
+		return nil, utils.ErrInvalidAuditResource
+	}
+	if resourceId != "" && !uuidPattern.MatchString(resourceId) {
+		return nil, utils.ErrInvalidAuditId
+	}
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+
+	entries, err := as.auditRepo.GetByResource(ctx, resource, resourceId, limit)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+	return entries, nil
+}
+
+// recordAudit appends a change to the audit log. Call it within the
+// transaction that makes the change, so that a change is never committed
+// without its entry. A nil before or after is stored as NULL.
+func recordAudit(ctx context.Context, auditRepo repo.AuditRepoIfc, resource string, resourceId utils.TEXT, before any, after any) error {
+	entry := models.AuditEntry{Resource: utils.TEXT(resource)}
+	if resourceId != "" {
+		entry.ResourceId = &resourceId
+	}
+
+	method, route, _ := strings.Cut(utils.RouteFrom(ctx), " ")
+	entry.Method = utils.TEXT(method)
+	entry.Route = utils.TEXT(route)
+
+	if principal, ok := utils.PrincipalFrom(ctx); ok {
+		entry.ActorName = utils.TEXT(principal.Name)
+		if principal.StaffId != "" {
+			staffId := utils.TEXT(principal.StaffId)
+			entry.ActorStaffId = &staffId
+		}
+		if principal.ApiKeyId != "" {
+			apiKeyId := utils.TEXT(principal.ApiKeyId)
+			entry.ActorApiKeyId = &apiKeyId
+		}
+	}
+
+	var err error
+	if entry.Before, err = auditSnapshot(before); err != nil {
+		return err
+	}
+	if entry.After, err = auditSnapshot(after); err != nil {
+		return err
+	}
+	if err := auditRepo.Record(ctx, &entry); err != nil {
+		return fmt.Errorf("recording audit entry for %s [%s]: %w", resource, resourceId, err)
+	}
+	return nil
+}
+
+func auditSnapshot(value any) (utils.JSONB, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
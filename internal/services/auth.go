@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"frappuccino/internal/repo"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	sessionTTL     = 12 * time.Hour
+	apiKeyPrefix   = "fk_"
+	apiKeyShownLen = 11
+)
+
+type AuthServiceIfc interface {
+	Login(ctx context.Context, request *models.LoginRequest) (models.AuthSession, error)
+	Logout(ctx context.Context, token string) error
+	AuthenticateToken(ctx context.Context, token string) (utils.Principal, error)
+	AuthenticateAPIKey(ctx context.Context, key string) (utils.Principal, error)
+	CreateAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, apiKeyId string) error
+	Bootstrap(ctx context.Context, username string, password string) error
+}
+
+type AuthService struct {
+	authRepo     repo.AuthRepoIfc
+	staffRepo    repo.StaffRepoIfc
+	staffService StaffServiceIfc
+}
+
+func NewAuthService(authRepo repo.AuthRepoIfc, staffRepo repo.StaffRepoIfc, staffService StaffServiceIfc) *AuthService {
+	return &AuthService{authRepo: authRepo, staffRepo: staffRepo, staffService: staffService}
+}
+
+// Login checks the credentials and issues a bearer token. Unknown users,
+// wrong passwords and disabled accounts get the same error.
+func (as *AuthService) Login(ctx context.Context, request *models.LoginRequest) (models.AuthSession, error) {
+	staff, passwordHash, err := as.staffRepo.GetByUsername(ctx, strings.TrimSpace(string(request.Username)))
+	if err != nil {
+		if errors.Is(err, utils.ErrIdNotFound) {
+			return models.AuthSession{}, utils.ErrInvalidCredentials
+		}
+		return models.AuthSession{}, err
+	}
+	if !utils.CheckPassword(string(request.Password), passwordHash) || !staff.IsActive {
+		return models.AuthSession{}, utils.ErrInvalidCredentials
+	}
+
+	token, tokenHash, err := utils.NewToken("")
+	if err != nil {
+		return models.AuthSession{}, err
+	}
+	expiresAt := time.Now().Add(sessionTTL)
+	if err := as.authRepo.CreateSession(ctx, string(staff.StaffId), tokenHash, expiresAt); err != nil {
+		return models.AuthSession{}, err
+	}
+
+	log.Printf("Staff [%s] logged in", staff.StaffId)
+	return models.AuthSession{
+		Token:     utils.TEXT(token),
+		TokenType: "Bearer",
+		ExpiresAt: utils.TIME(expiresAt),
+		Staff:     staff,
+	}, nil
+}
+
+func (as *AuthService) Logout(ctx context.Context, token string) error {
+	return as.authRepo.DeleteSession(ctx, utils.HashToken(token))
+}
+
+func (as *AuthService) AuthenticateToken(ctx context.Context, token string) (utils.Principal, error) {
+	staff, err := as.authRepo.GetSessionStaff(ctx, utils.HashToken(token))
+	if err != nil {
+		return utils.Principal{}, err
+	}
+	return utils.Principal{
+		StaffId: string(staff.StaffId),
+		Name:    string(staff.Username),
+		Role:    string(staff.StaffRole),
+	}, nil
+}
+
+func (as *AuthService) AuthenticateAPIKey(ctx context.Context, key string) (utils.Principal, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return utils.Principal{}, utils.ErrUnauthorized
+	}
+	apiKey, err := as.authRepo.GetAPIKeyByHash(ctx, utils.HashToken(key))
+	if err != nil {
+		return utils.Principal{}, err
+	}
+	return utils.Principal{
+		ApiKeyId: string(apiKey.ApiKeyId),
+		Name:     string(apiKey.KeyName),
+		Role:     string(apiKey.KeyRole),
+	}, nil
+}
+
+// CreateAPIKey issues a key for an integration. The secret is returned once
+// and only its hash is stored.
+func (as *AuthService) CreateAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	key.KeyName = utils.TEXT(strings.TrimSpace(string(key.KeyName)))
+	if key.KeyName == "" {
+		return nil, utils.ErrInvalidKeyName
+	}
+	key.KeyRole = utils.TEXT(strings.ToUpper(string(key.KeyRole)))
+	if key.KeyRole == "" {
+		key.KeyRole = utils.RoleBarista
+	}
+	if !utils.IsRole(string(key.KeyRole)) {
+		return nil, utils.ErrInvalidRole
+	}
+	if principal, ok := utils.PrincipalFrom(ctx); ok && principal.StaffId != "" {
+		createdBy := utils.TEXT(principal.StaffId)
+		key.CreatedBy = &createdBy
+	}
+
+	secret, keyHash, err := utils.NewToken(apiKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	key.KeyPrefix = utils.TEXT(secret[:apiKeyShownLen])
+
+	created, err := as.authRepo.CreateAPIKey(ctx, key, keyHash)
+	if err != nil {
+		return nil, err
+	}
+	created.Key = utils.TEXT(secret)
+	log.Printf("API key [%s] created for %s", created.ApiKeyId, created.KeyName)
+	return created, nil
+}
+
+func (as *AuthService) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	return as.authRepo.GetAPIKeys(ctx)
+}
+
+func (as *AuthService) RevokeAPIKey(ctx context.Context, apiKeyId string) error {
+	log.Printf("Revoking API key [%s]", apiKeyId)
+	return as.authRepo.RevokeAPIKey(ctx, apiKeyId)
+}
+
+// Bootstrap creates the first admin account from the given credentials when
+// no staff exist yet, so a fresh database can be logged into.
+func (as *AuthService) Bootstrap(ctx context.Context, username string, password string) error {
+	count, err := as.staffRepo.Count(ctx)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	if username == "" || password == "" {
+		log.Println("No staff accounts exist; set ADMIN_USERNAME and ADMIN_PASSWORD to create the first admin")
+		return nil
+	}
+
+	_, err = as.staffService.Create(ctx, &models.Staff{
+		FullName:  "Administrator",
+		Username:  utils.TEXT(username),
+		Password:  utils.TEXT(password),
+		StaffRole: utils.RoleAdmin,
+	})
+	return err
+}
@@ -8,14 +8,45 @@ type Base struct {
 	MenuService        MenuServiceIfc
 	OrderService       OrderServiceIfc
 	AggregationService AggregationServiceIfc
+	StockCountService  StockCountServiceIfc
+	LocationService    LocationServiceIfc
+	TransferService    StockTransferServiceIfc
+	StaffService       StaffServiceIfc
+	AuthService        AuthServiceIfc
+	AuditService       AuditServiceIfc
+	ShiftService       ShiftServiceIfc
+	PaymentService     PaymentServiceIfc
+	RefundService      RefundServiceIfc
+	IdempotencyService IdempotencyServiceIfc
+	OrderStreamService OrderStreamServiceIfc
+	WebhookService     WebhookServiceIfc
+	EventBus           EventBusIfc
+	StationService     StationServiceIfc
+	ReceiptService     ReceiptServiceIfc
 }
 
 func New(repo *repo.Repo) *Base {
 	var service Base
-	service.CustomerService = NewCustomerService(repo.CustomerRepo)
+	service.EventBus = NewEventBus(repo.TxRepo, repo.OutboxRepo)
+	service.CustomerService = NewCustomerService(repo.CustomerRepo, repo.AuditRepo, repo.TxRepo)
 	service.AggregationService = NewAggregationService(repo.AggregationRepo)
-	service.InventoryService = NewInventoryService(repo.InventoryRepo)
-	service.MenuService = NewMenuService(repo.MenuRepo)
-	service.OrderService = NewOrderService(repo.OrderRepo)
+	service.InventoryService = NewInventoryService(repo.InventoryRepo, repo.AuditRepo, service.EventBus)
+	service.MenuService = NewMenuService(repo.MenuRepo, repo.AuditRepo, service.EventBus)
+	service.OrderService = NewOrderService(repo.OrderRepo, repo.StationRepo, repo.StaffRepo, repo.AuditRepo, service.EventBus)
+	service.StockCountService = NewStockCountService(repo.StockCountRepo, repo.InventoryRepo, repo.AuditRepo, service.EventBus)
+	service.LocationService = NewLocationService(repo.LocationRepo)
+	service.TransferService = NewStockTransferService(repo.TransferRepo, repo.InventoryRepo, repo.AuditRepo, service.EventBus)
+	service.StaffService = NewStaffService(repo.StaffRepo)
+	service.AuthService = NewAuthService(repo.AuthRepo, repo.StaffRepo, service.StaffService)
+	service.AuditService = NewAuditService(repo.AuditRepo)
+	service.ShiftService = NewShiftService(repo.ShiftRepo)
+	service.PaymentService = NewPaymentService(repo.PaymentRepo, repo.OrderRepo, repo.AuditRepo, service.EventBus)
+	service.RefundService = NewRefundService(repo.RefundRepo, repo.OrderRepo, repo.AuditRepo, service.EventBus)
+	service.IdempotencyService = NewIdempotencyService(repo.IdempotencyRepo)
+	service.OrderStreamService = NewOrderStreamService(repo.OrderEventRepo)
+	service.WebhookService = NewWebhookService(repo.WebhookRepo)
+	service.StationService = NewStationService(repo.StationRepo, repo.OrderRepo, repo.AuditRepo, service.EventBus)
+	service.ReceiptService = NewReceiptService(repo.OrderRepo, repo.RefundRepo, repo.CustomerRepo, repo.LocationRepo, ShopHeaderFromEnv())
+	service.EventBus.Subscribe("webhooks", service.WebhookService.HandleEvent)
 	return &service
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"frappuccino/internal/repo"
 	"frappuccino/models"
+	"frappuccino/utils"
 	"log"
 )
 
@@ -12,21 +13,33 @@ type CustomerServiceIfc interface {
 	GetAll(ctx context.Context) ([]models.Customer, error)
 	GetByID(ctx context.Context, customerId string) (models.Customer, error)
 	UpdateById(ctx context.Context, customer *models.Customer) error
+	PatchById(ctx context.Context, customerId string, patch []byte) (models.Customer, error)
 	DeleteCustomerById(ctx context.Context, customerId string) error
+	RestoreCustomerById(ctx context.Context, customerId string) error
 	GetByFullNameAndPhone(ctx context.Context, fullname string, phone string) (string, error)
 }
 
 type CustomerService struct {
 	customerRepo repo.CustomerRepoIfc
+	auditRepo    repo.AuditRepoIfc
+	txRepo       repo.TxRepoIfc
 }
 
-func NewCustomerService(customerRepo repo.CustomerRepoIfc) *CustomerService {
-	return &CustomerService{customerRepo: customerRepo}
+func NewCustomerService(customerRepo repo.CustomerRepoIfc, auditRepo repo.AuditRepoIfc, txRepo repo.TxRepoIfc) *CustomerService {
+	return &CustomerService{customerRepo: customerRepo, auditRepo: auditRepo, txRepo: txRepo}
 }
 
 func (cs *CustomerService) Create(ctx context.Context, customer *models.Customer) (*models.Customer, error) {
 	log.Println("Creating new customer:", customer.FullName)
-	created, err := cs.customerRepo.Create(ctx, customer)
+	var created *models.Customer
+	err := cs.txRepo.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = cs.customerRepo.Create(ctx, customer)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, cs.auditRepo, "customer", created.CustomerId, nil, created)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +69,20 @@ func (cs *CustomerService) GetByID(ctx context.Context, customerId string) (mode
 
 func (cs *CustomerService) UpdateById(ctx context.Context, customer *models.Customer) error {
 	log.Printf("Updating customer [%s]", customer.CustomerId)
-	err := cs.customerRepo.UpdateById(ctx, customer)
+	err := cs.txRepo.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := cs.customerRepo.GetByID(ctx, string(customer.CustomerId))
+		if err != nil {
+			return err
+		}
+		if err := cs.customerRepo.UpdateById(ctx, customer); err != nil {
+			return err
+		}
+		after, err := cs.customerRepo.GetByID(ctx, string(customer.CustomerId))
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, cs.auditRepo, "customer", customer.CustomerId, before, after)
+	})
 	if err != nil {
 		return err
 	}
@@ -64,9 +90,76 @@ func (cs *CustomerService) UpdateById(ctx context.Context, customer *models.Cust
 	return nil
 }
 
+// PatchById applies a JSON merge patch to a customer. Only the fields named
+// by the patch are written; preferences are merged member by member.
+func (cs *CustomerService) PatchById(ctx context.Context, customerId string, patch []byte) (models.Customer, error) {
+	fields, err := utils.PatchFields(patch)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	log.Printf("Patching customer [%s]", customerId)
+	before, err := cs.customerRepo.GetByID(ctx, customerId)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	var patched models.Customer
+	if err := applyMergePatch(before, patch, &patched); err != nil {
+		return models.Customer{}, err
+	}
+
+	set := map[string]any{}
+	for _, field := range fields {
+		switch field {
+		case "full_name":
+			if patched.FullName == "" {
+				return models.Customer{}, emptyField(field)
+			}
+			set["full_name"] = patched.FullName
+		case "phone_number":
+			if patched.PhoneNumber == "" {
+				return models.Customer{}, emptyField(field)
+			}
+			set["phone_number"] = patched.PhoneNumber
+		case "email":
+			set["email"] = patched.Email
+		case "preferences":
+			set["preferences"] = patched.Preferences
+		default:
+			return models.Customer{}, readOnlyField(field)
+		}
+	}
+
+	var after models.Customer
+	err = cs.txRepo.WithinTx(ctx, func(ctx context.Context) error {
+		if err := cs.customerRepo.Patch(ctx, customerId, set); err != nil {
+			return err
+		}
+		var err error
+		after, err = cs.customerRepo.GetByID(ctx, customerId)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, cs.auditRepo, "customer", utils.TEXT(customerId), before, after)
+	})
+	if err != nil {
+		return models.Customer{}, err
+	}
+	log.Printf("Customer [%s] patched successfully", customerId)
+	return after, nil
+}
+
 func (cs *CustomerService) DeleteCustomerById(ctx context.Context, customerId string) error {
 	log.Printf("Deleting customer [%s]", customerId)
-	err := cs.customerRepo.DeleteById(ctx, customerId)
+	err := cs.txRepo.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := cs.customerRepo.GetByID(ctx, customerId)
+		if err != nil {
+			return err
+		}
+		if err := cs.customerRepo.DeleteById(ctx, customerId); err != nil {
+			return err
+		}
+		return recordAudit(ctx, cs.auditRepo, "customer", utils.TEXT(customerId), before, nil)
+	})
 	if err != nil {
 		return err
 	}
@@ -74,6 +167,25 @@ func (cs *CustomerService) DeleteCustomerById(ctx context.Context, customerId st
 	return nil
 }
 
+func (cs *CustomerService) RestoreCustomerById(ctx context.Context, customerId string) error {
+	log.Printf("Restoring customer [%s]", customerId)
+	err := cs.txRepo.WithinTx(ctx, func(ctx context.Context) error {
+		if err := cs.customerRepo.Restore(ctx, customerId); err != nil {
+			return err
+		}
+		after, err := cs.customerRepo.GetByID(ctx, customerId)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, cs.auditRepo, "customer", utils.TEXT(customerId), nil, after)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Customer [%s] restored successfully", customerId)
+	return nil
+}
+
 func (cs *CustomerService) GetByFullNameAndPhone(ctx context.Context, fullname string, phonenumber string) (string, error) {
 	log.Printf("Fetching customer ID by fullname and phone number: %s, %s", fullname, phonenumber)
 	customerId, err := cs.customerRepo.GetByFullNameAndPhone(ctx, fullname, phonenumber)
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"frappuccino/internal/repo"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"log"
+	"time"
+)
+
+const (
+	eventPollInterval = 2 * time.Second
+	eventBatchSize    = 100
+)
+
+// Domain event types, as stored in the outbox.
+const (
+	EventTypeOrderCreated       = "order.created"
+	EventTypeOrderStatusChanged = "order.status_changed"
+	EventTypeStockAdjusted      = "inventory.stock_adjusted"
+	EventTypePriceChanged       = "menu.price_changed"
+	EventTypePaymentRecorded    = "order.payment_recorded"
+)
+
+// Event is a change in the domain that other parts of the system react to.
+type Event interface {
+	EventType() string
+}
+
+type OrderCreated struct {
+	Order models.Orders `json:"order"`
+}
+
+// OrderStatusChanged holds the order as it is after the change.
+type OrderStatusChanged struct {
+	Order          models.Orders `json:"order"`
+	PreviousStatus utils.TEXT    `json:"previous_status"`
+}
+
+// StockAdjusted is a change to the stock of one ingredient at one location.
+// Reason is the inventory transaction action behind it, such as ADD, REMOVE,
+// ADJUST or WASTE.
+type StockAdjusted struct {
+	models.StockChange
+	Reason utils.TEXT `json:"reason"`
+}
+
+// PriceChanged is a change to the base price of a menu item.
+type PriceChanged struct {
+	MenuItemId utils.TEXT `json:"menu_item_id"`
+	ItemName   utils.TEXT `json:"item_name"`
+	OldPrice   utils.DEC  `json:"old_price"`
+	NewPrice   utils.DEC  `json:"new_price"`
+}
+
+// PaymentRecorded is one or more payments taken for an order, with the
+// payment state of the order after them.
+type PaymentRecorded struct {
+	LocationId utils.TEXT           `json:"location_id"`
+	Payments   []models.Payment     `json:"payments"`
+	State      models.OrderPayments `json:"state"`
+}
+
+func (OrderCreated) EventType() string       { return EventTypeOrderCreated }
+func (OrderStatusChanged) EventType() string { return EventTypeOrderStatusChanged }
+func (StockAdjusted) EventType() string      { return EventTypeStockAdjusted }
+func (PriceChanged) EventType() string       { return EventTypePriceChanged }
+func (PaymentRecorded) EventType() string    { return EventTypePaymentRecorded }
+
+// FellToReorderLevel reports whether the adjustment brought the stock down
+// to the reorder level of the ingredient.
+func (e StockAdjusted) FellToReorderLevel() bool {
+	return e.Quantity <= e.ReorderLevel && e.Quantity-e.Change > e.ReorderLevel
+}
+
+// eventDecoders reads each event type back from the outbox.
+var eventDecoders = map[string]func(payload []byte) (Event, error){
+	EventTypeOrderCreated:       decodeEvent[OrderCreated],
+	EventTypeOrderStatusChanged: decodeEvent[OrderStatusChanged],
+	EventTypeStockAdjusted:      decodeEvent[StockAdjusted],
+	EventTypePriceChanged:       decodeEvent[PriceChanged],
+	EventTypePaymentRecorded:    decodeEvent[PaymentRecorded],
+}
+
+func decodeEvent[E Event](payload []byte) (Event, error) {
+	var event E
+	err := json.Unmarshal(payload, &event)
+	return event, err
+}
+
+// eventLocation is the location an event happened at; menu prices belong to
+// none.
+func eventLocation(event Event) string {
+	switch e := event.(type) {
+	case OrderCreated:
+		return string(e.Order.LocationId)
+	case OrderStatusChanged:
+		return string(e.Order.LocationId)
+	case StockAdjusted:
+		return string(e.LocationId)
+	case PaymentRecorded:
+		return string(e.LocationId)
+	}
+	return ""
+}
+
+// PublishedEvent is an event read back from the outbox.
+type PublishedEvent struct {
+	EventId    int64
+	LocationId *utils.TEXT
+	CreatedAt  utils.TIME
+	Event      Event
+}
+
+// EventHandler reacts to a committed event. Whatever it writes through the
+// repos commits together with the subscriber's progress; an error makes the
+// bus offer the event again later, so handlers must tolerate repeats of any
+// effect outside the database.
+type EventHandler func(ctx context.Context, published PublishedEvent) error
+
+type EventBusIfc interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+	Publish(ctx context.Context, events ...Event) error
+	Subscribe(subscriber string, handler EventHandler)
+	Run(ctx context.Context)
+}
+
+type eventSubscription struct {
+	name    string
+	handler EventHandler
+}
+
+// EventBus carries domain events from the services that publish them to the
+// subscribers. Events go through the outbox: they are written in the
+// transaction of the change and only dispatched once it has committed.
+type EventBus struct {
+	txRepo      repo.TxRepoIfc
+	outboxRepo  repo.OutboxRepoIfc
+	subscribers []eventSubscription
+}
+
+func NewEventBus(txRepo repo.TxRepoIfc, outboxRepo repo.OutboxRepoIfc) *EventBus {
+	return &EventBus{txRepo: txRepo, outboxRepo: outboxRepo}
+}
+
+// WithinTx runs fn in one transaction; the repo calls and Publish calls made
+// with the ctx given to fn commit together.
+func (eb *EventBus) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return eb.txRepo.WithinTx(ctx, fn)
+}
+
+// Publish writes events to the outbox. Call it inside WithinTx so that they
+// are only dispatched if the change they describe is committed.
+func (eb *EventBus) Publish(ctx context.Context, events ...Event) error {
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if err := eb.outboxRepo.Append(ctx, event.EventType(), eventLocation(event), payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a handler for every event under a name that must stay
+// the same across restarts: the progress of the subscriber is kept under it.
+// Subscribers are registered before Run is started.
+func (eb *EventBus) Subscribe(subscriber string, handler EventHandler) {
+	eb.subscribers = append(eb.subscribers, eventSubscription{name: subscriber, handler: handler})
+}
+
+// Run dispatches committed events to the subscribers until ctx is done. Each
+// subscriber gets every event at least once and in order; one that fails is
+// retried from the failed event on the next round.
+func (eb *EventBus) Run(ctx context.Context) {
+	ticker := time.NewTicker(eventPollInterval)
+	defer ticker.Stop()
+	for {
+		for _, subscription := range eb.subscribers {
+			eb.dispatch(ctx, subscription)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (eb *EventBus) dispatch(ctx context.Context, subscription eventSubscription) {
+	for {
+		handled, err := eb.outboxRepo.Consume(ctx, subscription.name, eventBatchSize, func(ctx context.Context, event models.OutboxEvent) error {
+			decode, ok := eventDecoders[string(event.EventType)]
+			if !ok {
+				log.Printf("Skipping unknown event type %s [%d]", event.EventType, event.EventId)
+				return nil
+			}
+			payload, err := decode(event.Payload)
+			if err != nil {
+				log.Printf("Skipping malformed event %s [%d]: %v", event.EventType, event.EventId, err)
+				return nil
+			}
+			return subscription.handler(ctx, PublishedEvent{
+				EventId:    event.EventId,
+				LocationId: event.LocationId,
+				CreatedAt:  event.CreatedAt,
+				Event:      payload,
+			})
+		})
+		if err != nil {
+			log.Printf("Error dispatching events to %s: %v", subscription.name, err)
+			return
+		}
+		if handled < eventBatchSize {
+			return
+		}
+	}
+}
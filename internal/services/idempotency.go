@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"frappuccino/internal/repo"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// idempotencyTTL is how long a stored response is replayed.
+	idempotencyTTL = 24 * time.Hour
+	// idempotencyLockTimeout is how long a request may stay in progress
+	// before a retry with the same body is allowed to run it again.
+	idempotencyLockTimeout  = time.Minute
+	maxIdempotencyKeyLength = 255
+)
+
+type IdempotencyServiceIfc interface {
+	Begin(ctx context.Context, key string, body []byte) (*models.IdempotencyKey, error)
+	Finish(ctx context.Context, key string, statusCode int, contentType string, body []byte)
+}
+
+type IdempotencyService struct {
+	idempotencyRepo repo.IdempotencyRepoIfc
+}
+
+func NewIdempotencyService(idempotencyRepo repo.IdempotencyRepoIfc) *IdempotencyService {
+	return &IdempotencyService{idempotencyRepo: idempotencyRepo}
+}
+
+// Begin claims the key for the current request. It returns the stored
+// request when the key was already used for the same request and answered,
+// or nil when the request has to be processed now and handed to Finish.
+func (is *IdempotencyService) Begin(ctx context.Context, key string, body []byte) (*models.IdempotencyKey, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, utils.ErrInvalidIdempotencyKey
+	}
+
+	request := models.IdempotencyKey{
+		Scope:       utils.TEXT(idempotencyScope(ctx)),
+		Key:         utils.TEXT(key),
+		Route:       utils.TEXT(utils.RouteFrom(ctx)),
+		RequestHash: utils.TEXT(requestHash(body)),
+	}
+	reserved, err := is.idempotencyRepo.Reserve(ctx, &request, idempotencyTTL, idempotencyLockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	stored, err := is.idempotencyRepo.Get(ctx, string(request.Scope), key)
+	if err != nil {
+		// Released by a failed request in the meantime
+		if errors.Is(err, utils.ErrIdNotFound) {
+			return nil, utils.ErrIdempotencyInProgress
+		}
+		return nil, err
+	}
+	if stored.Route != request.Route || stored.RequestHash != request.RequestHash {
+		return nil, utils.ErrIdempotencyMismatch
+	}
+	if stored.StatusCode == nil {
+		return nil, utils.ErrIdempotencyInProgress
+	}
+
+	log.Printf("Replaying response for Idempotency-Key [%s]", key)
+	return &stored, nil
+}
+
+// Finish stores the response of a request started with Begin. Server errors
+// are not stored: the key is released so that the client can retry.
+func (is *IdempotencyService) Finish(ctx context.Context, key string, statusCode int, contentType string, body []byte) {
+	scope := idempotencyScope(ctx)
+	var err error
+	if statusCode >= http.StatusInternalServerError {
+		err = is.idempotencyRepo.Release(ctx, scope, key)
+	} else {
+		err = is.idempotencyRepo.Complete(ctx, scope, key, statusCode, contentType, body)
+	}
+	if err != nil {
+		log.Printf("Error storing response for Idempotency-Key [%s]: %v", key, err)
+	}
+}
+
+// idempotencyScope keeps the keys of different callers apart.
+func idempotencyScope(ctx context.Context) string {
+	principal, ok := utils.PrincipalFrom(ctx)
+	switch {
+	case !ok:
+		return "anonymous"
+	case principal.ApiKeyId != "":
+		return "api-key:" + principal.ApiKeyId
+	default:
+		return "staff:" + principal.StaffId
+	}
+}
+
+func requestHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
@@ -5,6 +5,18 @@ import (
 	"frappuccino/internal/repo"
 	"frappuccino/models"
 	"frappuccino/utils"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultForecastHistoryDays = 28
+	maxForecastHistoryDays     = 365
+	defaultForecastLeadTime    = 3
+	maxForecastHorizonDays     = 365
+	defaultExpiringWithin      = 48 * time.Hour
 )
 
 type InventoryServiceIfc interface {
@@ -12,17 +24,27 @@ type InventoryServiceIfc interface {
 	GetAll(ctx context.Context) ([]models.Inventory, error)
 	GetByID(ctx context.Context, ingredientId string) (models.Inventory, error)
 	UpdateByID(ctx context.Context, ingerdientId *models.Inventory) error
+	Patch(ctx context.Context, ingredientId string, patch []byte) (models.Inventory, error)
 	DeleteByID(ctx context.Context, ingerdientId string) error
+	Restore(ctx context.Context, ingredientId string) error
 	CreateTransaction(ctx context.Context, inventoryItem *models.Inventory, istatus string) error
 	GetLeftOvers(ctx context.Context, pagenum int, pagesize int) (models.Page, error)
+	GetForecast(ctx context.Context, historyDays int, leadTimeDays int) (models.InventoryForecast, error)
+	RecordWaste(ctx context.Context, ingredientId string, waste *models.WasteRecord) (models.InventoryTransactions, error)
+	CreateBatch(ctx context.Context, batch *models.InventoryBatch) (*models.InventoryBatch, error)
+	GetBatches(ctx context.Context, ingredientId string) ([]models.InventoryBatch, error)
+	GetExpiring(ctx context.Context, within string) ([]models.InventoryBatch, error)
+	WriteOffExpired(ctx context.Context) ([]models.InventoryBatch, error)
 }
 
 type InventoryService struct {
 	inventoryRepo repo.InventoryRepoIfc
+	auditRepo     repo.AuditRepoIfc
+	events        EventBusIfc
 }
 
-func NewInventoryService(inventoryRepo repo.InventoryRepoIfc) *InventoryService {
-	return &InventoryService{inventoryRepo: inventoryRepo}
+func NewInventoryService(inventoryRepo repo.InventoryRepoIfc, auditRepo repo.AuditRepoIfc, events EventBusIfc) *InventoryService {
+	return &InventoryService{inventoryRepo: inventoryRepo, auditRepo: auditRepo, events: events}
 }
 
 func (is *InventoryService) Create(ctx context.Context, ingredient *models.Inventory) (*models.Inventory, error) {
@@ -33,7 +55,19 @@ func (is *InventoryService) Create(ctx context.Context, ingredient *models.Inven
 		return nil, utils.ErrInvalidReorderLevel
 	}
 
-	return is.inventoryRepo.Create(ctx, ingredient)
+	var created *models.Inventory
+	err := is.events.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = is.inventoryRepo.Create(ctx, ingredient)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, is.auditRepo, "inventory", created.IngredientId, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
 func (is *InventoryService) GetAll(ctx context.Context) ([]models.Inventory, error) {
@@ -60,20 +94,410 @@ func (is *InventoryService) UpdateByID(ctx context.Context, ingredient *models.I
 	if ingredient.ReorderLevel < 0 {
 		return utils.ErrInvalidReorderLevel
 	}
-	return is.inventoryRepo.UpdateByID(ctx, ingredient)
+	before, err := is.inventoryRepo.GetByID(ctx, string(ingredient.IngredientId))
+	if err != nil {
+		return err
+	}
+	return is.adjustStock(ctx, "ADJUST", []utils.TEXT{ingredient.IngredientId}, func(ctx context.Context) error {
+		if err := is.inventoryRepo.UpdateByID(ctx, ingredient); err != nil {
+			return err
+		}
+		_, err := is.recordChange(ctx, ingredient.IngredientId, before)
+		return err
+	})
+}
+
+// Patch applies a JSON merge patch to an ingredient. quantity is the stock
+// of the selected location, as in GetByID.
+func (is *InventoryService) Patch(ctx context.Context, ingredientId string, patch []byte) (models.Inventory, error) {
+	fields, err := utils.PatchFields(patch)
+	if err != nil {
+		return models.Inventory{}, err
+	}
+	before, err := is.inventoryRepo.GetByID(ctx, ingredientId)
+	if err != nil {
+		return models.Inventory{}, err
+	}
+	var patched models.Inventory
+	if err := applyMergePatch(before, patch, &patched); err != nil {
+		return models.Inventory{}, err
+	}
+
+	set := map[string]any{}
+	var quantity *utils.DEC
+	for _, field := range fields {
+		switch field {
+		case "ingredient_name":
+			if patched.IngredientName == "" {
+				return models.Inventory{}, utils.ErrInvalidIngredientName
+			}
+			set["ingredient_name"] = patched.IngredientName
+		case "unit":
+			if patched.Unit == "" {
+				return models.Inventory{}, emptyField(field)
+			}
+			set["unit"] = patched.Unit
+		case "reorder_level":
+			if patched.ReorderLevel < 0 {
+				return models.Inventory{}, utils.ErrInvalidReorderLevel
+			}
+			set["reorder_level"] = patched.ReorderLevel
+		case "unit_cost":
+			if patched.UnitCost < 0 {
+				return models.Inventory{}, utils.ErrInvalidPrice
+			}
+			set["unit_cost"] = patched.UnitCost
+		case "quantity":
+			if patched.Quantity < 0 {
+				return models.Inventory{}, utils.ErrInvalidQuantity
+			}
+			quantity = &patched.Quantity
+		default:
+			return models.Inventory{}, readOnlyField(field)
+		}
+	}
+
+	var adjusted []utils.TEXT
+	if quantity != nil {
+		adjusted = append(adjusted, utils.TEXT(ingredientId))
+	}
+	var after models.Inventory
+	err = is.adjustStock(ctx, "ADJUST", adjusted, func(ctx context.Context) error {
+		if err := is.inventoryRepo.Patch(ctx, ingredientId, set, quantity); err != nil {
+			return err
+		}
+		var err error
+		after, err = is.recordChange(ctx, utils.TEXT(ingredientId), before)
+		return err
+	})
+	if err != nil {
+		return models.Inventory{}, err
+	}
+	return after, nil
 }
 
 func (is *InventoryService) DeleteByID(ctx context.Context, IngredientId string) error {
 	if IngredientId == "" {
 		return utils.ErrInvalidIngredientId
 	}
-	return is.inventoryRepo.DeleteByID(ctx, IngredientId)
+	return is.events.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := is.inventoryRepo.GetByID(ctx, IngredientId)
+		if err != nil {
+			return err
+		}
+		if err := is.inventoryRepo.DeleteByID(ctx, IngredientId); err != nil {
+			return err
+		}
+		return recordAudit(ctx, is.auditRepo, "inventory", utils.TEXT(IngredientId), before, nil)
+	})
+}
+
+func (is *InventoryService) Restore(ctx context.Context, IngredientId string) error {
+	if IngredientId == "" {
+		return utils.ErrInvalidIngredientId
+	}
+	return is.events.WithinTx(ctx, func(ctx context.Context) error {
+		if err := is.inventoryRepo.Restore(ctx, IngredientId); err != nil {
+			return err
+		}
+		_, err := is.recordChange(ctx, utils.TEXT(IngredientId), nil)
+		return err
+	})
 }
 
 func (is *InventoryService) CreateTransaction(ctx context.Context, inventoryItem *models.Inventory, status string) error {
 	return is.inventoryRepo.CreateTransaction(ctx, inventoryItem, status)
 }
 
+func (is *InventoryService) RecordWaste(ctx context.Context, ingredientId string, waste *models.WasteRecord) (models.InventoryTransactions, error) {
+	if ingredientId == "" {
+		return models.InventoryTransactions{}, utils.ErrInvalidIngredientId
+	}
+	if waste.Quantity <= 0 {
+		return models.InventoryTransactions{}, utils.ErrInvalidQuantity
+	}
+	waste.Reason = utils.TEXT(strings.ToUpper(string(waste.Reason)))
+	switch waste.Reason {
+	case "WASTE", "SPOILAGE", "THEFT":
+	default:
+		return models.InventoryTransactions{}, utils.ErrInvalidWasteReason
+	}
+	before, err := is.inventoryRepo.GetByID(ctx, ingredientId)
+	if err != nil {
+		return models.InventoryTransactions{}, err
+	}
+	var transaction models.InventoryTransactions
+	err = is.adjustStock(ctx, waste.Reason, []utils.TEXT{utils.TEXT(ingredientId)}, func(ctx context.Context) error {
+		var err error
+		transaction, err = is.inventoryRepo.RecordWaste(ctx, ingredientId, waste)
+		if err != nil {
+			return err
+		}
+		_, err = is.recordChange(ctx, utils.TEXT(ingredientId), before)
+		return err
+	})
+	if err != nil {
+		return models.InventoryTransactions{}, err
+	}
+	return transaction, nil
+}
+
+func (is *InventoryService) CreateBatch(ctx context.Context, batch *models.InventoryBatch) (*models.InventoryBatch, error) {
+	if batch.IngredientId == "" {
+		return nil, utils.ErrInvalidIngredientId
+	}
+	if batch.QuantityReceived <= 0 {
+		return nil, utils.ErrInvalidQuantity
+	}
+	before, err := is.inventoryRepo.GetByID(ctx, string(batch.IngredientId))
+	if err != nil {
+		return nil, err
+	}
+	var created *models.InventoryBatch
+	err = is.adjustStock(ctx, "ADD", []utils.TEXT{batch.IngredientId}, func(ctx context.Context) error {
+		var err error
+		created, err = is.inventoryRepo.CreateBatch(ctx, batch)
+		if err != nil {
+			return err
+		}
+		_, err = is.recordChange(ctx, created.IngredientId, before)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (is *InventoryService) GetBatches(ctx context.Context, ingredientId string) ([]models.InventoryBatch, error) {
+	if ingredientId == "" {
+		return nil, utils.ErrInvalidIngredientId
+	}
+	batches, err := is.inventoryRepo.GetBatches(ctx, ingredientId)
+	if err != nil {
+		return nil, err
+	}
+	if batches == nil {
+		batches = []models.InventoryBatch{}
+	}
+	return batches, nil
+}
+
+// GetExpiring accepts Go durations ("48h", "90m") as well as whole days ("2d").
+func (is *InventoryService) GetExpiring(ctx context.Context, within string) ([]models.InventoryBatch, error) {
+	window := defaultExpiringWithin
+	if within != "" {
+		var err error
+		if days, found := strings.CutSuffix(within, "d"); found {
+			var n int
+			n, err = strconv.Atoi(days)
+			window = time.Duration(n) * 24 * time.Hour
+		} else {
+			window, err = time.ParseDuration(within)
+		}
+		if err != nil || window <= 0 {
+			return nil, utils.ErrInvalidWithin
+		}
+	}
+
+	batches, err := is.inventoryRepo.GetExpiring(ctx, time.Now().Add(window))
+	if err != nil {
+		return nil, err
+	}
+	if batches == nil {
+		batches = []models.InventoryBatch{}
+	}
+	return batches, nil
+}
+
+func (is *InventoryService) WriteOffExpired(ctx context.Context) ([]models.InventoryBatch, error) {
+	var batches []models.InventoryBatch
+	err := is.events.WithinTx(ctx, func(ctx context.Context) error {
+		expired, err := is.inventoryRepo.GetExpiring(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		var ingredientIds []utils.TEXT
+		for _, batch := range expired {
+			ingredientIds = append(ingredientIds, batch.IngredientId)
+		}
+		return is.adjustStock(ctx, "SPOILAGE", ingredientIds, func(ctx context.Context) error {
+			batches, err = is.inventoryRepo.WriteOffExpired(ctx)
+			if err != nil {
+				return err
+			}
+			for _, batch := range batches {
+				if err := recordAudit(ctx, is.auditRepo, "inventory", batch.IngredientId, nil, batch); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if batches == nil {
+		batches = []models.InventoryBatch{}
+	}
+	return batches, nil
+}
+
+// adjustStock runs change in a transaction and publishes StockAdjusted, with
+// reason, for each of the ingredients whose stock at the selected location it
+// changed.
+func (is *InventoryService) adjustStock(ctx context.Context, reason utils.TEXT, ingredientIds []utils.TEXT, change func(ctx context.Context) error) error {
+	return adjustStock(ctx, is.events, is.inventoryRepo, utils.LocationOrDefault(ctx), reason, ingredientIds, change)
+}
+
+// adjustStock runs change in a transaction and publishes StockAdjusted, with
+// reason, for each of the ingredients whose stock at locationId it changed.
+func adjustStock(ctx context.Context, events EventBusIfc, inventoryRepo repo.InventoryRepoIfc, locationId string, reason utils.TEXT, ingredientIds []utils.TEXT, change func(ctx context.Context) error) error {
+	return events.WithinTx(ctx, func(ctx context.Context) error {
+		before := map[utils.TEXT]utils.DEC{}
+		var adjusted []utils.TEXT
+		for _, ingredientId := range ingredientIds {
+			if _, ok := before[ingredientId]; ok {
+				continue
+			}
+			level, err := inventoryRepo.GetStockLevel(ctx, locationId, string(ingredientId))
+			if err != nil {
+				return err
+			}
+			before[ingredientId] = level.Quantity
+			adjusted = append(adjusted, ingredientId)
+		}
+
+		if err := change(ctx); err != nil {
+			return err
+		}
+
+		var changes []Event
+		for _, ingredientId := range adjusted {
+			level, err := inventoryRepo.GetStockLevel(ctx, locationId, string(ingredientId))
+			if err != nil {
+				return err
+			}
+			level.Change = level.Quantity - before[ingredientId]
+			if level.Change != 0 {
+				changes = append(changes, StockAdjusted{StockChange: level, Reason: reason})
+			}
+		}
+		return events.Publish(ctx, changes...)
+	})
+}
+
+// recordChange writes an ingredient, as a change within the transaction of
+// ctx has left it, to the audit log and returns it.
+func (is *InventoryService) recordChange(ctx context.Context, ingredientId utils.TEXT, before any) (models.Inventory, error) {
+	after, err := is.inventoryRepo.GetByID(ctx, string(ingredientId))
+	if err != nil {
+		return models.Inventory{}, err
+	}
+	return after, recordAudit(ctx, is.auditRepo, "inventory", ingredientId, before, after)
+}
+
 func (is *InventoryService) GetLeftOvers(ctx context.Context, page int, pageSize int) (models.Page, error) {
 	return is.inventoryRepo.GetLeftOvers(ctx, page, pageSize)
 }
+
+// GetForecast projects stock-outs from the average usage of each weekday over
+// the last historyDays days and suggests how much to reorder so that stock
+// stays above the reorder level until a delivery placed today arrives.
+func (is *InventoryService) GetForecast(ctx context.Context, historyDays int, leadTimeDays int) (models.InventoryForecast, error) {
+	if historyDays <= 0 {
+		historyDays = defaultForecastHistoryDays
+	}
+	if historyDays > maxForecastHistoryDays {
+		historyDays = maxForecastHistoryDays
+	}
+	if leadTimeDays < 0 {
+		leadTimeDays = defaultForecastLeadTime
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from := today.AddDate(0, 0, -historyDays)
+	usage, err := is.inventoryRepo.GetUsageByWeekday(ctx, from, today)
+	if err != nil {
+		return models.InventoryForecast{}, err
+	}
+
+	// Number of times each ISO weekday occurs in the history window.
+	var weekdayCounts [7]float64
+	for day := from; day.Before(today); day = day.AddDate(0, 0, 1) {
+		weekdayCounts[isoWeekday(day)-1]++
+	}
+
+	forecast := models.InventoryForecast{
+		GeneratedAt:  time.Now().UTC().Format(time.RFC3339),
+		HistoryDays:  historyDays,
+		LeadTimeDays: leadTimeDays,
+		Items:        []models.IngredientForecast{},
+	}
+	for _, item := range usage {
+		result := models.IngredientForecast{
+			IngredientId:   item.IngredientId,
+			IngredientName: item.IngredientName,
+			Unit:           item.Unit,
+			Quantity:       item.Quantity,
+			ReorderLevel:   item.ReorderLevel,
+		}
+
+		var total float64
+		for i, sum := range item.WeekdayTotals {
+			total += sum
+			if weekdayCounts[i] > 0 {
+				result.WeekdayUsage[i] = round2(sum / weekdayCounts[i])
+			}
+		}
+		result.AvgDailyUsage = round2(total / float64(historyDays))
+
+		if total > 0 {
+			remaining := item.Quantity
+			stockoutDay := -1
+			if remaining <= 0 {
+				stockoutDay = 0
+			}
+			var leadTimeDemand float64
+			for day := 1; day <= maxForecastHorizonDays; day++ {
+				if stockoutDay >= 0 && day > leadTimeDays {
+					break
+				}
+				demand := result.WeekdayUsage[isoWeekday(today.AddDate(0, 0, day))-1]
+				if day <= leadTimeDays {
+					leadTimeDemand += demand
+				}
+				if stockoutDay < 0 {
+					remaining -= demand
+					if remaining <= 0 {
+						stockoutDay = day
+					}
+				}
+			}
+
+			if stockoutDay >= 0 {
+				daysOfStock := float64(stockoutDay)
+				result.DaysOfStock = &daysOfStock
+				stockout := today.AddDate(0, 0, stockoutDay)
+				result.StockoutDate = stockout.Format("2006-01-02")
+				result.ReorderBy = stockout.AddDate(0, 0, -leadTimeDays).Format("2006-01-02")
+			}
+			result.SuggestedReorder = round2(math.Max(0, leadTimeDemand+item.ReorderLevel-item.Quantity))
+		}
+
+		forecast.Items = append(forecast.Items, result)
+	}
+
+	return forecast, nil
+}
+
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
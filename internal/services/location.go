@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"frappuccino/internal/repo"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"log"
+	"strings"
+	"time"
+)
+
+type LocationServiceIfc interface {
+	Create(ctx context.Context, location *models.Location) (*models.Location, error)
+	GetAll(ctx context.Context) ([]models.Location, error)
+	GetByID(ctx context.Context, locationId string) (models.Location, error)
+	UpdateByID(ctx context.Context, location *models.Location) error
+	DeleteByID(ctx context.Context, locationId string) error
+	GetMenuPrices(ctx context.Context, locationId string) ([]models.LocationMenuPrice, error)
+	SetMenuPrice(ctx context.Context, price *models.LocationMenuPrice) error
+	DeleteMenuPrice(ctx context.Context, locationId string, menuItemId string) error
+}
+
+type LocationService struct {
+	locationRepo repo.LocationRepoIfc
+}
+
+func NewLocationService(locationRepo repo.LocationRepoIfc) *LocationService {
+	return &LocationService{locationRepo: locationRepo}
+}
+
+func (ls *LocationService) Create(ctx context.Context, location *models.Location) (*models.Location, error) {
+	if err := validateLocation(location); err != nil {
+		return nil, err
+	}
+	log.Println("Creating new location:", location.LocationName)
+	created, err := ls.locationRepo.Create(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	log.Println("Location created successfully:", created.LocationId)
+	return created, nil
+}
+
+func (ls *LocationService) GetAll(ctx context.Context) ([]models.Location, error) {
+	return ls.locationRepo.GetAll(ctx)
+}
+
+func (ls *LocationService) GetByID(ctx context.Context, locationId string) (models.Location, error) {
+	return ls.locationRepo.GetByID(ctx, locationId)
+}
+
+func (ls *LocationService) UpdateByID(ctx context.Context, location *models.Location) error {
+	if err := validateLocation(location); err != nil {
+		return err
+	}
+	log.Printf("Updating location [%s]", location.LocationId)
+	return ls.locationRepo.UpdateByID(ctx, location)
+}
+
+func (ls *LocationService) DeleteByID(ctx context.Context, locationId string) error {
+	if locationId == utils.DefaultLocationId {
+		return utils.ErrLocationInUse
+	}
+	log.Printf("Deleting location [%s]", locationId)
+	return ls.locationRepo.DeleteByID(ctx, locationId)
+}
+
+func (ls *LocationService) GetMenuPrices(ctx context.Context, locationId string) ([]models.LocationMenuPrice, error) {
+	if _, err := ls.locationRepo.GetByID(ctx, locationId); err != nil {
+		return nil, err
+	}
+	return ls.locationRepo.GetMenuPrices(ctx, locationId)
+}
+
+func (ls *LocationService) SetMenuPrice(ctx context.Context, price *models.LocationMenuPrice) error {
+	if price.Price != nil && *price.Price < 0 {
+		return utils.ErrInvalidPrice
+	}
+	log.Printf("Setting menu item [%s] at location [%s]", price.MenuItemId, price.LocationId)
+	return ls.locationRepo.SetMenuPrice(ctx, price)
+}
+
+func (ls *LocationService) DeleteMenuPrice(ctx context.Context, locationId string, menuItemId string) error {
+	log.Printf("Resetting menu item [%s] at location [%s] to the base menu", menuItemId, locationId)
+	return ls.locationRepo.DeleteMenuPrice(ctx, locationId, menuItemId)
+}
+
+// validateLocation trims the name and defaults the timezone to UTC.
+func validateLocation(location *models.Location) error {
+	location.LocationName = utils.TEXT(strings.TrimSpace(string(location.LocationName)))
+	if location.LocationName == "" {
+		return utils.ErrInvalidLocationName
+	}
+	if location.Timezone == "" {
+		location.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(string(location.Timezone)); err != nil {
+		return utils.ErrInvalidTimezone
+	}
+	return nil
+}
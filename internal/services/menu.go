@@ -4,34 +4,53 @@ import (
 	"context"
 	"frappuccino/internal/repo"
 	"frappuccino/models"
+	"frappuccino/utils"
 	"log"
 )
 
+const (
+	defaultSuggestLimit = 5
+	maxSuggestLimit     = 20
+)
+
 type MenuServiceIfc interface {
 	Create(ctx context.Context, item *models.MenuItems) (*models.MenuItems, error)
 	GetAll(ctx context.Context) ([]models.MenuItems, error)
 	GetByID(ctx context.Context, MenuItemId string) (models.MenuItems, error)
 	UpdateByID(ctx context.Context, item *models.MenuItems) error
+	Patch(ctx context.Context, MenuItemId string, patch []byte) (models.MenuItems, error)
 	DeleteByID(ctx context.Context, MenuItemId string) error
+	Restore(ctx context.Context, MenuItemId string) error
 	GetMenuItemPriceByName(ctx context.Context, name string) (float64, error)
+	Suggest(ctx context.Context, q string, limit int) ([]models.MenuSuggestion, error)
 }
 
 type MenuService struct {
-	menuRepo repo.MenuRepoIfc
+	menuRepo  repo.MenuRepoIfc
+	auditRepo repo.AuditRepoIfc
+	events    EventBusIfc
 }
 
-func NewMenuService(menuRepo repo.MenuRepoIfc) *MenuService {
-	return &MenuService{menuRepo: menuRepo}
+func NewMenuService(menuRepo repo.MenuRepoIfc, auditRepo repo.AuditRepoIfc, events EventBusIfc) *MenuService {
+	return &MenuService{menuRepo: menuRepo, auditRepo: auditRepo, events: events}
 }
 
 func (ms *MenuService) Create(ctx context.Context, item *models.MenuItems) (*models.MenuItems, error) {
 	log.Println("Creating new menu item:", item.ItemName)
-	created, err := ms.menuRepo.Create(ctx, item)
+	var created models.MenuItems
+	err := ms.events.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = ms.menuRepo.Create(ctx, *item)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, ms.auditRepo, "menu", created.MenuItemId, nil, created)
+	})
 	if err != nil {
 		return nil, err
 	}
 	log.Println("Menu item created successfully:", created.MenuItemId)
-	return created, nil
+	return &created, nil
 }
 
 func (ms *MenuService) GetAll(ctx context.Context) ([]models.MenuItems, error) {
@@ -56,7 +75,20 @@ func (ms *MenuService) GetByID(ctx context.Context, MenuItemId string) (models.M
 
 func (ms *MenuService) UpdateByID(ctx context.Context, item *models.MenuItems) error {
 	log.Printf("Updating menu item [%s]", item.MenuItemId)
-	err := ms.menuRepo.UpdateByID(ctx, item)
+	before, err := ms.menuRepo.GetByID(ctx, string(item.MenuItemId))
+	if err != nil {
+		return err
+	}
+	err = ms.withPriceChange(ctx, string(item.MenuItemId), item.ItemName, &item.Price, func(ctx context.Context) error {
+		if err := ms.menuRepo.UpdateByID(ctx, *item); err != nil {
+			return err
+		}
+		after, err := ms.menuRepo.GetByID(ctx, string(item.MenuItemId))
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, ms.auditRepo, "menu", item.MenuItemId, before, after)
+	})
 	if err != nil {
 		return err
 	}
@@ -64,9 +96,114 @@ func (ms *MenuService) UpdateByID(ctx context.Context, item *models.MenuItems) e
 	return nil
 }
 
+// Patch applies a JSON merge patch to a menu item. price is the base price;
+// location overrides are managed under /locations. Ingredients are replaced
+// with PUT only.
+func (ms *MenuService) Patch(ctx context.Context, MenuItemId string, patch []byte) (models.MenuItems, error) {
+	fields, err := utils.PatchFields(patch)
+	if err != nil {
+		return models.MenuItems{}, err
+	}
+	log.Printf("Patching menu item [%s]", MenuItemId)
+	before, err := ms.menuRepo.GetByID(ctx, MenuItemId)
+	if err != nil {
+		return models.MenuItems{}, err
+	}
+	var patched models.MenuItems
+	if err := applyMergePatch(before, patch, &patched); err != nil {
+		return models.MenuItems{}, err
+	}
+
+	set := map[string]any{}
+	for _, field := range fields {
+		switch field {
+		case "item_name":
+			if patched.ItemName == "" {
+				return models.MenuItems{}, emptyField(field)
+			}
+			set["item_name"] = patched.ItemName
+		case "item_description":
+			set["item_description"] = patched.ItemDescription
+		case "price":
+			if patched.Price < 0 {
+				return models.MenuItems{}, utils.ErrInvalidPrice
+			}
+			set["price"] = patched.Price
+		case "categories":
+			categories := []string(patched.Categories)
+			if categories == nil {
+				categories = []string{}
+			}
+			set["categories"] = categories
+		case "station_id":
+			set["station_id"] = patched.StationId
+		default:
+			return models.MenuItems{}, readOnlyField(field)
+		}
+	}
+
+	var newPrice *utils.DEC
+	if _, ok := set["price"]; ok {
+		newPrice = &patched.Price
+	}
+	var after models.MenuItems
+	err = ms.withPriceChange(ctx, MenuItemId, patched.ItemName, newPrice, func(ctx context.Context) error {
+		if err := ms.menuRepo.Patch(ctx, MenuItemId, set); err != nil {
+			return err
+		}
+		var err error
+		after, err = ms.menuRepo.GetByID(ctx, MenuItemId)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, ms.auditRepo, "menu", utils.TEXT(MenuItemId), before, after)
+	})
+	if err != nil {
+		return models.MenuItems{}, err
+	}
+	log.Printf("Menu item [%s] patched successfully", MenuItemId)
+	return after, nil
+}
+
+// withPriceChange runs update in a transaction and publishes PriceChanged
+// when it sets the base price of the item to a different newPrice. A nil
+// newPrice leaves the price alone.
+func (ms *MenuService) withPriceChange(ctx context.Context, menuItemId string, itemName utils.TEXT, newPrice *utils.DEC, update func(ctx context.Context) error) error {
+	return ms.events.WithinTx(ctx, func(ctx context.Context) error {
+		if newPrice == nil {
+			return update(ctx)
+		}
+		oldPrice, err := ms.menuRepo.GetBasePrice(ctx, menuItemId)
+		if err != nil {
+			return err
+		}
+		if err := update(ctx); err != nil {
+			return err
+		}
+		if oldPrice == *newPrice {
+			return nil
+		}
+		return ms.events.Publish(ctx, PriceChanged{
+			MenuItemId: utils.TEXT(menuItemId),
+			ItemName:   itemName,
+			OldPrice:   oldPrice,
+			NewPrice:   *newPrice,
+		})
+	})
+}
+
 func (ms *MenuService) DeleteByID(ctx context.Context, MenuItemId string) error {
 	log.Printf("Deleting menu item [%s]", MenuItemId)
-	err := ms.menuRepo.DeleteByID(ctx, MenuItemId)
+	err := ms.events.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := ms.menuRepo.GetByID(ctx, MenuItemId)
+		if err != nil {
+			return err
+		}
+		if err := ms.menuRepo.DeleteByID(ctx, MenuItemId); err != nil {
+			return err
+		}
+		return recordAudit(ctx, ms.auditRepo, "menu", utils.TEXT(MenuItemId), before, nil)
+	})
 	if err != nil {
 		return err
 	}
@@ -74,6 +211,42 @@ func (ms *MenuService) DeleteByID(ctx context.Context, MenuItemId string) error
 	return nil
 }
 
+func (ms *MenuService) Restore(ctx context.Context, MenuItemId string) error {
+	log.Printf("Restoring menu item [%s]", MenuItemId)
+	err := ms.events.WithinTx(ctx, func(ctx context.Context) error {
+		if err := ms.menuRepo.Restore(ctx, MenuItemId); err != nil {
+			return err
+		}
+		after, err := ms.menuRepo.GetByID(ctx, MenuItemId)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, ms.auditRepo, "menu", utils.TEXT(MenuItemId), nil, after)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Menu item [%s] restored successfully", MenuItemId)
+	return nil
+}
+
 func (ms *MenuService) GetMenuItemPriceByName(ctx context.Context, name string) (float64, error) {
 	return ms.menuRepo.GetMenuItemPriceByName(ctx, name)
 }
+
+func (ms *MenuService) Suggest(ctx context.Context, q string, limit int) ([]models.MenuSuggestion, error) {
+	if limit <= 0 {
+		limit = defaultSuggestLimit
+	}
+	if limit > maxSuggestLimit {
+		limit = maxSuggestLimit
+	}
+	suggestions, err := ms.menuRepo.Suggest(ctx, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	if suggestions == nil {
+		suggestions = []models.MenuSuggestion{}
+	}
+	return suggestions, nil
+}
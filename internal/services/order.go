@@ -2,31 +2,59 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"frappuccino/internal/repo"
 	"frappuccino/models"
+	"frappuccino/utils"
 	"log"
+	"strings"
+	"time"
 )
 
+// orderStatuses lists the values of the all_order_status enum.
+var orderStatuses = map[string]bool{
+	"PENDING":   true,
+	"READY":     true,
+	"COMPLETED": true,
+	"CANCELLED": true,
+	"REFUNDED":  true,
+}
+
 type OrderServiceIfc interface {
 	Create(ctx context.Context, order *models.Orders) (*models.Orders, error)
+	CreateBatch(ctx context.Context, orders []models.Orders) ([]models.Orders, error)
 	GetAll(ctx context.Context) ([]models.Orders, error)
 	GetByID(ctx context.Context, orderId string) (models.Orders, error)
 	UpdateByID(ctx context.Context, order *models.Orders) error
+	Patch(ctx context.Context, orderId string, patch []byte) (models.Orders, error)
 	DeleteByID(ctx context.Context, orderId string) error
+	Close(ctx context.Context, orderId string) (models.Orders, error)
+	NumberOfOrderedItems(ctx context.Context, startDate string, endDate string) (map[string]utils.DEC, error)
+	Restore(ctx context.Context, orderId string) error
 }
 
 type OrderService struct {
-	OrderRepo repo.OrderRepoIfc
+	OrderRepo   repo.OrderRepoIfc
+	stationRepo repo.StationRepoIfc
+	staffRepo   repo.StaffRepoIfc
+	auditRepo   repo.AuditRepoIfc
+	events      EventBusIfc
 }
 
-func NewOrderService(OrderRepo repo.OrderRepoIfc) *OrderService {
-	return &OrderService{OrderRepo: OrderRepo}
+func NewOrderService(OrderRepo repo.OrderRepoIfc, stationRepo repo.StationRepoIfc, staffRepo repo.StaffRepoIfc, auditRepo repo.AuditRepoIfc, events EventBusIfc) *OrderService {
+	return &OrderService{OrderRepo: OrderRepo, stationRepo: stationRepo, staffRepo: staffRepo, auditRepo: auditRepo, events: events}
 }
 
 // Create создает новый заказ
 func (os *OrderService) Create(ctx context.Context, order *models.Orders) (*models.Orders, error) {
 	log.Println("Creating new order for customer:", order.CustomerId)
-	createdOrder, err := os.OrderRepo.Create(ctx, order)
+	var createdOrder *models.Orders
+	err := os.events.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		createdOrder, err = os.create(ctx, order)
+		return err
+	})
 	if err != nil {
 		log.Println("Error creating order:", err)
 		return nil, err
@@ -35,6 +63,117 @@ func (os *OrderService) Create(ctx context.Context, order *models.Orders) (*mode
 	return createdOrder, nil
 }
 
+// CreateBatch creates all the orders in one transaction: when one of them
+// fails none is created.
+func (os *OrderService) CreateBatch(ctx context.Context, orders []models.Orders) ([]models.Orders, error) {
+	if len(orders) == 0 {
+		return nil, utils.ErrEmptyBatch
+	}
+	log.Printf("Creating a batch of %d orders", len(orders))
+	var created []models.Orders
+	err := os.events.WithinTx(ctx, func(ctx context.Context) error {
+		created = make([]models.Orders, 0, len(orders))
+		for i := range orders {
+			order, err := os.create(ctx, &orders[i])
+			if err != nil {
+				return fmt.Errorf("order %d: %w", i+1, err)
+			}
+			created = append(created, *order)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("Error creating batch:", err)
+		return nil, err
+	}
+	log.Printf("Batch of %d orders created successfully", len(created))
+	return created, nil
+}
+
+// create inserts an order inside the transaction of ctx. Orders are always
+// inserted as PENDING; an order created as COMPLETED is completed right
+// after, so that completing it draws its ingredients from the stock.
+func (os *OrderService) create(ctx context.Context, order *models.Orders) (*models.Orders, error) {
+	if order.OrderStatus == "" {
+		order.OrderStatus = "PENDING"
+	}
+	status := order.OrderStatus
+	if status != "PENDING" && status != "COMPLETED" {
+		return nil, utils.ErrNewOrderStatus
+	}
+	order.PaymentMethod = utils.TEXT(strings.ToUpper(string(order.PaymentMethod)))
+	if !paymentMethods[string(order.PaymentMethod)] {
+		return nil, utils.ErrInvalidPaymentMethod
+	}
+	for _, item := range order.OrderItems {
+		if item.Quantity <= 0 {
+			return nil, utils.ErrInvalidQuantity
+		}
+	}
+	if err := validatePayments(order.Payments); err != nil {
+		return nil, err
+	}
+	// Orders are credited to the staff member who took them; integrations
+	// using an API key may name an active staff member themselves.
+	if principal, ok := utils.PrincipalFrom(ctx); ok && principal.StaffId != "" {
+		staffId := utils.TEXT(principal.StaffId)
+		order.StaffId = &staffId
+	} else if order.StaffId != nil {
+		if err := os.checkStaff(ctx, string(*order.StaffId)); err != nil {
+			return nil, err
+		}
+	}
+
+	order.OrderStatus = "PENDING"
+	createdOrder, err := os.OrderRepo.Create(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	if status == "PENDING" {
+		// Orders still to be prepared go to the station displays
+		if err := os.stationRepo.CreateTickets(ctx, string(createdOrder.OrderId)); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.events.Publish(ctx, OrderCreated{Order: *createdOrder}); err != nil {
+		return nil, err
+	}
+	if status == "COMPLETED" {
+		err = os.OrderRepo.Patch(ctx, string(createdOrder.OrderId), map[string]any{"order_status": string(status)}, nil)
+		if err != nil {
+			return nil, err
+		}
+		completed, err := os.publishStatusChange(ctx, *createdOrder)
+		if err != nil {
+			return nil, err
+		}
+		createdOrder = &completed
+	}
+	if err := recordAudit(ctx, os.auditRepo, "order", createdOrder.OrderId, nil, createdOrder); err != nil {
+		return nil, err
+	}
+	return createdOrder, nil
+}
+
+// checkStaff fails with ErrInvalidStaffId unless staffId names an active
+// staff member.
+func (os *OrderService) checkStaff(ctx context.Context, staffId string) error {
+	if !uuidPattern.MatchString(staffId) {
+		return utils.ErrInvalidStaffId
+	}
+	staff, err := os.staffRepo.GetByID(ctx, staffId)
+	if err != nil {
+		if errors.Is(err, utils.ErrIdNotFound) {
+			return utils.ErrInvalidStaffId
+		}
+		return err
+	}
+	if !staff.IsActive {
+		return utils.ErrInvalidStaffId
+	}
+	return nil
+}
+
 // GetAll возвращает все заказы
 func (os *OrderService) GetAll(ctx context.Context) ([]models.Orders, error) {
 	log.Println("Fetching all orders")
@@ -62,7 +201,25 @@ func (os *OrderService) GetByID(ctx context.Context, orderId string) (models.Ord
 // UpdateByID обновляет заказ по ID
 func (os *OrderService) UpdateByID(ctx context.Context, order *models.Orders) error {
 	log.Printf("Updating order [%s]", order.OrderId)
-	err := os.OrderRepo.UpdateItemByID(ctx, order)
+	before, err := os.OrderRepo.GetOrderByID(ctx, string(order.OrderId))
+	if err != nil {
+		log.Println("Error fetching order:", err)
+		return err
+	}
+	if err := checkStatusChange(before.OrderStatus, order.OrderStatus); err != nil {
+		return err
+	}
+	var after models.Orders
+	err = os.events.WithinTx(ctx, func(ctx context.Context) error {
+		if err := os.OrderRepo.UpdateItemByID(ctx, order); err != nil {
+			return err
+		}
+		after, err = os.publishStatusChange(ctx, before)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, os.auditRepo, "order", order.OrderId, before, after)
+	})
 	if err != nil {
 		log.Println("Error updating order:", err)
 		return err
@@ -71,10 +228,164 @@ func (os *OrderService) UpdateByID(ctx context.Context, order *models.Orders) er
 	return nil
 }
 
+// Patch applies a JSON merge patch to an order. OrderItems is an array and so
+// is replaced as a whole: it must list the same lines as the order, whose
+// quantity and customizations may change while the order is PENDING. The
+// lines are priced again from the menu; prices sent by the client are ignored.
+func (os *OrderService) Patch(ctx context.Context, orderId string, patch []byte) (models.Orders, error) {
+	fields, err := utils.PatchFields(patch)
+	if err != nil {
+		return models.Orders{}, err
+	}
+	log.Printf("Patching order [%s]", orderId)
+	before, err := os.OrderRepo.GetOrderByID(ctx, orderId)
+	if err != nil {
+		log.Println("Error fetching order:", err)
+		return models.Orders{}, err
+	}
+	var patched models.Orders
+	if err := applyMergePatch(before, patch, &patched); err != nil {
+		return models.Orders{}, err
+	}
+
+	set := map[string]any{}
+	var items []models.OrderItems
+	for _, field := range fields {
+		switch field {
+		case "special_instructions":
+			set["special_instructions"] = patched.SpecialInstructions
+		case "order_status":
+			if err := checkStatusChange(before.OrderStatus, patched.OrderStatus); err != nil {
+				return models.Orders{}, err
+			}
+			set["order_status"] = string(patched.OrderStatus)
+		case "payment_method":
+			if !paymentMethods[string(patched.PaymentMethod)] {
+				return models.Orders{}, utils.ErrInvalidPaymentMethod
+			}
+			set["order_payment_method"] = string(patched.PaymentMethod)
+		case "OrderItems":
+			if before.OrderStatus != "PENDING" {
+				return models.Orders{}, utils.ErrOrderItemsLocked
+			}
+			if err := checkPatchedItems(before.OrderItems, patched.OrderItems); err != nil {
+				return models.Orders{}, err
+			}
+			items = patched.OrderItems
+		default:
+			return models.Orders{}, readOnlyField(field)
+		}
+	}
+
+	var after models.Orders
+	err = os.events.WithinTx(ctx, func(ctx context.Context) error {
+		if err := os.OrderRepo.Patch(ctx, orderId, set, items); err != nil {
+			return err
+		}
+		after, err = os.publishStatusChange(ctx, before)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, os.auditRepo, "order", utils.TEXT(orderId), before, after)
+	})
+	if err != nil {
+		log.Println("Error patching order:", err)
+		return models.Orders{}, err
+	}
+	log.Printf("Order [%s] patched successfully", orderId)
+	return after, nil
+}
+
+// publishStatusChange reads the order back after a change and publishes
+// OrderStatusChanged when its status differs from before. Completing an order
+// draws its ingredients from the stock, which is published as StockAdjusted.
+func (os *OrderService) publishStatusChange(ctx context.Context, before models.Orders) (models.Orders, error) {
+	after, err := os.OrderRepo.GetOrderByID(ctx, string(before.OrderId))
+	if err != nil {
+		return models.Orders{}, err
+	}
+	if after.OrderStatus == before.OrderStatus {
+		return after, nil
+	}
+
+	events := []Event{OrderStatusChanged{Order: after, PreviousStatus: before.OrderStatus}}
+	if after.OrderStatus == "COMPLETED" {
+		usage, err := os.OrderRepo.GetIngredientUsage(ctx, string(after.OrderId))
+		if err != nil {
+			return models.Orders{}, err
+		}
+		for _, change := range usage {
+			events = append(events, StockAdjusted{StockChange: change, Reason: "REMOVE"})
+		}
+	}
+	return after, os.events.Publish(ctx, events...)
+}
+
+// finalStatuses are the statuses an order keeps once it has them: a completed
+// order has drawn its ingredients from the stock and counts in the sales,
+// leaving it would draw them again or take it out of the reports. Only a
+// refund moves a COMPLETED order on, to REFUNDED.
+var finalStatuses = map[utils.TEXT]bool{
+	"COMPLETED": true,
+	"CANCELLED": true,
+	"REFUNDED":  true,
+}
+
+// checkStatusChange allows an order that is not final yet to be set to any
+// status but READY, which the stations give it when its last ticket is
+// bumped, and REFUNDED, which refunds give it.
+func checkStatusChange(from utils.TEXT, to utils.TEXT) error {
+	if !orderStatuses[string(to)] {
+		return utils.ErrInvalidStatus
+	}
+	if to == from {
+		return nil
+	}
+	if finalStatuses[from] {
+		return utils.ErrOrderFinal
+	}
+	if to == "READY" || to == "REFUNDED" {
+		return utils.ErrStatusNotSettable
+	}
+	return nil
+}
+
+// checkPatchedItems makes sure a patched OrderItems array holds exactly the
+// lines of the order, each changed only where PATCH allows it.
+func checkPatchedItems(current []models.OrderItems, patched []models.OrderItems) error {
+	if len(patched) != len(current) {
+		return utils.ErrOrderItemsChanged
+	}
+	lines := make(map[utils.TEXT]models.OrderItems, len(current))
+	for _, item := range current {
+		lines[item.OrderItemId] = item
+	}
+	for _, item := range patched {
+		line, ok := lines[item.OrderItemId]
+		if !ok || line.MenuItemId != item.MenuItemId || line.ItemName != item.ItemName {
+			return utils.ErrOrderItemsChanged
+		}
+		delete(lines, item.OrderItemId)
+		if item.Quantity < 0 {
+			return utils.ErrInvalidQuantity
+		}
+	}
+	return nil
+}
+
 // DeleteByID удаляет заказ по ID
 func (os *OrderService) DeleteByID(ctx context.Context, orderId string) error {
 	log.Printf("Deleting order [%s]", orderId)
-	err := os.OrderRepo.DeleteItemByID(ctx, orderId)
+	err := os.events.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := os.OrderRepo.GetOrderByID(ctx, orderId)
+		if err != nil {
+			return err
+		}
+		if err := os.OrderRepo.DeleteItemByID(ctx, orderId); err != nil {
+			return err
+		}
+		return recordAudit(ctx, os.auditRepo, "order", utils.TEXT(orderId), before, nil)
+	})
 	if err != nil {
 		log.Println("Error deleting order:", err)
 		return err
@@ -83,24 +394,98 @@ func (os *OrderService) DeleteByID(ctx context.Context, orderId string) error {
 	return nil
 }
 
-func (orderService *OrderService) CloseOrderById(id string) error {
-	return orderService.OrderRepo.CloseOrderById(id)
+// Close completes a PENDING or READY order, which must be paid in full.
+// Completing it draws its ingredients from the stock.
+func (os *OrderService) Close(ctx context.Context, orderId string) (models.Orders, error) {
+	log.Printf("Closing order [%s]", orderId)
+	before, err := os.OrderRepo.GetOrderByID(ctx, orderId)
+	if err != nil {
+		log.Println("Error fetching order:", err)
+		return models.Orders{}, err
+	}
+	if before.OrderStatus != "PENDING" && before.OrderStatus != "READY" {
+		return models.Orders{}, utils.ErrOrderClosed
+	}
+	var after models.Orders
+	err = os.events.WithinTx(ctx, func(ctx context.Context) error {
+		err := os.OrderRepo.Patch(ctx, orderId, map[string]any{"order_status": "COMPLETED"}, nil)
+		if err != nil {
+			return err
+		}
+		after, err = os.publishStatusChange(ctx, before)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, os.auditRepo, "order", utils.TEXT(orderId), before, after)
+	})
+	if err != nil {
+		log.Println("Error closing order:", err)
+		return models.Orders{}, err
+	}
+	log.Printf("Order [%s] closed", orderId)
+	return after, nil
 }
 
-func (orderService *OrderService) BatchProcess(NewOrders []models.Order) error {
-	for _, val := range NewOrders {
-		err := orderService.OrderRepo(val)
+// Restore возвращает архивированный заказ
+func (os *OrderService) Restore(ctx context.Context, orderId string) error {
+	log.Printf("Restoring order [%s]", orderId)
+	err := os.events.WithinTx(ctx, func(ctx context.Context) error {
+		if err := os.OrderRepo.Restore(ctx, orderId); err != nil {
+			return err
+		}
+		after, err := os.OrderRepo.GetOrderByID(ctx, orderId)
 		if err != nil {
 			return err
 		}
+		return recordAudit(ctx, os.auditRepo, "order", utils.TEXT(orderId), nil, after)
+	})
+	if err != nil {
+		log.Println("Error restoring order:", err)
+		return err
 	}
+	log.Printf("Order [%s] restored successfully", orderId)
 	return nil
 }
 
-func (orderService *OrderService) NumberOfOrderedItems(startDate string, endDate string) ([]models.OrderedItem, error) {
-	result, err := orderService.OrderRepo.NumberOfOrderedItems(startDate, endDate)
+// NumberOfOrderedItems counts the units of every menu item ordered at the
+// selected location from startDate to endDate, both inclusive, given as
+// YYYY-MM-DD or DD.MM.YYYY. A missing date leaves that end of the period open.
+func (os *OrderService) NumberOfOrderedItems(ctx context.Context, startDate string, endDate string) (map[string]utils.DEC, error) {
+	from, err := parseOrderDate(startDate)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseOrderDate(endDate)
+	if err != nil {
+		return nil, err
+	}
+	if to != nil {
+		end := to.AddDate(0, 0, 1)
+		to = &end
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, utils.ErrInvalidDateRange
+	}
+
+	counts, err := os.OrderRepo.NumberOfOrderedItems(ctx, from, to)
 	if err != nil {
 		return nil, err
 	}
+	result := make(map[string]utils.DEC, len(counts))
+	for _, count := range counts {
+		result[count.Name] += count.Count
+	}
 	return result, nil
 }
+
+func parseOrderDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{"2006-01-02", "02.01.2006"} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return &date, nil
+		}
+	}
+	return nil, utils.ErrInvalidDateRange
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"frappuccino/internal/repo"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// orderStreamBuffer is how many events a slow client may fall behind
+	// before it is disconnected; it catches up with Last-Event-ID.
+	orderStreamBuffer = 64
+	maxOrderReplay    = 1000
+	listenRetryDelay  = 5 * time.Second
+)
+
+type OrderStreamServiceIfc interface {
+	Run(ctx context.Context)
+	Subscribe(ctx context.Context, statuses []string, lastEventId int64) (*OrderSubscription, error)
+}
+
+// OrderSubscription is one client of the order stream. Replay holds the
+// events missed since Last-Event-ID; Events delivers the live ones and is
+// closed when the client falls too far behind or ctx is done.
+//
+// When more than maxOrderReplay events were missed Replay is empty and
+// ResetId is set to the newest event: the client has to reload the orders
+// and carry on from there.
+type OrderSubscription struct {
+	Replay  []models.OrderEvent
+	ResetId int64
+	Events  <-chan models.OrderEvent
+}
+
+type orderSubscriber struct {
+	events     chan models.OrderEvent
+	statuses   map[string]bool
+	locationId string
+}
+
+func (s *orderSubscriber) wants(event models.OrderEvent) bool {
+	if s.locationId != "" && string(event.LocationId) != s.locationId {
+		return false
+	}
+	return len(s.statuses) == 0 || s.statuses[string(event.OrderStatus)]
+}
+
+// OrderStreamService fans the order events announced by the database out to
+// the connected stream clients.
+type OrderStreamService struct {
+	orderEventRepo repo.OrderEventRepoIfc
+
+	mu          sync.Mutex
+	subscribers map[*orderSubscriber]struct{}
+	// The ids of the latest events published, oldest first, to drop the
+	// ones the listener reads back twice
+	recent    []int64
+	published map[int64]bool
+	lastId    int64
+}
+
+func NewOrderStreamService(orderEventRepo repo.OrderEventRepoIfc) *OrderStreamService {
+	return &OrderStreamService{
+		orderEventRepo: orderEventRepo,
+		subscribers:    map[*orderSubscriber]struct{}{},
+		published:      map[int64]bool{},
+	}
+}
+
+// Run listens for order events until ctx is done. After the first attempt
+// it listens from the last event published, so that none is lost between
+// attempts.
+func (ss *OrderStreamService) Run(ctx context.Context) {
+	for {
+		events, err := ss.orderEventRepo.Listen(ctx, ss.lastId)
+		if err != nil {
+			log.Println("Error listening for order events:", err)
+		} else {
+			for event := range events {
+				ss.publish(event)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func (ss *OrderStreamService) publish(event models.OrderEvent) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if ss.published[event.EventId] {
+		return
+	}
+	ss.published[event.EventId] = true
+	ss.recent = append(ss.recent, event.EventId)
+	if len(ss.recent) > maxOrderReplay {
+		delete(ss.published, ss.recent[0])
+		ss.recent = ss.recent[1:]
+	}
+	ss.lastId = max(ss.lastId, event.EventId)
+
+	for subscriber := range ss.subscribers {
+		if !subscriber.wants(event) {
+			continue
+		}
+		select {
+		case subscriber.events <- event:
+		default:
+			log.Println("Disconnecting slow order stream client")
+			ss.remove(subscriber)
+		}
+	}
+}
+
+// remove must be called with mu held.
+func (ss *OrderStreamService) remove(subscriber *orderSubscriber) {
+	if _, ok := ss.subscribers[subscriber]; ok {
+		delete(ss.subscribers, subscriber)
+		close(subscriber.events)
+	}
+}
+
+// Subscribe registers a client for the events of the selected location (all
+// locations when none is selected), optionally only those leaving an order
+// in one of statuses. With a lastEventId the events after it are replayed.
+func (ss *OrderStreamService) Subscribe(ctx context.Context, statuses []string, lastEventId int64) (*OrderSubscription, error) {
+	subscriber := &orderSubscriber{
+		events:   make(chan models.OrderEvent, orderStreamBuffer),
+		statuses: map[string]bool{},
+	}
+	for _, status := range statuses {
+		status = strings.ToUpper(strings.TrimSpace(status))
+		if !orderStatuses[status] {
+			return nil, utils.ErrInvalidStatus
+		}
+		subscriber.statuses[status] = true
+	}
+	if lastEventId < 0 {
+		return nil, utils.ErrInvalidEventId
+	}
+	subscriber.locationId, _ = utils.SelectedLocation(ctx)
+
+	// Registered before the replay is read so that no event falls in
+	// between; the client skips live events it already got replayed.
+	ss.mu.Lock()
+	ss.subscribers[subscriber] = struct{}{}
+	ss.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		ss.mu.Lock()
+		ss.remove(subscriber)
+		ss.mu.Unlock()
+	}()
+
+	subscription := &OrderSubscription{Events: subscriber.events}
+	if lastEventId > 0 {
+		replay, err := ss.orderEventRepo.GetAfter(ctx, lastEventId, subscriber.locationId, maxOrderReplay+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(replay) > maxOrderReplay {
+			subscription.ResetId, err = ss.orderEventRepo.GetLastId(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return subscription, nil
+		}
+		for _, event := range replay {
+			if subscriber.wants(event) {
+				subscription.Replay = append(subscription.Replay, event)
+			}
+		}
+	}
+
+	return subscription, nil
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"errors"
+	"frappuccino/utils"
+	"testing"
+)
+
+func TestCheckStatusChange(t *testing.T) {
+	tests := []struct {
+		name string
+		from utils.TEXT
+		to   utils.TEXT
+		want error
+	}{
+		{name: "pending to completed", from: "PENDING", to: "COMPLETED"},
+		{name: "pending to cancelled", from: "PENDING", to: "CANCELLED"},
+		{name: "ready to completed", from: "READY", to: "COMPLETED"},
+		{name: "ready back to pending", from: "READY", to: "PENDING"},
+		{name: "unchanged pending", from: "PENDING", to: "PENDING"},
+		{name: "unchanged ready", from: "READY", to: "READY"},
+		{name: "unchanged completed", from: "COMPLETED", to: "COMPLETED"},
+		{name: "unchanged refunded", from: "REFUNDED", to: "REFUNDED"},
+		{name: "unknown status", from: "PENDING", to: "SERVED", want: utils.ErrInvalidStatus},
+		{name: "pending to ready", from: "PENDING", to: "READY", want: utils.ErrStatusNotSettable},
+		{name: "pending to refunded", from: "PENDING", to: "REFUNDED", want: utils.ErrStatusNotSettable},
+		{name: "completed to pending", from: "COMPLETED", to: "PENDING", want: utils.ErrOrderFinal},
+		{name: "completed to cancelled", from: "COMPLETED", to: "CANCELLED", want: utils.ErrOrderFinal},
+		{name: "completed to refunded", from: "COMPLETED", to: "REFUNDED", want: utils.ErrOrderFinal},
+		{name: "refunded to completed", from: "REFUNDED", to: "COMPLETED", want: utils.ErrOrderFinal},
+		{name: "refunded to pending", from: "REFUNDED", to: "PENDING", want: utils.ErrOrderFinal},
+		{name: "cancelled to pending", from: "CANCELLED", to: "PENDING", want: utils.ErrOrderFinal},
+		{name: "cancelled to completed", from: "CANCELLED", to: "COMPLETED", want: utils.ErrOrderFinal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkStatusChange(tt.from, tt.to); !errors.Is(err, tt.want) {
+				t.Errorf("checkStatusChange(%q, %q) = %v, want %v", tt.from, tt.to, err, tt.want)
+			}
+		})
+	}
+}
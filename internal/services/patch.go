@@ -0,0 +1,32 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"frappuccino/utils"
+)
+
+// applyMergePatch applies a JSON merge patch (RFC 7396) to the JSON form of
+// current and decodes the result into patched.
+func applyMergePatch(current any, patch []byte, patched any) error {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	merged, err := utils.MergePatch(doc, patch)
+	if err != nil {
+		return utils.ErrInvalidMergePatch
+	}
+	if err := json.Unmarshal(merged, patched); err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInvalidPatchValue, err)
+	}
+	return nil
+}
+
+func readOnlyField(field string) error {
+	return fmt.Errorf("%w: %s", utils.ErrPatchField, field)
+}
+
+func emptyField(field string) error {
+	return fmt.Errorf("%w: %s", utils.ErrEmptyField, field)
+}
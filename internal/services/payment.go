@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"frappuccino/internal/repo"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"log"
+	"strings"
+)
+
+// paymentMethods lists the values of the all_order_payment_method enum.
+var paymentMethods = map[string]bool{
+	"CASH": true,
+	"CARD": true,
+}
+
+type PaymentServiceIfc interface {
+	AddPayments(ctx context.Context, orderId string, payments []models.Payment) (models.OrderPayments, error)
+	GetByOrderID(ctx context.Context, orderId string) (models.OrderPayments, error)
+}
+
+type PaymentService struct {
+	paymentRepo repo.PaymentRepoIfc
+	orderRepo   repo.OrderRepoIfc
+	auditRepo   repo.AuditRepoIfc
+	events      EventBusIfc
+}
+
+func NewPaymentService(paymentRepo repo.PaymentRepoIfc, orderRepo repo.OrderRepoIfc, auditRepo repo.AuditRepoIfc, events EventBusIfc) *PaymentService {
+	return &PaymentService{paymentRepo: paymentRepo, orderRepo: orderRepo, auditRepo: auditRepo, events: events}
+}
+
+// AddPayments records one or more payments (a split bill) against an order
+// and returns its payment state.
+func (ps *PaymentService) AddPayments(ctx context.Context, orderId string, payments []models.Payment) (models.OrderPayments, error) {
+	if len(payments) == 0 {
+		return models.OrderPayments{}, utils.ErrEmptyPayment
+	}
+	if err := validatePayments(payments); err != nil {
+		return models.OrderPayments{}, err
+	}
+
+	log.Printf("Recording %d payments for order [%s]", len(payments), orderId)
+	var state models.OrderPayments
+	err := ps.events.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := ps.orderRepo.GetOrderByID(ctx, orderId)
+		if err != nil {
+			return err
+		}
+		if err := ps.paymentRepo.AddPayments(ctx, orderId, payments); err != nil {
+			return err
+		}
+		after, err := ps.orderRepo.GetOrderByID(ctx, orderId)
+		if err != nil {
+			return err
+		}
+		state, err = ps.paymentRepo.GetByOrderID(ctx, orderId)
+		if err != nil {
+			return err
+		}
+		err = ps.events.Publish(ctx, PaymentRecorded{LocationId: after.LocationId, Payments: payments, State: state})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, ps.auditRepo, "order", utils.TEXT(orderId), before, after)
+	})
+	if err != nil {
+		return models.OrderPayments{}, err
+	}
+	return state, nil
+}
+
+func (ps *PaymentService) GetByOrderID(ctx context.Context, orderId string) (models.OrderPayments, error) {
+	return ps.paymentRepo.GetByOrderID(ctx, orderId)
+}
+
+func validatePayments(payments []models.Payment) error {
+	for i := range payments {
+		payments[i].PaymentMethod = utils.TEXT(strings.ToUpper(string(payments[i].PaymentMethod)))
+		if !paymentMethods[string(payments[i].PaymentMethod)] {
+			return utils.ErrInvalidPaymentMethod
+		}
+		if payments[i].Amount <= 0 {
+			return utils.ErrInvalidPaymentAmount
+		}
+		if payments[i].Tip < 0 {
+			return utils.ErrInvalidTip
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"testing"
+)
+
+func TestValidatePayments(t *testing.T) {
+	tests := []struct {
+		name     string
+		payments []models.Payment
+		want     error
+	}{
+		{name: "none", payments: nil},
+		{
+			name:     "split bill",
+			payments: []models.Payment{{PaymentMethod: "CASH", Amount: 5}, {PaymentMethod: "CARD", Amount: 7.5, Tip: 1}},
+		},
+		{name: "lower case method", payments: []models.Payment{{PaymentMethod: "card", Amount: 1}}},
+		{name: "unknown method", payments: []models.Payment{{PaymentMethod: "CHEQUE", Amount: 1}}, want: utils.ErrInvalidPaymentMethod},
+		{name: "zero amount", payments: []models.Payment{{PaymentMethod: "CASH"}}, want: utils.ErrInvalidPaymentAmount},
+		{name: "negative amount", payments: []models.Payment{{PaymentMethod: "CASH", Amount: -1}}, want: utils.ErrInvalidPaymentAmount},
+		{name: "negative tip", payments: []models.Payment{{PaymentMethod: "CASH", Amount: 1, Tip: -0.5}}, want: utils.ErrInvalidTip},
+		{
+			name:     "second payment invalid",
+			payments: []models.Payment{{PaymentMethod: "CASH", Amount: 1}, {PaymentMethod: "CASH", Amount: 0}},
+			want:     utils.ErrInvalidPaymentAmount,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePayments(tt.payments); !errors.Is(err, tt.want) {
+				t.Errorf("validatePayments() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePaymentsNormalizesMethod(t *testing.T) {
+	payments := []models.Payment{{PaymentMethod: "cash", Amount: 1}}
+	if err := validatePayments(payments); err != nil {
+		t.Fatalf("validatePayments() = %v", err)
+	}
+	if payments[0].PaymentMethod != "CASH" {
+		t.Errorf("payment method = %q, want CASH", payments[0].PaymentMethod)
+	}
+}
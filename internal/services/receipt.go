@@ -0,0 +1,512 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"frappuccino/internal/repo"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"html/template"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type ReceiptServiceIfc interface {
+	Render(ctx context.Context, orderId string, format string) ([]byte, string, error)
+}
+
+type ReceiptService struct {
+	orderRepo    repo.OrderRepoIfc
+	refundRepo   repo.RefundRepoIfc
+	customerRepo repo.CustomerRepoIfc
+	locationRepo repo.LocationRepoIfc
+	shop         models.ShopHeader
+}
+
+func NewReceiptService(orderRepo repo.OrderRepoIfc, refundRepo repo.RefundRepoIfc, customerRepo repo.CustomerRepoIfc, locationRepo repo.LocationRepoIfc, shop models.ShopHeader) *ReceiptService {
+	return &ReceiptService{orderRepo: orderRepo, refundRepo: refundRepo, customerRepo: customerRepo, locationRepo: locationRepo, shop: shop}
+}
+
+// ShopHeaderFromEnv reads the receipt header from SHOP_NAME, SHOP_ADDRESS,
+// SHOP_PHONE, SHOP_TAX_ID, RECEIPT_FOOTER, CURRENCY, TAX_NAME and TAX_RATE
+// (percent included in the prices).
+func ShopHeaderFromEnv() models.ShopHeader {
+	shop := models.ShopHeader{
+		Name:     os.Getenv("SHOP_NAME"),
+		Address:  os.Getenv("SHOP_ADDRESS"),
+		Phone:    os.Getenv("SHOP_PHONE"),
+		TaxId:    os.Getenv("SHOP_TAX_ID"),
+		Footer:   os.Getenv("RECEIPT_FOOTER"),
+		Currency: os.Getenv("CURRENCY"),
+		TaxName:  os.Getenv("TAX_NAME"),
+	}
+	if shop.Name == "" {
+		shop.Name = "Frappuccino"
+	}
+	if shop.Footer == "" {
+		shop.Footer = "Thank you for your visit!"
+	}
+	if shop.TaxName == "" {
+		shop.TaxName = "VAT"
+	}
+	if rate, err := strconv.ParseFloat(os.Getenv("TAX_RATE"), 64); err == nil && rate > 0 {
+		shop.TaxRate = rate
+	}
+	return shop
+}
+
+// Render returns the receipt of an order as txt, html or pdf, with its
+// content type.
+func (rs *ReceiptService) Render(ctx context.Context, orderId string, format string) ([]byte, string, error) {
+	format = strings.ToLower(format)
+	if format == "" {
+		format = "txt"
+	}
+	if format != "txt" && format != "html" && format != "pdf" {
+		return nil, "", utils.ErrInvalidReceiptFormat
+	}
+
+	receipt, err := rs.get(ctx, orderId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch format {
+	case "html":
+		var buf bytes.Buffer
+		if err := receiptTemplate.Execute(&buf, receipt); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "text/html; charset=utf-8", nil
+	case "pdf":
+		return receiptPDF(receipt), "application/pdf", nil
+	default:
+		var buf bytes.Buffer
+		for _, line := range receiptText(receipt, receiptTextWidth) {
+			buf.WriteString(line.text)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), "text/plain; charset=utf-8", nil
+	}
+}
+
+func (rs *ReceiptService) get(ctx context.Context, orderId string) (models.Receipt, error) {
+	order, err := rs.orderRepo.GetOrderByID(ctx, orderId)
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	refunds, err := rs.refundRepo.GetByOrderID(ctx, orderId)
+	if err != nil {
+		return models.Receipt{}, err
+	}
+
+	receipt := models.Receipt{
+		Shop:        rs.shop,
+		OrderId:     order.OrderId,
+		OrderStatus: order.OrderStatus,
+		Total:       order.TotalPrice,
+		Payments:    order.Payments,
+	}
+
+	timezone := time.UTC
+	if order.LocationId != "" {
+		location, err := rs.locationRepo.GetByID(ctx, string(order.LocationId))
+		if err != nil {
+			return models.Receipt{}, err
+		}
+		receipt.LocationName = location.LocationName
+		if tz, err := time.LoadLocation(string(location.Timezone)); err == nil {
+			timezone = tz
+		}
+	}
+	receipt.OrderedAt = utils.TIME(time.Time(order.CreatedAt).In(timezone))
+
+	if order.CustomerId != "" {
+		customer, err := rs.customerRepo.GetByID(ctx, string(order.CustomerId))
+		if err != nil {
+			return models.Receipt{}, err
+		}
+		receipt.CustomerName = customer.FullName
+		receipt.CustomerEmail = customer.Email
+	}
+
+	for _, item := range order.OrderItems {
+		amount := roundMoney(item.Quantity * item.UnitPrice)
+		receipt.Lines = append(receipt.Lines, models.ReceiptLine{
+			ItemName:       item.ItemName,
+			Customizations: customizationLines(item.Customizations),
+			Quantity:       item.Quantity,
+			UnitPrice:      item.UnitPrice,
+			Amount:         amount,
+		})
+		receipt.Subtotal += amount
+	}
+	receipt.Subtotal = roundMoney(receipt.Subtotal)
+	if discount := roundMoney(receipt.Subtotal - receipt.Total); discount > 0 {
+		receipt.Discount = discount
+	}
+
+	// Prices include the tax, so it is taken out of the total rather than
+	// added on top
+	if rate := rs.shop.TaxRate; rate > 0 {
+		receipt.Taxes = append(receipt.Taxes, models.ReceiptTax{
+			Name:   rs.shop.TaxName,
+			Rate:   rate,
+			Amount: roundMoney(receipt.Total * utils.DEC(rate/(100+rate))),
+		})
+	}
+
+	for _, payment := range order.Payments {
+		receipt.Paid += payment.Amount
+		receipt.Tips += payment.Tip
+	}
+	receipt.Paid = roundMoney(receipt.Paid)
+	receipt.Tips = roundMoney(receipt.Tips)
+	if balance := roundMoney(receipt.Total - receipt.Paid); balance > 0 {
+		receipt.Balance = balance
+	}
+	for _, refund := range refunds {
+		receipt.Refunded += refund.Amount
+	}
+	receipt.Refunded = roundMoney(receipt.Refunded)
+
+	return receipt, nil
+}
+
+func roundMoney(amount utils.DEC) utils.DEC {
+	return utils.DEC(math.Round(float64(amount)*100) / 100)
+}
+
+// customizationLines turns the customizations of an order line into
+// "key: value" lines sorted by key. Flags set to true print as the key
+// alone and flags set to false are left out.
+func customizationLines(customizations utils.JSONB) []string {
+	if len(customizations) == 0 {
+		return nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(customizations, &fields); err != nil {
+		var list []any
+		if err := json.Unmarshal(customizations, &list); err != nil {
+			return nil
+		}
+		lines := make([]string, 0, len(list))
+		for _, value := range list {
+			lines = append(lines, customizationValue(value))
+		}
+		return lines
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, key := range keys {
+		name := strings.ReplaceAll(key, "_", " ")
+		switch value := fields[key].(type) {
+		case nil:
+		case bool:
+			if value {
+				lines = append(lines, name)
+			}
+		default:
+			lines = append(lines, name+": "+customizationValue(value))
+		}
+	}
+	return lines
+}
+
+func customizationValue(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		if value {
+			return "yes"
+		}
+		return "no"
+	case []any:
+		values := make([]string, len(value))
+		for i, v := range value {
+			values[i] = customizationValue(v)
+		}
+		return strings.Join(values, ", ")
+	default:
+		data, _ := json.Marshal(value)
+		return string(data)
+	}
+}
+
+func formatMoney(amount utils.DEC) string {
+	return strconv.FormatFloat(float64(amount), 'f', 2, 64)
+}
+
+func formatQuantity(quantity utils.DEC) string {
+	return strconv.FormatFloat(float64(quantity), 'f', -1, 64)
+}
+
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', -1, 64) + "%"
+}
+
+func paymentMethodLabel(method utils.TEXT) string {
+	switch method {
+	case "CASH":
+		return "Cash"
+	case "CARD":
+		return "Card"
+	default:
+		return string(method)
+	}
+}
+
+func totalLabel(shop models.ShopHeader) string {
+	if shop.Currency == "" {
+		return "TOTAL"
+	}
+	return "TOTAL " + shop.Currency
+}
+
+// receiptTextWidth fits an 80mm receipt printer.
+const receiptTextWidth = 42
+
+type textLine struct {
+	text string
+	bold bool
+}
+
+// receiptText lays the receipt out in lines of at most width characters.
+// The PDF prints the same lines, so both formats read alike.
+func receiptText(receipt models.Receipt, width int) []textLine {
+	var lines []textLine
+	add := func(text string) {
+		lines = append(lines, textLine{text: text})
+	}
+	rule := func() {
+		add(strings.Repeat("-", width))
+	}
+
+	for _, text := range centerText(receipt.Shop.Name, width) {
+		lines = append(lines, textLine{text: text, bold: true})
+	}
+	for _, text := range centerText(receipt.Shop.Address, width) {
+		add(text)
+	}
+	if receipt.Shop.Phone != "" {
+		for _, text := range centerText("Tel: "+receipt.Shop.Phone, width) {
+			add(text)
+		}
+	}
+	if receipt.Shop.TaxId != "" {
+		for _, text := range centerText("Tax ID: "+receipt.Shop.TaxId, width) {
+			add(text)
+		}
+	}
+	rule()
+
+	if receipt.LocationName != "" {
+		add(textRow("Location", string(receipt.LocationName), width))
+	}
+	add(textRow("Order", string(receipt.OrderId), width))
+	add(textRow("Date", time.Time(receipt.OrderedAt).Format("2006-01-02 15:04"), width))
+	add(textRow("Status", string(receipt.OrderStatus), width))
+	if receipt.CustomerName != "" {
+		add(textRow("Customer", string(receipt.CustomerName), width))
+	}
+	rule()
+
+	for _, line := range receipt.Lines {
+		add(textRow(formatQuantity(line.Quantity)+" x "+string(line.ItemName), formatMoney(line.Amount), width))
+		if line.Quantity != 1 {
+			add(truncateText("    @ "+formatMoney(line.UnitPrice), width))
+		}
+		for _, customization := range line.Customizations {
+			add(truncateText("    "+customization, width))
+		}
+	}
+	rule()
+
+	add(textRow("Subtotal", formatMoney(receipt.Subtotal), width))
+	if receipt.Discount > 0 {
+		add(textRow("Discount", "-"+formatMoney(receipt.Discount), width))
+	}
+	lines = append(lines, textLine{text: textRow(totalLabel(receipt.Shop), formatMoney(receipt.Total), width), bold: true})
+	for _, tax := range receipt.Taxes {
+		add(textRow("  incl. "+tax.Name+" "+formatRate(tax.Rate), formatMoney(tax.Amount), width))
+	}
+
+	if len(receipt.Payments) > 0 || receipt.Refunded > 0 {
+		rule()
+	}
+	for _, payment := range receipt.Payments {
+		add(textRow(paymentMethodLabel(payment.PaymentMethod), formatMoney(payment.Amount), width))
+		if payment.Tip > 0 {
+			add(textRow("  Tip", formatMoney(payment.Tip), width))
+		}
+	}
+	if len(receipt.Payments) > 0 {
+		add(textRow("Paid", formatMoney(receipt.Paid), width))
+	}
+	if receipt.Balance > 0 {
+		add(textRow("Balance due", formatMoney(receipt.Balance), width))
+	}
+	if receipt.Refunded > 0 {
+		add(textRow("Refunded", "-"+formatMoney(receipt.Refunded), width))
+	}
+
+	if receipt.Shop.Footer != "" {
+		rule()
+		for _, text := range centerText(receipt.Shop.Footer, width) {
+			add(text)
+		}
+	}
+	return lines
+}
+
+// textRow puts left and right at the two edges of a line, cutting left short
+// when both do not fit, and right too when it alone is wider than the line.
+func textRow(left string, right string, width int) string {
+	right = truncateText(right, width)
+	left = truncateText(left, width-utf8.RuneCountInString(right)-1)
+	padding := width - utf8.RuneCountInString(left) - utf8.RuneCountInString(right)
+	return left + strings.Repeat(" ", max(padding, 0)) + right
+}
+
+func truncateText(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:width-1]) + "~"
+}
+
+// centerText wraps text on spaces and centers every line.
+func centerText(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		word = truncateText(word, width)
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	for i, line := range lines {
+		lines[i] = strings.Repeat(" ", (width-utf8.RuneCountInString(line))/2) + line
+	}
+	return lines
+}
+
+// A4 in points, printed in 10pt Courier.
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 56
+	pdfFontSize   = 10
+	pdfLeading    = 13
+	pdfColumns    = 64
+)
+
+func receiptPDF(receipt models.Receipt) []byte {
+	// Center the column of text on the page
+	x := (pdfPageWidth - pdfColumns*pdfFontSize*utils.CourierAdvance) / 2
+
+	pdf := utils.NewPDF(pdfPageWidth, pdfPageHeight)
+	y := float64(0)
+	for _, line := range receiptText(receipt, pdfColumns) {
+		if y < pdfMargin {
+			pdf.AddPage()
+			y = pdfPageHeight - pdfMargin - pdfFontSize
+		}
+		pdf.Text(x, y, pdfFontSize, line.bold, line.text)
+		y -= pdfLeading
+	}
+	return pdf.Bytes()
+}
+
+var receiptTemplate = template.Must(template.New("receipt").Funcs(template.FuncMap{
+	"money":    formatMoney,
+	"quantity": formatQuantity,
+	"rate":     formatRate,
+	"method":   paymentMethodLabel,
+	"total":    totalLabel,
+	"date": func(t utils.TIME) string {
+		return time.Time(t).Format("2006-01-02 15:04")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Receipt {{.OrderId}}</title>
+<style>
+body { font-family: sans-serif; max-width: 26rem; margin: 2rem auto; color: #222; }
+header, footer { text-align: center; }
+h1 { font-size: 1.3rem; margin: 0 0 .3rem; }
+p { margin: .15rem 0; }
+table { width: 100%; border-collapse: collapse; margin: .8rem 0; }
+td { padding: .15rem 0; vertical-align: top; }
+td.amount { text-align: right; white-space: nowrap; }
+tbody tr:first-child td, tr.total td { border-top: 1px solid #ccc; }
+tr.total td { font-weight: bold; }
+.note { color: #666; font-size: .85rem; padding-left: 1rem; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Shop.Name}}</h1>
+{{with .Shop.Address}}<p>{{.}}</p>{{end}}
+{{with .Shop.Phone}}<p>Tel: {{.}}</p>{{end}}
+{{with .Shop.TaxId}}<p>Tax ID: {{.}}</p>{{end}}
+</header>
+<table>
+{{with .LocationName}}<tr><td>Location</td><td class="amount">{{.}}</td></tr>{{end}}
+<tr><td>Order</td><td class="amount">{{.OrderId}}</td></tr>
+<tr><td>Date</td><td class="amount">{{date .OrderedAt}}</td></tr>
+<tr><td>Status</td><td class="amount">{{.OrderStatus}}</td></tr>
+{{with .CustomerName}}<tr><td>Customer</td><td class="amount">{{.}}</td></tr>{{end}}
+</table>
+<table>
+<tbody>
+{{range .Lines}}<tr><td>{{quantity .Quantity}} &times; {{.ItemName}}{{if ne .Quantity 1.0}} <span class="note">@ {{money .UnitPrice}}</span>{{end}}{{range .Customizations}}<div class="note">{{.}}</div>{{end}}</td><td class="amount">{{money .Amount}}</td></tr>
+{{end}}</tbody>
+<tbody>
+<tr><td>Subtotal</td><td class="amount">{{money .Subtotal}}</td></tr>
+{{if gt .Discount 0.0}}<tr><td>Discount</td><td class="amount">-{{money .Discount}}</td></tr>{{end}}
+<tr class="total"><td>{{total .Shop}}</td><td class="amount">{{money .Total}}</td></tr>
+{{range .Taxes}}<tr><td class="note">incl. {{.Name}} {{rate .Rate}}</td><td class="amount note">{{money .Amount}}</td></tr>
+{{end}}</tbody>
+{{if or .Payments (gt .Refunded 0.0)}}<tbody>
+{{range .Payments}}<tr><td>{{method .PaymentMethod}}</td><td class="amount">{{money .Amount}}</td></tr>
+{{if gt .Tip 0.0}}<tr><td class="note">Tip</td><td class="amount note">{{money .Tip}}</td></tr>{{end}}
+{{end}}{{if .Payments}}<tr><td>Paid</td><td class="amount">{{money .Paid}}</td></tr>{{end}}
+{{if gt .Balance 0.0}}<tr><td>Balance due</td><td class="amount">{{money .Balance}}</td></tr>{{end}}
+{{if gt .Refunded 0.0}}<tr><td>Refunded</td><td class="amount">-{{money .Refunded}}</td></tr>{{end}}
+</tbody>{{end}}
+</table>
+{{with .Shop.Footer}}<footer><p>{{.}}</p></footer>{{end}}
+</body>
+</html>
+`))
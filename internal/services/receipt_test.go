@@ -0,0 +1,97 @@
+package services
+
+import (
+	"frappuccino/models"
+	"frappuccino/utils"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestTextRow(t *testing.T) {
+	tests := []struct {
+		name  string
+		left  string
+		right string
+		width int
+		want  string
+	}{
+		{name: "pads between", left: "Latte", right: "4.50", width: 12, want: "Latte   4.50"},
+		{name: "exact fit keeps a space", left: "Latte", right: "14.50", width: 11, want: "Latte 14.50"},
+		{name: "cuts left short", left: "Caramel Macchiato", right: "4.50", width: 12, want: "Carame~ 4.50"},
+		{name: "counts runes", left: "Café crème", right: "3.00", width: 12, want: "Café c~ 3.00"},
+		{name: "right alone too wide", left: "Order", right: "0123456789abcdef", width: 8, want: "0123456~"},
+		{name: "empty left", left: "", right: "1.00", width: 6, want: "  1.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := textRow(tt.left, tt.right, tt.width)
+			if got != tt.want {
+				t.Errorf("textRow(%q, %q, %d) = %q, want %q", tt.left, tt.right, tt.width, got, tt.want)
+			}
+			if n := utf8.RuneCountInString(got); n > tt.width {
+				t.Errorf("textRow() is %d wide, more than %d", n, tt.width)
+			}
+		})
+	}
+}
+
+func TestReceiptText(t *testing.T) {
+	receipt := models.Receipt{
+		Shop: models.ShopHeader{
+			Name:     "Frappuccino Coffee House",
+			Address:  "1 Very Long Street Name That Has To Wrap, Springfield",
+			Currency: "USD",
+			TaxName:  "VAT",
+			TaxRate:  20,
+			Footer:   "Thank you!",
+		},
+		OrderId:     "3f1c2a7e-8a65-4b53-9d7e-2b1f0c4d5e6f",
+		OrderStatus: "COMPLETED",
+		OrderedAt:   utils.TIME(time.Date(2024, 3, 1, 9, 15, 0, 0, time.UTC)),
+		Lines: []models.ReceiptLine{
+			{ItemName: "Latte", Quantity: 2, UnitPrice: 4.5, Amount: 9, Customizations: []string{"oat milk"}},
+			{ItemName: "An item with an extraordinarily long name", Quantity: 1, UnitPrice: 3, Amount: 3},
+		},
+		Subtotal: 12,
+		Discount: 2,
+		Total:    10,
+		Taxes:    []models.ReceiptTax{{Name: "VAT", Rate: 20, Amount: 1.67}},
+		Payments: []models.Payment{{PaymentMethod: "CARD", Amount: 10, Tip: 1}},
+		Paid:     10,
+		Tips:     1,
+	}
+
+	tests := []struct {
+		name  string
+		width int
+	}{
+		{name: "receipt printer", width: receiptTextWidth},
+		{name: "narrow", width: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := receiptText(receipt, tt.width)
+			var text []string
+			for _, line := range lines {
+				if n := utf8.RuneCountInString(line.text); n > tt.width {
+					t.Errorf("line %q is %d wide, more than %d", line.text, n, tt.width)
+				}
+				text = append(text, line.text)
+			}
+			if !lines[0].bold {
+				t.Error("shop name is not bold")
+			}
+			joined := strings.Join(text, "\n")
+			for _, want := range []string{"Subtotal", "Discount", "TOTAL USD", "Card", "Tip", "Paid", "Thank you!", "oat milk"} {
+				if !strings.Contains(joined, want) {
+					t.Errorf("receipt misses %q:\n%s", want, joined)
+				}
+			}
+			if strings.Contains(joined, "Balance due") {
+				t.Errorf("paid receipt shows a balance:\n%s", joined)
+			}
+		})
+	}
+}
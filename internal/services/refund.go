@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"frappuccino/internal/repo"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"log"
+	"strings"
+)
+
+type RefundServiceIfc interface {
+	Create(ctx context.Context, orderId string, request *models.RefundRequest) (models.Refund, error)
+	GetByOrderID(ctx context.Context, orderId string) ([]models.Refund, error)
+}
+
+type RefundService struct {
+	refundRepo repo.RefundRepoIfc
+	orderRepo  repo.OrderRepoIfc
+	auditRepo  repo.AuditRepoIfc
+	events     EventBusIfc
+}
+
+func NewRefundService(refundRepo repo.RefundRepoIfc, orderRepo repo.OrderRepoIfc, auditRepo repo.AuditRepoIfc, events EventBusIfc) *RefundService {
+	return &RefundService{refundRepo: refundRepo, orderRepo: orderRepo, auditRepo: auditRepo, events: events}
+}
+
+// Create refunds an order. Once nothing is left to refund the order becomes
+// REFUNDED; both the status change and a restock are published.
+func (rs *RefundService) Create(ctx context.Context, orderId string, request *models.RefundRequest) (models.Refund, error) {
+	request.Reason = utils.TEXT(strings.TrimSpace(string(request.Reason)))
+	if request.Reason == "" {
+		return models.Refund{}, utils.ErrMissingRefundReason
+	}
+	for _, item := range request.Items {
+		if item.Quantity <= 0 {
+			return models.Refund{}, utils.ErrInvalidQuantity
+		}
+	}
+
+	log.Printf("Refunding order [%s]: %s", orderId, request.Reason)
+	var refund models.Refund
+	err := rs.events.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := rs.orderRepo.GetOrderByID(ctx, orderId)
+		if err != nil {
+			return err
+		}
+		refund, err = rs.refundRepo.Create(ctx, orderId, request)
+		if err != nil {
+			return err
+		}
+
+		var events []Event
+		if refund.Restocked {
+			restock, err := rs.refundRepo.GetRestock(ctx, string(refund.RefundId))
+			if err != nil {
+				return err
+			}
+			for _, change := range restock {
+				events = append(events, StockAdjusted{StockChange: change, Reason: "ADD"})
+			}
+		}
+		remaining, err := rs.refundRepo.GetRemaining(ctx, orderId)
+		if err != nil {
+			return err
+		}
+		if remaining <= 0 {
+			err := rs.orderRepo.Patch(ctx, orderId, map[string]any{"order_status": "REFUNDED"}, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		after, err := rs.orderRepo.GetOrderByID(ctx, orderId)
+		if err != nil {
+			return err
+		}
+		if after.OrderStatus != before.OrderStatus {
+			events = append(events, OrderStatusChanged{Order: after, PreviousStatus: before.OrderStatus})
+		}
+		if err := rs.events.Publish(ctx, events...); err != nil {
+			return err
+		}
+		return recordAudit(ctx, rs.auditRepo, "order", utils.TEXT(orderId), before, after)
+	})
+	if err != nil {
+		return models.Refund{}, err
+	}
+	log.Printf("Refund [%s] of %.2f recorded for order [%s]", refund.RefundId, float64(refund.Amount), orderId)
+	return refund, nil
+}
+
+func (rs *RefundService) GetByOrderID(ctx context.Context, orderId string) ([]models.Refund, error) {
+	refunds, err := rs.refundRepo.GetByOrderID(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+	if refunds == nil {
+		refunds = []models.Refund{}
+	}
+	return refunds, nil
+}
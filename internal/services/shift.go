@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"frappuccino/internal/repo"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"log"
+	"time"
+)
+
+// clockInEarly is how long before the scheduled start a shift may be
+// clocked in.
+const clockInEarly = 15 * time.Minute
+
+type ShiftServiceIfc interface {
+	Create(ctx context.Context, shift *models.Shift) (models.Shift, error)
+	GetAll(ctx context.Context, staffId string, from string, to string) ([]models.Shift, error)
+	GetByID(ctx context.Context, shiftId string) (models.Shift, error)
+	UpdateByID(ctx context.Context, shift *models.Shift) error
+	DeleteByID(ctx context.Context, shiftId string) error
+	ClockIn(ctx context.Context, shiftId string) (models.Shift, error)
+	ClockOut(ctx context.Context, shiftId string) (models.Shift, error)
+}
+
+type ShiftService struct {
+	shiftRepo repo.ShiftRepoIfc
+}
+
+func NewShiftService(shiftRepo repo.ShiftRepoIfc) *ShiftService {
+	return &ShiftService{shiftRepo: shiftRepo}
+}
+
+func (ss *ShiftService) Create(ctx context.Context, shift *models.Shift) (models.Shift, error) {
+	if err := validateShift(shift); err != nil {
+		return models.Shift{}, err
+	}
+	log.Printf("Scheduling shift for staff [%s]", shift.StaffId)
+	created, err := ss.shiftRepo.Create(ctx, shift)
+	if err != nil {
+		return models.Shift{}, err
+	}
+	log.Println("Shift scheduled successfully:", created.ShiftId)
+	return created, nil
+}
+
+// GetAll lists the shifts that overlap the from/to window; both ends are
+// optional and accept RFC 3339 timestamps or dates.
+func (ss *ShiftService) GetAll(ctx context.Context, staffId string, from string, to string) ([]models.Shift, error) {
+	var fromTime, toTime time.Time
+	var err error
+	if from != "" {
+		if fromTime, err = parseReportTime(from, time.UTC, false); err != nil {
+			return nil, err
+		}
+	}
+	if to != "" {
+		if toTime, err = parseReportTime(to, time.UTC, true); err != nil {
+			return nil, err
+		}
+	}
+	if !fromTime.IsZero() && !toTime.IsZero() && !fromTime.Before(toTime) {
+		return nil, utils.ErrInvalidDateRange
+	}
+
+	shifts, err := ss.shiftRepo.GetAll(ctx, staffId, fromTime, toTime)
+	if err != nil {
+		return nil, err
+	}
+	if shifts == nil {
+		shifts = []models.Shift{}
+	}
+	return shifts, nil
+}
+
+func (ss *ShiftService) GetByID(ctx context.Context, shiftId string) (models.Shift, error) {
+	return ss.shiftRepo.GetByID(ctx, shiftId)
+}
+
+func (ss *ShiftService) UpdateByID(ctx context.Context, shift *models.Shift) error {
+	if err := validateShift(shift); err != nil {
+		return err
+	}
+	log.Printf("Rescheduling shift [%s]", shift.ShiftId)
+	return ss.shiftRepo.UpdateByID(ctx, shift)
+}
+
+func (ss *ShiftService) DeleteByID(ctx context.Context, shiftId string) error {
+	log.Printf("Deleting shift [%s]", shiftId)
+	return ss.shiftRepo.DeleteByID(ctx, shiftId)
+}
+
+// ClockIn starts the hours worked on a shift. Baristas can only clock in
+// their own shifts; managers can clock in anyone.
+func (ss *ShiftService) ClockIn(ctx context.Context, shiftId string) (models.Shift, error) {
+	shift, err := ss.ownShift(ctx, shiftId)
+	if err != nil {
+		return models.Shift{}, err
+	}
+	now := time.Now()
+	if now.Before(time.Time(shift.StartsAt).Add(-clockInEarly)) || !now.Before(time.Time(shift.EndsAt)) {
+		return models.Shift{}, utils.ErrClockInWindow
+	}
+
+	if err := ss.shiftRepo.ClockIn(ctx, shiftId); err != nil {
+		return models.Shift{}, err
+	}
+	log.Printf("Staff [%s] clocked in to shift [%s]", shift.StaffId, shiftId)
+	return ss.shiftRepo.GetByID(ctx, shiftId)
+}
+
+func (ss *ShiftService) ClockOut(ctx context.Context, shiftId string) (models.Shift, error) {
+	shift, err := ss.ownShift(ctx, shiftId)
+	if err != nil {
+		return models.Shift{}, err
+	}
+
+	if err := ss.shiftRepo.ClockOut(ctx, shiftId); err != nil {
+		return models.Shift{}, err
+	}
+	log.Printf("Staff [%s] clocked out of shift [%s]", shift.StaffId, shiftId)
+	return ss.shiftRepo.GetByID(ctx, shiftId)
+}
+
+// ownShift loads a shift the caller may clock: their own, or any shift when
+// the caller is at least a manager.
+func (ss *ShiftService) ownShift(ctx context.Context, shiftId string) (models.Shift, error) {
+	shift, err := ss.shiftRepo.GetByID(ctx, shiftId)
+	if err != nil {
+		return models.Shift{}, err
+	}
+	principal, ok := utils.PrincipalFrom(ctx)
+	if !ok {
+		return models.Shift{}, utils.ErrUnauthorized
+	}
+	if principal.StaffId != string(shift.StaffId) && !utils.RoleAllows(principal.Role, utils.RoleManager) {
+		return models.Shift{}, utils.ErrForbidden
+	}
+	return shift, nil
+}
+
+func validateShift(shift *models.Shift) error {
+	if shift.StaffId == "" {
+		return utils.ErrMissingStaff
+	}
+	if time.Time(shift.StartsAt).IsZero() || !time.Time(shift.EndsAt).After(time.Time(shift.StartsAt)) {
+		return utils.ErrInvalidShiftTime
+	}
+	return nil
+}
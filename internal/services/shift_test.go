@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"testing"
+	"time"
+)
+
+func TestValidateShift(t *testing.T) {
+	start := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		shift models.Shift
+		want  error
+	}{
+		{
+			name:  "valid",
+			shift: models.Shift{StaffId: "s1", StartsAt: utils.TIME(start), EndsAt: utils.TIME(start.Add(8 * time.Hour))},
+		},
+		{
+			name:  "no staff",
+			shift: models.Shift{StartsAt: utils.TIME(start), EndsAt: utils.TIME(start.Add(time.Hour))},
+			want:  utils.ErrMissingStaff,
+		},
+		{
+			name:  "no start",
+			shift: models.Shift{StaffId: "s1", EndsAt: utils.TIME(start)},
+			want:  utils.ErrInvalidShiftTime,
+		},
+		{
+			name:  "ends when it starts",
+			shift: models.Shift{StaffId: "s1", StartsAt: utils.TIME(start), EndsAt: utils.TIME(start)},
+			want:  utils.ErrInvalidShiftTime,
+		},
+		{
+			name:  "ends before it starts",
+			shift: models.Shift{StaffId: "s1", StartsAt: utils.TIME(start), EndsAt: utils.TIME(start.Add(-time.Hour))},
+			want:  utils.ErrInvalidShiftTime,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateShift(&tt.shift); !errors.Is(err, tt.want) {
+				t.Errorf("validateShift() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
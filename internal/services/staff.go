@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"frappuccino/internal/repo"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"log"
+	"strings"
+)
+
+const minPasswordLength = 8
+
+type StaffServiceIfc interface {
+	Create(ctx context.Context, staff *models.Staff) (*models.Staff, error)
+	GetAll(ctx context.Context) ([]models.Staff, error)
+	GetByID(ctx context.Context, staffId string) (models.Staff, error)
+	UpdateByID(ctx context.Context, staff *models.Staff) error
+}
+
+type StaffService struct {
+	staffRepo repo.StaffRepoIfc
+}
+
+func NewStaffService(staffRepo repo.StaffRepoIfc) *StaffService {
+	return &StaffService{staffRepo: staffRepo}
+}
+
+// Create opens an account. The password is only kept as a PBKDF2 hash.
+func (ss *StaffService) Create(ctx context.Context, staff *models.Staff) (*models.Staff, error) {
+	staff.Username = utils.TEXT(strings.TrimSpace(string(staff.Username)))
+	if staff.Username == "" {
+		return nil, utils.ErrInvalidUsername
+	}
+	staff.StaffRole = utils.TEXT(strings.ToUpper(string(staff.StaffRole)))
+	if staff.StaffRole == "" {
+		staff.StaffRole = utils.RoleBarista
+	}
+	if !utils.IsRole(string(staff.StaffRole)) {
+		return nil, utils.ErrInvalidRole
+	}
+	if len(staff.Password) < minPasswordLength {
+		return nil, utils.ErrWeakPassword
+	}
+
+	passwordHash, err := utils.HashPassword(string(staff.Password))
+	if err != nil {
+		return nil, err
+	}
+	staff.IsActive = true
+
+	log.Printf("Creating staff account %s with role %s", staff.Username, staff.StaffRole)
+	created, err := ss.staffRepo.Create(ctx, staff, passwordHash)
+	if err != nil {
+		return nil, err
+	}
+	log.Println("Staff account created successfully:", created.StaffId)
+	return created, nil
+}
+
+func (ss *StaffService) GetAll(ctx context.Context) ([]models.Staff, error) {
+	return ss.staffRepo.GetAll(ctx)
+}
+
+func (ss *StaffService) GetByID(ctx context.Context, staffId string) (models.Staff, error) {
+	return ss.staffRepo.GetByID(ctx, staffId)
+}
+
+// UpdateByID changes an account. The username is fixed; the password is only
+// changed when a new one is given.
+func (ss *StaffService) UpdateByID(ctx context.Context, staff *models.Staff) error {
+	staff.StaffRole = utils.TEXT(strings.ToUpper(string(staff.StaffRole)))
+	if !utils.IsRole(string(staff.StaffRole)) {
+		return utils.ErrInvalidRole
+	}
+
+	var passwordHash string
+	if staff.Password != "" {
+		if len(staff.Password) < minPasswordLength {
+			return utils.ErrWeakPassword
+		}
+		var err error
+		passwordHash, err = utils.HashPassword(string(staff.Password))
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Updating staff account [%s]", staff.StaffId)
+	if err := ss.staffRepo.UpdateByID(ctx, staff, passwordHash); err != nil {
+		return err
+	}
+	log.Printf("Staff account [%s] updated successfully", staff.StaffId)
+	return nil
+}
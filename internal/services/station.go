@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"frappuccino/internal/repo"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"log"
+	"strings"
+)
+
+type StationServiceIfc interface {
+	GetAll(ctx context.Context) ([]models.Station, error)
+	GetTickets(ctx context.Context, stationId string, status string) ([]models.StationTicket, error)
+	BumpTicket(ctx context.Context, stationId string, ticketId string) (models.StationTicket, error)
+	BumpItem(ctx context.Context, stationId string, ticketId string, orderItemId string) (models.StationTicket, error)
+}
+
+type StationService struct {
+	stationRepo repo.StationRepoIfc
+	orderRepo   repo.OrderRepoIfc
+	auditRepo   repo.AuditRepoIfc
+	events      EventBusIfc
+}
+
+func NewStationService(stationRepo repo.StationRepoIfc, orderRepo repo.OrderRepoIfc, auditRepo repo.AuditRepoIfc, events EventBusIfc) *StationService {
+	return &StationService{stationRepo: stationRepo, orderRepo: orderRepo, auditRepo: auditRepo, events: events}
+}
+
+func (ss *StationService) GetAll(ctx context.Context) ([]models.Station, error) {
+	stations, err := ss.stationRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if stations == nil {
+		stations = []models.Station{}
+	}
+	return stations, nil
+}
+
+// GetTickets lists the OPEN tickets of a station, or with status BUMPED the
+// ones most recently bumped.
+func (ss *StationService) GetTickets(ctx context.Context, stationId string, status string) ([]models.StationTicket, error) {
+	status = strings.ToUpper(status)
+	switch status {
+	case "":
+		status = "OPEN"
+	case "OPEN", "BUMPED":
+	default:
+		return nil, utils.ErrInvalidTicketStatus
+	}
+
+	tickets, err := ss.stationRepo.GetTickets(ctx, stationId, status)
+	if err != nil {
+		return nil, err
+	}
+	if tickets == nil {
+		tickets = []models.StationTicket{}
+	}
+	return tickets, nil
+}
+
+// BumpTicket marks a ticket as done. Bumping the last open ticket of a
+// PENDING order makes the order READY.
+func (ss *StationService) BumpTicket(ctx context.Context, stationId string, ticketId string) (models.StationTicket, error) {
+	return ss.bump(ctx, stationId, ticketId, func(ctx context.Context, staffId *utils.TEXT) (string, error) {
+		return ss.stationRepo.BumpTicket(ctx, stationId, ticketId, staffId)
+	})
+}
+
+// BumpItem marks one line of a ticket as done; the ticket is bumped with its
+// last line.
+func (ss *StationService) BumpItem(ctx context.Context, stationId string, ticketId string, orderItemId string) (models.StationTicket, error) {
+	return ss.bump(ctx, stationId, ticketId, func(ctx context.Context, staffId *utils.TEXT) (string, error) {
+		return ss.stationRepo.BumpItem(ctx, stationId, ticketId, orderItemId, staffId)
+	})
+}
+
+// bump runs a bump and moves the order of the ticket to READY once none of
+// its tickets is open, all in one transaction.
+func (ss *StationService) bump(ctx context.Context, stationId string, ticketId string, bump func(ctx context.Context, staffId *utils.TEXT) (string, error)) (models.StationTicket, error) {
+	var staffId *utils.TEXT
+	if principal, ok := utils.PrincipalFrom(ctx); ok && principal.StaffId != "" {
+		id := utils.TEXT(principal.StaffId)
+		staffId = &id
+	}
+
+	var before, after models.Orders
+	var ready bool
+	err := ss.events.WithinTx(ctx, func(ctx context.Context) error {
+		orderId, err := bump(ctx, staffId)
+		if err != nil {
+			return err
+		}
+		before, err = ss.orderRepo.GetOrderByID(ctx, orderId)
+		if err != nil {
+			return err
+		}
+		ready, err = ss.stationRepo.MarkOrderReady(ctx, orderId)
+		if err != nil || !ready {
+			return err
+		}
+		after, err = ss.orderRepo.GetOrderByID(ctx, orderId)
+		if err != nil {
+			return err
+		}
+		if err := ss.events.Publish(ctx, OrderStatusChanged{Order: after, PreviousStatus: before.OrderStatus}); err != nil {
+			return err
+		}
+		return recordAudit(ctx, ss.auditRepo, "order", after.OrderId, before, after)
+	})
+	if err != nil {
+		return models.StationTicket{}, err
+	}
+	log.Printf("Ticket [%s] bumped at station [%s]", ticketId, stationId)
+	if ready {
+		log.Printf("Order [%s] is ready", after.OrderId)
+	}
+
+	return ss.stationRepo.GetTicket(ctx, stationId, ticketId)
+}
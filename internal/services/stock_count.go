@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"frappuccino/internal/repo"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"log"
+)
+
+type StockCountServiceIfc interface {
+	Create(ctx context.Context, stockCount *models.StockCount) (*models.StockCount, error)
+	GetAll(ctx context.Context) ([]models.StockCount, error)
+	GetByID(ctx context.Context, stockCountId string) (models.StockCount, error)
+	SubmitLines(ctx context.Context, stockCountId string, submission *models.StockCountSubmission) error
+	Commit(ctx context.Context, stockCountId string) error
+	Cancel(ctx context.Context, stockCountId string) error
+}
+
+type StockCountService struct {
+	stockCountRepo repo.StockCountRepoIfc
+	inventoryRepo  repo.InventoryRepoIfc
+	auditRepo      repo.AuditRepoIfc
+	events         EventBusIfc
+}
+
+func NewStockCountService(stockCountRepo repo.StockCountRepoIfc, inventoryRepo repo.InventoryRepoIfc, auditRepo repo.AuditRepoIfc, events EventBusIfc) *StockCountService {
+	return &StockCountService{stockCountRepo: stockCountRepo, inventoryRepo: inventoryRepo, auditRepo: auditRepo, events: events}
+}
+
+func (ss *StockCountService) Create(ctx context.Context, stockCount *models.StockCount) (*models.StockCount, error) {
+	log.Println("Opening new stock count by:", stockCount.OpenedBy)
+	created, err := ss.stockCountRepo.Create(ctx, stockCount)
+	if err != nil {
+		return nil, err
+	}
+	log.Println("Stock count opened successfully:", created.StockCountId)
+	return created, nil
+}
+
+func (ss *StockCountService) GetAll(ctx context.Context) ([]models.StockCount, error) {
+	return ss.stockCountRepo.GetAll(ctx)
+}
+
+func (ss *StockCountService) GetByID(ctx context.Context, stockCountId string) (models.StockCount, error) {
+	return ss.stockCountRepo.GetByID(ctx, stockCountId)
+}
+
+func (ss *StockCountService) SubmitLines(ctx context.Context, stockCountId string, submission *models.StockCountSubmission) error {
+	if len(submission.Items) == 0 {
+		return utils.ErrEmptyStockCount
+	}
+	for _, item := range submission.Items {
+		if item.IngredientId == "" {
+			return utils.ErrInvalidIngredientId
+		}
+		if item.CountedQuantity < 0 {
+			return utils.ErrInvalidQuantity
+		}
+	}
+	log.Printf("Submitting %d counted items to stock count [%s]", len(submission.Items), stockCountId)
+	return ss.stockCountRepo.SubmitLines(ctx, stockCountId, submission)
+}
+
+// Commit books the differences found by the count as ADJUST and publishes
+// them as StockAdjusted.
+func (ss *StockCountService) Commit(ctx context.Context, stockCountId string) error {
+	log.Printf("Committing stock count [%s]", stockCountId)
+	err := ss.events.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := ss.stockCountRepo.GetByID(ctx, stockCountId)
+		if err != nil {
+			return err
+		}
+		var ingredientIds []utils.TEXT
+		for _, line := range before.Lines {
+			ingredientIds = append(ingredientIds, line.IngredientId)
+		}
+		return adjustStock(ctx, ss.events, ss.inventoryRepo, string(before.LocationId), "ADJUST", ingredientIds, func(ctx context.Context) error {
+			if err := ss.stockCountRepo.Commit(ctx, stockCountId); err != nil {
+				return err
+			}
+			after, err := ss.stockCountRepo.GetByID(ctx, stockCountId)
+			if err != nil {
+				return err
+			}
+			return recordAudit(ctx, ss.auditRepo, "stock_count", utils.TEXT(stockCountId), before, after)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Stock count [%s] committed successfully", stockCountId)
+	return nil
+}
+
+func (ss *StockCountService) Cancel(ctx context.Context, stockCountId string) error {
+	log.Printf("Cancelling stock count [%s]", stockCountId)
+	return ss.stockCountRepo.Cancel(ctx, stockCountId)
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"frappuccino/internal/repo"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"log"
+	"strings"
+)
+
+type StockTransferServiceIfc interface {
+	Create(ctx context.Context, transfer *models.StockTransfer) (models.StockTransfer, error)
+	GetAll(ctx context.Context, status string) ([]models.StockTransfer, error)
+	GetByID(ctx context.Context, transferId string) (models.StockTransfer, error)
+	Ship(ctx context.Context, transferId string) error
+	Receive(ctx context.Context, transferId string, receipt *models.StockTransferReceipt) error
+	Cancel(ctx context.Context, transferId string) error
+}
+
+// transferStatuses mirrors the all_stock_transfer_status enum.
+var transferStatuses = map[string]bool{
+	"DRAFT":      true,
+	"IN_TRANSIT": true,
+	"RECEIVED":   true,
+	"CANCELLED":  true,
+}
+
+type StockTransferService struct {
+	stockTransferRepo repo.StockTransferRepoIfc
+	inventoryRepo     repo.InventoryRepoIfc
+	auditRepo         repo.AuditRepoIfc
+	events            EventBusIfc
+}
+
+func NewStockTransferService(stockTransferRepo repo.StockTransferRepoIfc, inventoryRepo repo.InventoryRepoIfc, auditRepo repo.AuditRepoIfc, events EventBusIfc) *StockTransferService {
+	return &StockTransferService{stockTransferRepo: stockTransferRepo, inventoryRepo: inventoryRepo, auditRepo: auditRepo, events: events}
+}
+
+// Create drafts a transfer. The source defaults to the selected location.
+func (ts *StockTransferService) Create(ctx context.Context, transfer *models.StockTransfer) (models.StockTransfer, error) {
+	if transfer.FromLocationId == "" {
+		transfer.FromLocationId = utils.TEXT(utils.LocationOrDefault(ctx))
+	}
+	if transfer.ToLocationId == "" {
+		return models.StockTransfer{}, utils.ErrMissingDestination
+	}
+	if transfer.FromLocationId == transfer.ToLocationId {
+		return models.StockTransfer{}, utils.ErrSameLocation
+	}
+	if len(transfer.Items) == 0 {
+		return models.StockTransfer{}, utils.ErrEmptyTransfer
+	}
+	for _, item := range transfer.Items {
+		if item.IngredientId == "" {
+			return models.StockTransfer{}, utils.ErrInvalidIngredientId
+		}
+		if item.QuantitySent <= 0 {
+			return models.StockTransfer{}, utils.ErrInvalidQuantity
+		}
+	}
+
+	log.Printf("Drafting stock transfer from [%s] to [%s] with %d items", transfer.FromLocationId, transfer.ToLocationId, len(transfer.Items))
+	created, err := ts.stockTransferRepo.Create(ctx, transfer)
+	if err != nil {
+		return models.StockTransfer{}, err
+	}
+	log.Println("Stock transfer drafted successfully:", created.TransferId)
+	return created, nil
+}
+
+func (ts *StockTransferService) GetAll(ctx context.Context, status string) ([]models.StockTransfer, error) {
+	status = strings.ToUpper(status)
+	if status != "" && !transferStatuses[status] {
+		return nil, utils.ErrInvalidStatus
+	}
+	return ts.stockTransferRepo.GetAll(ctx, status)
+}
+
+func (ts *StockTransferService) GetByID(ctx context.Context, transferId string) (models.StockTransfer, error) {
+	return ts.stockTransferRepo.GetByID(ctx, transferId)
+}
+
+func (ts *StockTransferService) Ship(ctx context.Context, transferId string) error {
+	log.Printf("Shipping stock transfer [%s]", transferId)
+	err := ts.move(ctx, transferId, "TRANSFER_OUT", func(ctx context.Context) error {
+		return ts.stockTransferRepo.Ship(ctx, transferId)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Stock transfer [%s] is in transit", transferId)
+	return nil
+}
+
+func (ts *StockTransferService) Receive(ctx context.Context, transferId string, receipt *models.StockTransferReceipt) error {
+	for _, item := range receipt.Items {
+		if item.IngredientId == "" {
+			return utils.ErrInvalidIngredientId
+		}
+		if item.QuantityReceived < 0 {
+			return utils.ErrInvalidQuantity
+		}
+	}
+
+	log.Printf("Receiving stock transfer [%s]", transferId)
+	err := ts.move(ctx, transferId, "TRANSFER_IN", func(ctx context.Context) error {
+		return ts.stockTransferRepo.Receive(ctx, transferId, receipt)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Stock transfer [%s] received successfully", transferId)
+	return nil
+}
+
+// move runs change, which books the transfer out of its source or into its
+// destination as reason says, in a transaction together with the audit entry
+// of the transfer, and publishes the stock it moved as StockAdjusted.
+func (ts *StockTransferService) move(ctx context.Context, transferId string, reason utils.TEXT, change func(ctx context.Context) error) error {
+	return ts.events.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := ts.stockTransferRepo.GetByID(ctx, transferId)
+		if err != nil {
+			return err
+		}
+		locationId := before.FromLocationId
+		if reason == "TRANSFER_IN" {
+			locationId = before.ToLocationId
+		}
+		var ingredientIds []utils.TEXT
+		for _, item := range before.Items {
+			ingredientIds = append(ingredientIds, item.IngredientId)
+		}
+
+		return adjustStock(ctx, ts.events, ts.inventoryRepo, string(locationId), reason, ingredientIds, func(ctx context.Context) error {
+			if err := change(ctx); err != nil {
+				return err
+			}
+			after, err := ts.stockTransferRepo.GetByID(ctx, transferId)
+			if err != nil {
+				return err
+			}
+			return recordAudit(ctx, ts.auditRepo, "transfer", utils.TEXT(transferId), before, after)
+		})
+	})
+}
+
+func (ts *StockTransferService) Cancel(ctx context.Context, transferId string) error {
+	log.Printf("Cancelling stock transfer [%s]", transferId)
+	return ts.stockTransferRepo.Cancel(ctx, transferId)
+}
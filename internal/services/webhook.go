@@ -0,0 +1,323 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"frappuccino/internal/repo"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	webhookSecretPrefix = "whsec_"
+	minWebhookSecretLen = 16
+	maxWebhookSecretLen = 255
+
+	webhookPollInterval = 5 * time.Second
+	webhookBatchSize    = 50
+	webhookTimeout      = 10 * time.Second
+	// webhookLease must outlast an attempt, see WebhookRepo.ClaimDeliveries
+	webhookLease = time.Minute
+
+	// Attempts are retried after 30s, 1m, 2m, ... up to 6h apart; a delivery
+	// still failing after maxWebhookAttempts is given up.
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = 6 * time.Hour
+	maxWebhookAttempts = 12
+
+	defaultDeliveryLimit = 100
+	maxDeliveryLimit     = 1000
+	maxWebhookErrorLen   = 500
+)
+
+// webhookEventTypes lists the events a subscription can ask for.
+var webhookEventTypes = map[string]bool{
+	models.EventOrderCreated:      true,
+	models.EventOrderCompleted:    true,
+	models.EventInventoryLowStock: true,
+}
+
+var webhookDeliveryStatuses = map[string]bool{
+	"PENDING":   true,
+	"DELIVERED": true,
+	"FAILED":    true,
+}
+
+type WebhookServiceIfc interface {
+	Run(ctx context.Context)
+	HandleEvent(ctx context.Context, published PublishedEvent) error
+	Create(ctx context.Context, subscription *models.WebhookSubscription) (*models.WebhookSubscription, error)
+	GetAll(ctx context.Context) ([]models.WebhookSubscription, error)
+	GetByID(ctx context.Context, subscriptionId string) (models.WebhookSubscription, error)
+	UpdateByID(ctx context.Context, subscription *models.WebhookSubscription) error
+	DeleteByID(ctx context.Context, subscriptionId string) error
+	GetDeliveries(ctx context.Context, subscriptionId string, status string, limit int) ([]models.WebhookDelivery, error)
+}
+
+type WebhookService struct {
+	webhookRepo repo.WebhookRepoIfc
+	client      *http.Client
+}
+
+func NewWebhookService(webhookRepo repo.WebhookRepoIfc) *WebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		client:      &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Create generates a secret unless one is given. The secret is only
+// returned here.
+func (ws *WebhookService) Create(ctx context.Context, subscription *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	if err := validateWebhook(subscription); err != nil {
+		return nil, err
+	}
+	if subscription.Secret == "" {
+		secret, _, err := utils.NewToken(webhookSecretPrefix)
+		if err != nil {
+			return nil, err
+		}
+		subscription.Secret = utils.TEXT(secret)
+	}
+
+	created, err := ws.webhookRepo.Create(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Webhook subscription [%s] created for %s", created.SubscriptionId, created.Url)
+	return created, nil
+}
+
+func (ws *WebhookService) GetAll(ctx context.Context) ([]models.WebhookSubscription, error) {
+	return ws.webhookRepo.GetAll(ctx)
+}
+
+func (ws *WebhookService) GetByID(ctx context.Context, subscriptionId string) (models.WebhookSubscription, error) {
+	return ws.webhookRepo.GetByID(ctx, subscriptionId)
+}
+
+// UpdateByID keeps the secret when none is given.
+func (ws *WebhookService) UpdateByID(ctx context.Context, subscription *models.WebhookSubscription) error {
+	if err := validateWebhook(subscription); err != nil {
+		return err
+	}
+	log.Printf("Updating webhook subscription [%s]", subscription.SubscriptionId)
+	return ws.webhookRepo.UpdateByID(ctx, subscription)
+}
+
+func (ws *WebhookService) DeleteByID(ctx context.Context, subscriptionId string) error {
+	log.Printf("Deleting webhook subscription [%s]", subscriptionId)
+	return ws.webhookRepo.DeleteByID(ctx, subscriptionId)
+}
+
+func (ws *WebhookService) GetDeliveries(ctx context.Context, subscriptionId string, status string, limit int) ([]models.WebhookDelivery, error) {
+	status = strings.ToUpper(status)
+	if status != "" && !webhookDeliveryStatuses[status] {
+		return nil, utils.ErrInvalidDeliveryState
+	}
+	if limit <= 0 {
+		limit = defaultDeliveryLimit
+	}
+	if limit > maxDeliveryLimit {
+		limit = maxDeliveryLimit
+	}
+
+	deliveries, err := ws.webhookRepo.GetDeliveries(ctx, subscriptionId, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+	return deliveries, nil
+}
+
+// HandleEvent turns domain events into webhook events and queues a delivery
+// of each to the subscriptions asking for it. It is subscribed to the event
+// bus, so the deliveries commit together with the progress of the bus.
+func (ws *WebhookService) HandleEvent(ctx context.Context, published PublishedEvent) error {
+	var eventType string
+	var data any
+	switch event := published.Event.(type) {
+	case OrderCreated:
+		if err := ws.enqueue(ctx, published.EventId, models.EventOrderCreated, event.Order); err != nil {
+			return err
+		}
+		if event.Order.OrderStatus != "COMPLETED" {
+			return nil
+		}
+		eventType, data = models.EventOrderCompleted, event.Order
+	case OrderStatusChanged:
+		if event.Order.OrderStatus != "COMPLETED" {
+			return nil
+		}
+		eventType, data = models.EventOrderCompleted, event.Order
+	case StockAdjusted:
+		if !event.FellToReorderLevel() {
+			return nil
+		}
+		eventType, data = models.EventInventoryLowStock, models.LowStock{
+			IngredientId:   event.IngredientId,
+			IngredientName: event.IngredientName,
+			Unit:           event.Unit,
+			LocationId:     event.LocationId,
+			Quantity:       event.Quantity,
+			ReorderLevel:   event.ReorderLevel,
+		}
+	default:
+		return nil
+	}
+	return ws.enqueue(ctx, published.EventId, eventType, data)
+}
+
+func (ws *WebhookService) enqueue(ctx context.Context, eventId int64, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return ws.webhookRepo.Enqueue(ctx, eventId, eventType, payload)
+}
+
+// Run sends the due deliveries until ctx is done.
+func (ws *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		jobs, err := ws.webhookRepo.ClaimDeliveries(ctx, webhookBatchSize, webhookLease)
+		if err != nil {
+			log.Println("Error claiming webhook deliveries:", err)
+		}
+		for _, job := range jobs {
+			ws.deliver(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliver sends one event and records the outcome. Any 2xx response counts
+// as delivered.
+func (ws *WebhookService) deliver(ctx context.Context, job models.WebhookJob) {
+	deliveryId := string(job.Delivery.DeliveryId)
+
+	body, err := json.Marshal(job.Event)
+	if err != nil {
+		ws.fail(ctx, job, nil, err.Error())
+		return
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, string(job.Url), bytes.NewReader(body))
+	if err != nil {
+		ws.fail(ctx, job, nil, err.Error())
+		return
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "frappuccino-webhooks")
+	request.Header.Set("X-Webhook-Id", deliveryId)
+	request.Header.Set("X-Webhook-Event", string(job.Event.EventType))
+	request.Header.Set("X-Webhook-Timestamp", timestamp)
+	request.Header.Set("X-Webhook-Signature", "sha256="+signWebhook(string(job.Secret), timestamp, body))
+
+	response, err := ws.client.Do(request)
+	if err != nil {
+		ws.fail(ctx, job, nil, err.Error())
+		return
+	}
+	defer response.Body.Close()
+	excerpt, _ := io.ReadAll(io.LimitReader(response.Body, maxWebhookErrorLen))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		status := response.StatusCode
+		ws.fail(ctx, job, &status, fmt.Sprintf("%s: %s", response.Status, excerpt))
+		return
+	}
+	if err := ws.webhookRepo.MarkDelivered(ctx, deliveryId, response.StatusCode); err != nil {
+		log.Printf("Error recording webhook delivery [%s]: %v", deliveryId, err)
+	}
+}
+
+// fail schedules the next attempt with exponential backoff, or gives the
+// delivery up after maxWebhookAttempts.
+func (ws *WebhookService) fail(ctx context.Context, job models.WebhookJob, responseStatus *int, reason string) {
+	deliveryId := string(job.Delivery.DeliveryId)
+	if len(reason) > maxWebhookErrorLen {
+		reason = reason[:maxWebhookErrorLen]
+	}
+
+	var retryAt *time.Time
+	attempts := int(job.Delivery.Attempts)
+	if attempts < maxWebhookAttempts {
+		backoff := webhookBaseBackoff << (attempts - 1)
+		if backoff > webhookMaxBackoff || backoff <= 0 {
+			backoff = webhookMaxBackoff
+		}
+		next := time.Now().Add(backoff)
+		retryAt = &next
+		log.Printf("Webhook delivery [%s] attempt %d failed, retrying at %s: %s", deliveryId, attempts, next.Format(time.RFC3339), reason)
+	} else {
+		log.Printf("Webhook delivery [%s] given up after %d attempts: %s", deliveryId, attempts, reason)
+	}
+
+	if err := ws.webhookRepo.MarkFailed(ctx, deliveryId, responseStatus, reason, retryAt); err != nil {
+		log.Printf("Error recording webhook delivery [%s]: %v", deliveryId, err)
+	}
+}
+
+// signWebhook returns the hex HMAC-SHA256 of "timestamp.body". Receivers
+// recompute it with their secret and reject old timestamps to stop replays.
+func signWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validateWebhook trims the fields and removes duplicate event types.
+func validateWebhook(subscription *models.WebhookSubscription) error {
+	subscription.Url = utils.TEXT(strings.TrimSpace(string(subscription.Url)))
+	target, err := url.Parse(string(subscription.Url))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return utils.ErrInvalidWebhookUrl
+	}
+
+	seen := map[string]bool{}
+	var eventTypes utils.TEXTARR
+	for _, eventType := range subscription.EventTypes {
+		eventType = strings.ToLower(strings.TrimSpace(eventType))
+		if !webhookEventTypes[eventType] {
+			return utils.ErrInvalidWebhookEvents
+		}
+		if !seen[eventType] {
+			seen[eventType] = true
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	if len(eventTypes) == 0 {
+		return utils.ErrInvalidWebhookEvents
+	}
+	subscription.EventTypes = eventTypes
+
+	subscription.Secret = utils.TEXT(strings.TrimSpace(string(subscription.Secret)))
+	if subscription.Secret != "" && (len(subscription.Secret) < minWebhookSecretLen || len(subscription.Secret) > maxWebhookSecretLen) {
+		return utils.ErrInvalidWebhookSecret
+	}
+	return nil
+}
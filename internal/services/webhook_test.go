@@ -0,0 +1,40 @@
+package services
+
+import "testing"
+
+func TestSignWebhook(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		want      string
+	}{
+		{
+			name:      "signs timestamp and body",
+			secret:    "secret",
+			timestamp: "1700000000",
+			body:      `{"a":1}`,
+			want:      "49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686",
+		},
+		{
+			name:      "empty secret and body",
+			secret:    "",
+			timestamp: "0",
+			body:      "",
+			want:      "b849d5a581847b281957065739df36df2463d1977ea8d6e1e4e6cf33fadc68c3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := signWebhook(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+				t.Errorf("signWebhook() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// The timestamp is part of what is signed, so replays with a new one fail
+	if signWebhook("secret", "1700000000", []byte("{}")) == signWebhook("secret", "1700000001", []byte("{}")) {
+		t.Error("signWebhook() does not depend on the timestamp")
+	}
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // TotalSales
 type TotalSales struct {
 	Value float64 `json:"total_sales"`
@@ -7,12 +9,31 @@ type TotalSales struct {
 
 // Popular Items
 type PopularItems struct {
-	Items []PopularItem `json:"popular_items"`
+	From     string        `json:"from,omitempty"`
+	To       string        `json:"to,omitempty"`
+	Category string        `json:"category,omitempty"`
+	Metric   string        `json:"metric"`
+	Items    []PopularItem `json:"popular_items"`
 }
 
 type PopularItem struct {
-	ItemName     string `json:"item_name"`
-	OrderedTimes int    `json:"ordered_times"`
+	MenuItemId   string  `json:"menu_item_id"`
+	ItemName     string  `json:"item_name"`
+	OrderedTimes int     `json:"ordered_times"`
+	UnitsSold    float64 `json:"units_sold"`
+	Revenue      float64 `json:"revenue"`
+	Customers    int     `json:"distinct_customers"`
+	Rank         int     `json:"rank"`
+	PreviousRank *int    `json:"previous_rank,omitempty"`
+	RankChange   *int    `json:"rank_change,omitempty"`
+}
+
+type PopularItemsFilter struct {
+	From     *time.Time
+	To       *time.Time
+	Category string
+	Metric   string
+	Limit    int
 }
 
 // Search
@@ -48,3 +69,122 @@ type OrderedItemByPeriod struct {
 	Date  string
 	Count int
 }
+
+// SalesReport
+type SalesReport struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Bucket   string        `json:"bucket"`
+	Timezone string        `json:"timezone"`
+	GroupBy  string        `json:"group_by,omitempty"`
+	Buckets  []SalesBucket `json:"buckets"`
+}
+
+type SalesBucket struct {
+	BucketStart string `json:"bucket_start"`
+	Group       string `json:"group,omitempty"`
+	// GroupName is shown for groups keyed by an id, such as menu items
+	GroupName     string  `json:"group_name,omitempty"`
+	GrossRevenue  float64 `json:"gross_revenue"`
+	OrderCount    int     `json:"order_count"`
+	AverageTicket float64 `json:"average_ticket"`
+	ItemCount     float64 `json:"item_count"`
+}
+
+// TipReport
+type TipReport struct {
+	From      string         `json:"from"`
+	To        string         `json:"to"`
+	Timezone  string         `json:"timezone"`
+	TotalTips float64        `json:"total_tips"`
+	ByStaff   []TipTotal     `json:"by_staff"`
+	ByDay     []TipTotal     `json:"by_day"`
+	Rows      []TipReportRow `json:"rows"`
+}
+
+type TipTotal struct {
+	Key          string  `json:"key"`
+	Name         string  `json:"name,omitempty"`
+	Tips         float64 `json:"tips"`
+	PaymentCount int     `json:"payment_count"`
+}
+
+type TipReportRow struct {
+	Day          string  `json:"day"`
+	StaffId      string  `json:"staff_id"`
+	StaffName    string  `json:"staff_name"`
+	Tips         float64 `json:"tips"`
+	PaymentCount int     `json:"payment_count"`
+}
+
+// Heatmap
+type Heatmap struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Timezone string        `json:"timezone"`
+	Cells    []HeatmapCell `json:"cells"`
+}
+
+type HeatmapCell struct {
+	Weekday        int      `json:"weekday"`
+	WeekdayName    string   `json:"weekday_name"`
+	Hour           int      `json:"hour"`
+	OrderCount     int      `json:"order_count"`
+	Revenue        float64  `json:"revenue"`
+	AvgPrepSeconds *float64 `json:"avg_prep_seconds"`
+}
+
+// PrepTimeReport
+type PrepTimeReport struct {
+	From       string            `json:"from"`
+	To         string            `json:"to"`
+	Timezone   string            `json:"timezone"`
+	FromStatus string            `json:"from_status"`
+	ToStatus   string            `json:"to_status"`
+	Threshold  string            `json:"threshold"`
+	Overall    PrepTimeStats     `json:"overall"`
+	ByMenuItem []PrepTimeStats   `json:"by_menu_item"`
+	ByHour     []PrepTimeStats   `json:"by_hour"`
+	ByDay      []PrepTimeStats   `json:"by_day"`
+	Outliers   []PrepTimeOutlier `json:"outliers"`
+}
+
+type PrepTimeStats struct {
+	Key        string  `json:"key,omitempty"`
+	OrderCount int     `json:"order_count"`
+	AvgSeconds float64 `json:"avg_seconds"`
+	P50Seconds float64 `json:"p50_seconds"`
+	P90Seconds float64 `json:"p90_seconds"`
+	P99Seconds float64 `json:"p99_seconds"`
+}
+
+type PrepTimeOutlier struct {
+	OrderId         string  `json:"order_id"`
+	CustomerId      string  `json:"customer_id"`
+	CreatedAt       string  `json:"created_at"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// InventoryVariance
+type InventoryVarianceReport struct {
+	From              string               `json:"from"`
+	To                string               `json:"to"`
+	TotalVarianceCost float64              `json:"total_variance_cost"`
+	Items             []IngredientVariance `json:"items"`
+}
+
+type IngredientVariance struct {
+	IngredientId     string  `json:"ingredient_id"`
+	IngredientName   string  `json:"ingredient_name"`
+	Unit             string  `json:"unit"`
+	UnitCost         float64 `json:"unit_cost"`
+	TheoreticalUsage float64 `json:"theoretical_usage"`
+	RecordedSales    float64 `json:"recorded_sales"`
+	Waste            float64 `json:"waste"`
+	Spoilage         float64 `json:"spoilage"`
+	Theft            float64 `json:"theft"`
+	Adjustments      float64 `json:"adjustments"`
+	ActualUsage      float64 `json:"actual_usage"`
+	VarianceUnits    float64 `json:"variance_units"`
+	VarianceCost     float64 `json:"variance_cost"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "frappuccino/utils"
+
+// AuditEntry records one change made through the API. Before is empty for
+// created resources and After for deleted ones.
+type AuditEntry struct {
+	AuditId       utils.TEXT  `json:"audit_id"`
+	ActorStaffId  *utils.TEXT `json:"actor_staff_id"`
+	ActorApiKeyId *utils.TEXT `json:"actor_api_key_id"`
+	ActorName     utils.TEXT  `json:"actor_name"`
+	Method        utils.TEXT  `json:"method"`
+	Route         utils.TEXT  `json:"route"`
+	Resource      utils.TEXT  `json:"resource"`
+	ResourceId    *utils.TEXT `json:"resource_id"`
+	Before        utils.JSONB `json:"before"`
+	After         utils.JSONB `json:"after"`
+	CreatedAt     utils.TIME  `json:"created_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "frappuccino/utils"
+
+type LoginRequest struct {
+	Username utils.TEXT `json:"username"`
+	Password utils.TEXT `json:"password"`
+}
+
+type AuthSession struct {
+	Token     utils.TEXT `json:"token"`
+	TokenType utils.TEXT `json:"token_type"`
+	ExpiresAt utils.TIME `json:"expires_at"`
+	Staff     Staff      `json:"staff"`
+}
+
+// APIKey is an integration credential. Key holds the secret only in the
+// response that creates it; afterwards only KeyPrefix identifies it.
+type APIKey struct {
+	ApiKeyId   utils.TEXT  `json:"api_key_id"`
+	KeyName    utils.TEXT  `json:"key_name"`
+	KeyPrefix  utils.TEXT  `json:"key_prefix"`
+	KeyRole    utils.TEXT  `json:"key_role"`
+	Key        utils.TEXT  `json:"key,omitempty"`
+	CreatedBy  *utils.TEXT `json:"created_by"`
+	CreatedAt  utils.TIME  `json:"created_at"`
+	LastUsedAt *utils.TIME `json:"last_used_at"`
+	RevokedAt  *utils.TIME `json:"revoked_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "frappuccino/utils"
+
+// IdempotencyKey is a request made with an Idempotency-Key header. StatusCode
+// is nil until the response has been stored.
+type IdempotencyKey struct {
+	Scope        utils.TEXT `json:"scope"`
+	Key          utils.TEXT `json:"idempotency_key"`
+	Route        utils.TEXT `json:"route"`
+	RequestHash  utils.TEXT `json:"request_hash"`
+	StatusCode   *int       `json:"status_code"`
+	ContentType  utils.TEXT `json:"content_type"`
+	ResponseBody []byte     `json:"response_body"`
+	CreatedAt    utils.TIME `json:"created_at"`
+	ExpiresAt    utils.TIME `json:"expires_at"`
+}
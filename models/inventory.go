@@ -8,6 +8,7 @@ type Inventory struct {
 	Unit           utils.TEXT `json:"unit"`
 	Quantity       utils.DEC  `json:"quantity"`
 	ReorderLevel   utils.DEC  `json:"reorder_level"`
+	UnitCost       utils.DEC  `json:"unit_cost"`
 	CreatedAt      utils.TIME `json:"created_at"`
 	UpdatedAt      utils.TIME `json:"updated_at"`
 }
@@ -17,9 +18,28 @@ type InventoryTransactions struct {
 	IngredientId               utils.TEXT `json:"ingredient_id"`
 	InventoryTransactionAction utils.TEXT `json:"inventory_transaction_action"`
 	Quantity                   utils.DEC  `json:"quantity"`
+	ReferenceId                utils.TEXT `json:"reference_id"`
+	Notes                      utils.TEXT `json:"notes"`
 	CreatedAt                  utils.TIME `json:"created_at"`
 }
 
+type InventoryBatch struct {
+	BatchId           utils.TEXT  `json:"batch_id"`
+	IngredientId      utils.TEXT  `json:"ingredient_id"`
+	IngredientName    utils.TEXT  `json:"ingredient_name"`
+	QuantityReceived  utils.DEC   `json:"quantity_received"`
+	QuantityRemaining utils.DEC   `json:"quantity_remaining"`
+	ExpiresAt         *utils.TIME `json:"expires_at"`
+	Notes             utils.TEXT  `json:"notes"`
+	ReceivedAt        utils.TIME  `json:"received_at"`
+}
+
+type WasteRecord struct {
+	Quantity utils.DEC  `json:"quantity"`
+	Reason   utils.TEXT `json:"reason"`
+	Notes    utils.TEXT `json:"notes"`
+}
+
 type Page struct {
 	CurrentPage int  `json:"current_page"`
 	HasNextPage bool `json:"has_next_page"`
@@ -32,3 +52,46 @@ type Data struct {
 	Name     string  `json:"name"`
 	Quantity float64 `json:"quantity"`
 }
+
+type IngredientUsage struct {
+	IngredientId   string
+	IngredientName string
+	Unit           string
+	Quantity       float64
+	ReorderLevel   float64
+	// WeekdayTotals holds the quantity removed per ISO weekday, Monday first.
+	WeekdayTotals [7]float64
+}
+
+type InventoryForecast struct {
+	GeneratedAt  string               `json:"generated_at"`
+	HistoryDays  int                  `json:"history_days"`
+	LeadTimeDays int                  `json:"lead_time_days"`
+	Items        []IngredientForecast `json:"items"`
+}
+
+type IngredientForecast struct {
+	IngredientId     string     `json:"ingredient_id"`
+	IngredientName   string     `json:"ingredient_name"`
+	Unit             string     `json:"unit"`
+	Quantity         float64    `json:"quantity"`
+	ReorderLevel     float64    `json:"reorder_level"`
+	AvgDailyUsage    float64    `json:"avg_daily_usage"`
+	WeekdayUsage     [7]float64 `json:"weekday_usage"`
+	DaysOfStock      *float64   `json:"days_of_stock"`
+	StockoutDate     string     `json:"stockout_date,omitempty"`
+	ReorderBy        string     `json:"reorder_by,omitempty"`
+	SuggestedReorder float64    `json:"suggested_reorder"`
+}
+
+// StockChange is a change of Change to the stock of an ingredient at a
+// location; Quantity is the stock after it.
+type StockChange struct {
+	IngredientId   utils.TEXT `json:"ingredient_id"`
+	IngredientName utils.TEXT `json:"ingredient_name"`
+	Unit           utils.TEXT `json:"unit"`
+	LocationId     utils.TEXT `json:"location_id"`
+	Change         utils.DEC  `json:"change"`
+	Quantity       utils.DEC  `json:"quantity"`
+	ReorderLevel   utils.DEC  `json:"reorder_level"`
+}
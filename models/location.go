@@ -0,0 +1,23 @@
+package models
+
+import "frappuccino/utils"
+
+type Location struct {
+	LocationId   utils.TEXT `json:"location_id"`
+	LocationName utils.TEXT `json:"location_name"`
+	Address      utils.TEXT `json:"address"`
+	Timezone     utils.TEXT `json:"timezone"`
+	CreatedAt    utils.TIME `json:"created_at"`
+	UpdatedAt    utils.TIME `json:"updated_at"`
+}
+
+// LocationMenuPrice is a menu item as offered at one location. Price is nil
+// when the location sells the item at its base price.
+type LocationMenuPrice struct {
+	LocationId  utils.TEXT `json:"location_id"`
+	MenuItemId  utils.TEXT `json:"menu_item_id"`
+	ItemName    utils.TEXT `json:"item_name"`
+	BasePrice   utils.DEC  `json:"base_price"`
+	Price       *utils.DEC `json:"price"`
+	IsAvailable bool       `json:"is_available"`
+}
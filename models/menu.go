@@ -8,9 +8,11 @@ type MenuItems struct {
 	ItemDescription utils.TEXT    `json:"item_description"`
 	Price           utils.DEC     `json:"price"`
 	Categories      utils.TEXTARR `json:"categories"`
-	Ingredients     []Ingredients
-	CreatedAt       utils.TIME `json:"created_at"`
-	UpdatedAt       utils.TIME `json:"updated_at"`
+	// StationId is where the item is prepared; see StationTicket.
+	StationId   *utils.TEXT `json:"station_id"`
+	Ingredients []Ingredients
+	CreatedAt   utils.TIME `json:"created_at"`
+	UpdatedAt   utils.TIME `json:"updated_at"`
 }
 
 type MenuItemsIngredients struct {
@@ -45,3 +47,10 @@ func (m *MenuItems) Unmarshal(menu *MenuItems) {
 	m.CreatedAt = menu.CreatedAt
 	m.UpdatedAt = menu.UpdatedAt
 }
+
+type MenuSuggestion struct {
+	MenuItemId string   `json:"menu_item_id"`
+	ItemName   string   `json:"item_name"`
+	Categories []string `json:"categories"`
+	Score      float64  `json:"score"`
+}
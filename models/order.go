@@ -18,13 +18,16 @@ import "frappuccino/utils"
 // )
 
 type Orders struct {
-	OrderId             utils.TEXT `json:"order_id"`
-	CustomerId          utils.TEXT `json:"customer_id"`
-	SpecialInstructions utils.TEXT `json:"special_instructions"`
-	TotalPrice          utils.DEC  `json:"total_price"`
-	OrderStatus         utils.TEXT `json:"order_status"`
-	PaymentMethod       utils.TEXT `json:"payment_method"`
+	OrderId             utils.TEXT  `json:"order_id"`
+	LocationId          utils.TEXT  `json:"location_id"`
+	CustomerId          utils.TEXT  `json:"customer_id"`
+	SpecialInstructions utils.JSONB `json:"special_instructions"`
+	TotalPrice          utils.DEC   `json:"total_price"`
+	OrderStatus         utils.TEXT  `json:"order_status"`
+	PaymentMethod       utils.TEXT  `json:"payment_method"`
+	StaffId             *utils.TEXT `json:"staff_id"`
 	OrderItems          []OrderItems
+	Payments            []Payment  `json:"payments,omitempty"`
 	CreatedAt           utils.TIME `json:"created_at"`
 	UpdatedAt           utils.TIME `json:"updated_at"`
 }
@@ -47,7 +50,8 @@ type OrderStatusHistory struct {
 	UpdatedAt            utils.TIME `json:"updated_at"`
 }
 
+// OrderedItem is how many units of a menu item were ordered.
 type OrderedItem struct {
 	Name  string
-	Count int
+	Count utils.DEC
 }
@@ -0,0 +1,16 @@
+package models
+
+import "frappuccino/utils"
+
+// OrderEvent is an entry of the order stream: an order was created or its
+// status changed.
+type OrderEvent struct {
+	EventId        int64       `json:"event_id"`
+	EventType      utils.TEXT  `json:"event_type"`
+	OrderId        utils.TEXT  `json:"order_id"`
+	LocationId     utils.TEXT  `json:"location_id"`
+	OrderStatus    utils.TEXT  `json:"order_status"`
+	PreviousStatus *utils.TEXT `json:"previous_status"`
+	Notes          utils.TEXT  `json:"notes"`
+	CreatedAt      utils.TIME  `json:"created_at"`
+}
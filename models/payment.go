@@ -0,0 +1,24 @@
+package models
+
+import "frappuccino/utils"
+
+type Payment struct {
+	PaymentId     utils.TEXT  `json:"payment_id"`
+	OrderId       utils.TEXT  `json:"order_id"`
+	PaymentMethod utils.TEXT  `json:"payment_method"`
+	Amount        utils.DEC   `json:"amount"`
+	Tip           utils.DEC   `json:"tip"`
+	StaffId       *utils.TEXT `json:"staff_id"`
+	CreatedAt     utils.TIME  `json:"created_at"`
+}
+
+// OrderPayments is the payment state of an order. Balance is what is still
+// owed on total_price; tips are not part of it.
+type OrderPayments struct {
+	OrderId    utils.TEXT `json:"order_id"`
+	TotalPrice utils.DEC  `json:"total_price"`
+	Paid       utils.DEC  `json:"paid"`
+	Balance    utils.DEC  `json:"balance"`
+	Tips       utils.DEC  `json:"tips"`
+	Payments   []Payment  `json:"payments"`
+}
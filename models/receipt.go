@@ -0,0 +1,53 @@
+package models
+
+import "frappuccino/utils"
+
+// ShopHeader is printed at the top of every receipt. Prices include tax at
+// TaxRate percent; with a zero rate no tax is shown.
+type ShopHeader struct {
+	Name     string  `json:"name"`
+	Address  string  `json:"address"`
+	Phone    string  `json:"phone"`
+	TaxId    string  `json:"tax_id"`
+	Footer   string  `json:"footer"`
+	Currency string  `json:"currency"`
+	TaxName  string  `json:"tax_name"`
+	TaxRate  float64 `json:"tax_rate"`
+}
+
+// Receipt is an order laid out for the customer.
+type Receipt struct {
+	Shop          ShopHeader    `json:"shop"`
+	OrderId       utils.TEXT    `json:"order_id"`
+	OrderStatus   utils.TEXT    `json:"order_status"`
+	LocationName  utils.TEXT    `json:"location_name"`
+	CustomerName  utils.TEXT    `json:"customer_name"`
+	CustomerEmail utils.TEXT    `json:"customer_email"`
+	OrderedAt     utils.TIME    `json:"ordered_at"`
+	Lines         []ReceiptLine `json:"lines"`
+	Subtotal      utils.DEC     `json:"subtotal"`
+	// Discount is what the order total is below the sum of its lines.
+	Discount utils.DEC    `json:"discount"`
+	Total    utils.DEC    `json:"total"`
+	Taxes    []ReceiptTax `json:"taxes"`
+	Payments []Payment    `json:"payments"`
+	Paid     utils.DEC    `json:"paid"`
+	Tips     utils.DEC    `json:"tips"`
+	Balance  utils.DEC    `json:"balance"`
+	Refunded utils.DEC    `json:"refunded"`
+}
+
+type ReceiptLine struct {
+	ItemName       utils.TEXT `json:"item_name"`
+	Customizations []string   `json:"customizations"`
+	Quantity       utils.DEC  `json:"quantity"`
+	UnitPrice      utils.DEC  `json:"unit_price"`
+	Amount         utils.DEC  `json:"amount"`
+}
+
+// ReceiptTax is the tax included in the total.
+type ReceiptTax struct {
+	Name   string    `json:"name"`
+	Rate   float64   `json:"rate"`
+	Amount utils.DEC `json:"amount"`
+}
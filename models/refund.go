@@ -0,0 +1,35 @@
+package models
+
+import "frappuccino/utils"
+
+type Refund struct {
+	RefundId  utils.TEXT   `json:"refund_id"`
+	OrderId   utils.TEXT   `json:"order_id"`
+	Reason    utils.TEXT   `json:"reason"`
+	Amount    utils.DEC    `json:"amount"`
+	Restocked bool         `json:"restocked"`
+	StaffId   *utils.TEXT  `json:"staff_id"`
+	Lines     []RefundLine `json:"lines"`
+	CreatedAt utils.TIME   `json:"created_at"`
+}
+
+type RefundLine struct {
+	RefundLineId utils.TEXT `json:"refund_line_id"`
+	OrderItemId  utils.TEXT `json:"order_item_id"`
+	ItemName     utils.TEXT `json:"item_name"`
+	Quantity     utils.DEC  `json:"quantity"`
+	Amount       utils.DEC  `json:"amount"`
+}
+
+// RefundRequest refunds the listed order lines, or everything not refunded
+// yet when Items is empty. Restock puts the ingredients back into stock.
+type RefundRequest struct {
+	Reason  utils.TEXT   `json:"reason"`
+	Restock bool         `json:"restock"`
+	Items   []RefundItem `json:"items"`
+}
+
+type RefundItem struct {
+	OrderItemId utils.TEXT `json:"order_item_id"`
+	Quantity    utils.DEC  `json:"quantity"`
+}
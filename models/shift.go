@@ -0,0 +1,20 @@
+package models
+
+import "frappuccino/utils"
+
+type Shift struct {
+	ShiftId    utils.TEXT  `json:"shift_id"`
+	StaffId    utils.TEXT  `json:"staff_id"`
+	StaffName  utils.TEXT  `json:"staff_name"`
+	LocationId utils.TEXT  `json:"location_id"`
+	StartsAt   utils.TIME  `json:"starts_at"`
+	EndsAt     utils.TIME  `json:"ends_at"`
+	ClockInAt  *utils.TIME `json:"clock_in_at"`
+	ClockOutAt *utils.TIME `json:"clock_out_at"`
+	// WorkedHours is the time between clock-in and clock-out, or up to now
+	// while the shift is still clocked in.
+	WorkedHours utils.DEC  `json:"worked_hours"`
+	Notes       utils.TEXT `json:"notes"`
+	CreatedAt   utils.TIME `json:"created_at"`
+	UpdatedAt   utils.TIME `json:"updated_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "frappuccino/utils"
+
+type Staff struct {
+	StaffId   utils.TEXT `json:"staff_id"`
+	FullName  utils.TEXT `json:"full_name"`
+	Username  utils.TEXT `json:"username"`
+	Password  utils.TEXT `json:"password,omitempty"`
+	StaffRole utils.TEXT `json:"staff_role"`
+	IsActive  bool       `json:"is_active"`
+	CreatedAt utils.TIME `json:"created_at"`
+	UpdatedAt utils.TIME `json:"updated_at"`
+}
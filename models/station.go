@@ -0,0 +1,33 @@
+package models
+
+import "frappuccino/utils"
+
+type Station struct {
+	StationId   utils.TEXT `json:"station_id"`
+	StationName utils.TEXT `json:"station_name"`
+	CreatedAt   utils.TIME `json:"created_at"`
+	UpdatedAt   utils.TIME `json:"updated_at"`
+}
+
+// StationTicket is what one station has to prepare for an order.
+type StationTicket struct {
+	TicketId            utils.TEXT          `json:"ticket_id"`
+	OrderId             utils.TEXT          `json:"order_id"`
+	StationId           utils.TEXT          `json:"station_id"`
+	LocationId          utils.TEXT          `json:"location_id"`
+	TicketStatus        utils.TEXT          `json:"ticket_status"`
+	SpecialInstructions utils.JSONB         `json:"special_instructions"`
+	Items               []StationTicketItem `json:"items"`
+	BumpedAt            *utils.TIME         `json:"bumped_at"`
+	BumpedBy            *utils.TEXT         `json:"bumped_by"`
+	CreatedAt           utils.TIME          `json:"created_at"`
+}
+
+type StationTicketItem struct {
+	OrderItemId    utils.TEXT  `json:"order_item_id"`
+	MenuItemId     utils.TEXT  `json:"menu_item_id"`
+	ItemName       utils.TEXT  `json:"item_name"`
+	Quantity       utils.DEC   `json:"quantity"`
+	Customizations utils.JSONB `json:"customizations"`
+	BumpedAt       *utils.TIME `json:"bumped_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "frappuccino/utils"
+
+type StockCount struct {
+	StockCountId     utils.TEXT       `json:"stock_count_id"`
+	LocationId       utils.TEXT       `json:"location_id"`
+	StockCountStatus utils.TEXT       `json:"stock_count_status"`
+	OpenedBy         utils.TEXT       `json:"opened_by"`
+	Notes            utils.TEXT       `json:"notes"`
+	Lines            []StockCountLine `json:"lines,omitempty"`
+	CreatedAt        utils.TIME       `json:"created_at"`
+	ClosedAt         *utils.TIME      `json:"closed_at"`
+}
+
+type StockCountLine struct {
+	StockCountLineId utils.TEXT `json:"stock_count_line_id"`
+	IngredientId     utils.TEXT `json:"ingredient_id"`
+	IngredientName   utils.TEXT `json:"ingredient_name"`
+	CountedQuantity  utils.DEC  `json:"counted_quantity"`
+	// ExpectedQuantity is the live stock while the count is open and the
+	// stock on record at commit time afterwards.
+	ExpectedQuantity utils.DEC  `json:"expected_quantity"`
+	Variance         utils.DEC  `json:"variance"`
+	CountedBy        utils.TEXT `json:"counted_by"`
+	CountedAt        utils.TIME `json:"counted_at"`
+}
+
+type StockCountSubmission struct {
+	CountedBy utils.TEXT             `json:"counted_by"`
+	Items     []StockCountedQuantity `json:"items"`
+}
+
+type StockCountedQuantity struct {
+	IngredientId    utils.TEXT `json:"ingredient_id"`
+	CountedQuantity utils.DEC  `json:"counted_quantity"`
+}
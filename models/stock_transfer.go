@@ -0,0 +1,41 @@
+package models
+
+import "frappuccino/utils"
+
+type StockTransfer struct {
+	TransferId     utils.TEXT          `json:"transfer_id"`
+	FromLocationId utils.TEXT          `json:"from_location_id"`
+	ToLocationId   utils.TEXT          `json:"to_location_id"`
+	TransferStatus utils.TEXT          `json:"transfer_status"`
+	CreatedBy      utils.TEXT          `json:"created_by"`
+	ReceivedBy     utils.TEXT          `json:"received_by"`
+	Notes          utils.TEXT          `json:"notes"`
+	Items          []StockTransferLine `json:"items"`
+	CreatedAt      utils.TIME          `json:"created_at"`
+	ShippedAt      *utils.TIME         `json:"shipped_at"`
+	ReceivedAt     *utils.TIME         `json:"received_at"`
+}
+
+type StockTransferLine struct {
+	TransferLineId   utils.TEXT `json:"transfer_line_id"`
+	IngredientId     utils.TEXT `json:"ingredient_id"`
+	IngredientName   utils.TEXT `json:"ingredient_name"`
+	QuantitySent     utils.DEC  `json:"quantity_sent"`
+	QuantityReceived *utils.DEC `json:"quantity_received"`
+	// Discrepancy is quantity_sent minus quantity_received: positive when
+	// stock was lost in transit, negative when more arrived than was sent.
+	Discrepancy *utils.DEC `json:"discrepancy"`
+}
+
+// StockTransferReceipt confirms what arrived at the destination. Items that
+// are not listed are taken as received in full.
+type StockTransferReceipt struct {
+	ReceivedBy utils.TEXT                 `json:"received_by"`
+	Notes      utils.TEXT                 `json:"notes"`
+	Items      []StockTransferReceiptLine `json:"items"`
+}
+
+type StockTransferReceiptLine struct {
+	IngredientId     utils.TEXT `json:"ingredient_id"`
+	QuantityReceived utils.DEC  `json:"quantity_received"`
+}
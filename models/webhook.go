@@ -0,0 +1,67 @@
+package models
+
+import "frappuccino/utils"
+
+// Event types that webhook subscriptions can ask for.
+const (
+	EventOrderCreated      = "order.created"
+	EventOrderCompleted    = "order.completed"
+	EventInventoryLowStock = "inventory.low_stock"
+)
+
+// WebhookSubscription is an endpoint of another system. Secret is only
+// returned when the subscription is created.
+type WebhookSubscription struct {
+	SubscriptionId utils.TEXT    `json:"subscription_id"`
+	Url            utils.TEXT    `json:"url"`
+	EventTypes     utils.TEXTARR `json:"event_types"`
+	Secret         utils.TEXT    `json:"secret,omitempty"`
+	IsActive       bool          `json:"is_active"`
+	CreatedAt      utils.TIME    `json:"created_at"`
+	UpdatedAt      utils.TIME    `json:"updated_at"`
+}
+
+// WebhookDelivery is one event sent, or still to be sent, to a subscription.
+// NextAttemptAt is nil once the delivery succeeded or was given up.
+type WebhookDelivery struct {
+	DeliveryId     utils.TEXT  `json:"delivery_id"`
+	SubscriptionId utils.TEXT  `json:"subscription_id"`
+	EventId        int64       `json:"event_id"`
+	EventType      utils.TEXT  `json:"event_type"`
+	DeliveryStatus utils.TEXT  `json:"delivery_status"`
+	Attempts       utils.INT   `json:"attempts"`
+	NextAttemptAt  *utils.TIME `json:"next_attempt_at"`
+	ResponseStatus *utils.INT  `json:"response_status"`
+	LastError      utils.TEXT  `json:"last_error"`
+	DeliveredAt    *utils.TIME `json:"delivered_at"`
+	CreatedAt      utils.TIME  `json:"created_at"`
+	UpdatedAt      utils.TIME  `json:"updated_at"`
+}
+
+// OutboxEvent is a committed change waiting to be told to other systems.
+type OutboxEvent struct {
+	EventId    int64       `json:"event_id"`
+	EventType  utils.TEXT  `json:"event_type"`
+	LocationId *utils.TEXT `json:"location_id"`
+	Payload    utils.JSONB `json:"data"`
+	CreatedAt  utils.TIME  `json:"created_at"`
+}
+
+// WebhookJob is a due delivery together with what is needed to send it.
+type WebhookJob struct {
+	Delivery WebhookDelivery
+	Url      utils.TEXT
+	Secret   utils.TEXT
+	Event    OutboxEvent
+}
+
+// LowStock is the payload of inventory.low_stock: the stock of an ingredient
+// at a location has fallen to its reorder level.
+type LowStock struct {
+	IngredientId   utils.TEXT `json:"ingredient_id"`
+	IngredientName utils.TEXT `json:"ingredient_name"`
+	Unit           utils.TEXT `json:"unit"`
+	LocationId     utils.TEXT `json:"location_id"`
+	Quantity       utils.DEC  `json:"quantity"`
+	ReorderLevel   utils.DEC  `json:"reorder_level"`
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const (
+	RoleBarista = "BARISTA"
+	RoleManager = "MANAGER"
+	RoleAdmin   = "ADMIN"
+)
+
+// roleRanks orders the staff roles; a role may do everything a lower one can.
+var roleRanks = map[string]int{
+	RoleBarista: 1,
+	RoleManager: 2,
+	RoleAdmin:   3,
+}
+
+func IsRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAllows reports whether a caller with role have may use a route that
+// requires role need.
+func RoleAllows(have string, need string) bool {
+	return IsRole(have) && roleRanks[have] >= roleRanks[need]
+}
+
+// Principal is the authenticated caller: a staff member with a session or an
+// integration with an API key.
+type Principal struct {
+	StaffId  string
+	ApiKeyId string
+	Name     string
+	Role     string
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+type routeKey struct{}
+
+// WithRoute stores the mux pattern ("PUT /menu/{id}") that matched the request.
+func WithRoute(ctx context.Context, pattern string) context.Context {
+	return context.WithValue(ctx, routeKey{}, pattern)
+}
+
+func RouteFrom(ctx context.Context) string {
+	pattern, _ := ctx.Value(routeKey{}).(string)
+	return pattern
+}
+
+// BearerToken returns the token of an "Authorization: Bearer" header.
+func BearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
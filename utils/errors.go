@@ -10,11 +10,103 @@ var (
 	ErrIdNotFound     = errors.New("ID not found")
 	ErrConflictFields = errors.New("Conflict duplicate fields")
 	ErrMenuItem       = errors.New("Menu Item does not exist")
+	ErrNotArchived    = errors.New("record is not archived")
+
+	ErrUnknownIngredient = errors.New("ingredient does not exist")
+	ErrOrderClosed       = errors.New("only PENDING or READY orders can be closed")
+
+	ErrPreconditionRequired = errors.New("If-Match header is required")
+	ErrPreconditionFailed   = errors.New("record was changed since it was read")
+
+	ErrInvalidMergePatch = errors.New("merge patch must be a JSON object")
+	ErrPatchField        = errors.New("field cannot be patched")
+	ErrInvalidPatchValue = errors.New("patched value has the wrong type")
+	ErrEmptyField        = errors.New("field cannot be empty")
+	ErrOrderItemsChanged = errors.New("order items cannot be added, removed or swapped with PATCH")
+	ErrOrderItemsLocked  = errors.New("only the items of PENDING orders can be changed")
+
+	ErrUnauthorized       = errors.New("authentication required")
+	ErrForbidden          = errors.New("insufficient role for this action")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrInvalidRole        = errors.New("role must be one of BARISTA, MANAGER, ADMIN")
+	ErrWeakPassword       = errors.New("password must be at least 8 characters")
+	ErrInvalidUsername    = errors.New("username cannot be empty")
+	ErrInvalidKeyName     = errors.New("key name cannot be empty")
+
+	ErrMissingStaff     = errors.New("staff_id is required")
+	ErrInvalidStaffId   = errors.New("staff member does not exist")
+	ErrInvalidShiftTime = errors.New("ends_at must be after starts_at")
+	ErrShiftOverlap     = errors.New("shift overlaps another shift of the staff member")
+	ErrShiftState       = errors.New("shift is not in a state that allows this action")
+	ErrClockInWindow    = errors.New("shift cannot be clocked in at this time")
 
 	ErrInvalidQuantity       = errors.New("quantity cannot be negative")
 	ErrInvalidReorderLevel   = errors.New("reorder level cannot be negative")
 	ErrInvalidIngredientId   = errors.New("Id be positive")
 	ErrInvalidIngredientName = errors.New("ingredient name cannot be empty")
+	ErrInvalidPrice          = errors.New("price cannot be negative")
+	ErrInvalidWasteReason    = errors.New("reason must be one of WASTE, SPOILAGE, THEFT")
+	ErrInsufficientStock     = errors.New("not enough stock")
+	ErrInvalidWithin         = errors.New("within must be a positive duration such as 48h or 2d")
+
+	ErrInvalidLocationName = errors.New("location name cannot be empty")
+	ErrLocationInUse       = errors.New("location still has stock or orders")
+
+	ErrStockCountClosed = errors.New("stock count is no longer open")
+	ErrEmptyStockCount  = errors.New("stock count has no counted items")
+
+	ErrSameLocation         = errors.New("source and destination location must differ")
+	ErrMissingDestination   = errors.New("to_location_id is required")
+	ErrEmptyTransfer        = errors.New("transfer has no items")
+	ErrTransferState        = errors.New("transfer is not in a state that allows this action")
+	ErrTransferItemNotFound = errors.New("received item is not part of the transfer")
+
+	ErrInvalidPaymentMethod = errors.New("payment method must be one of CASH, CARD")
+	ErrInvalidPaymentAmount = errors.New("payment amount must be positive")
+	ErrInvalidTip           = errors.New("tip cannot be negative")
+	ErrEmptyPayment         = errors.New("no payments given")
+	ErrOverpayment          = errors.New("payments exceed the order total")
+	ErrOrderUnpaid          = errors.New("payments do not cover the order total")
+	ErrOrderNotPayable      = errors.New("cancelled orders cannot be paid")
+
+	ErrMissingRefundReason = errors.New("refund reason is required")
+	ErrRefundState         = errors.New("only completed orders can be refunded")
+	ErrRefundItemNotFound  = errors.New("refunded item is not part of the order")
+	ErrRefundQuantity      = errors.New("refund quantity exceeds what is left to refund")
+
+	ErrInvalidBucket    = errors.New("bucket must be one of hour, day, week, month")
+	ErrInvalidGroupBy   = errors.New("groupBy must be one of menu_item, category, payment_method, status, location, staff")
+	ErrInvalidTimezone  = errors.New("unknown timezone")
+	ErrInvalidDateRange = errors.New("invalid date range")
+	ErrInvalidMetric    = errors.New("metric must be one of units, revenue, customers")
+	ErrInvalidStatus    = errors.New("unknown order status")
+	ErrInvalidThreshold = errors.New("threshold must be a positive duration such as 10m")
+	ErrInvalidEventId   = errors.New("Last-Event-ID must be a non-negative event id")
+
+	ErrInvalidAuditResource = errors.New("resource must be one of customer, menu, inventory, order, stock_count, transfer")
+	ErrInvalidAuditId       = errors.New("id must be a valid UUID")
+
+	ErrInvalidWebhookUrl    = errors.New("url must be an absolute http or https URL")
+	ErrInvalidWebhookEvents = errors.New("event_types must list one or more of order.created, order.completed, inventory.low_stock")
+	ErrInvalidWebhookSecret = errors.New("secret must be 16 to 255 characters")
+	ErrInvalidDeliveryState = errors.New("status must be one of PENDING, DELIVERED, FAILED")
+
+	ErrInvalidIdempotencyKey = errors.New("Idempotency-Key must be 1 to 255 characters")
+	ErrIdempotencyMismatch   = errors.New("Idempotency-Key was already used with a different request")
+	ErrIdempotencyInProgress = errors.New("a request with this Idempotency-Key is still being processed")
+
+	ErrInvalidStationId    = errors.New("station does not exist")
+	ErrInvalidTicketStatus = errors.New("status must be one of OPEN, BUMPED")
+	ErrTicketBumped        = errors.New("ticket is already bumped")
+	ErrTicketItemBumped    = errors.New("ticket item is already bumped")
+
+	ErrInvalidReceiptFormat = errors.New("format must be one of txt, html, pdf")
+
+	ErrInvalidCustomerId = errors.New("customer does not exist")
+	ErrNewOrderStatus    = errors.New("new orders must be PENDING or COMPLETED")
+	ErrEmptyBatch        = errors.New("batch has no orders")
+	ErrStatusNotSettable = errors.New("orders become READY at the stations and REFUNDED by refunds")
+	ErrOrderFinal        = errors.New("COMPLETED, CANCELLED and REFUNDED orders cannot change status")
 )
 
 type APIError struct {
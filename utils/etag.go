@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ETag formats the version of a row, its updated_at, as a strong entity tag.
+func ETag(updatedAt TIME) string {
+	return `"` + strconv.FormatInt(time.Time(updatedAt).UnixMicro(), 36) + `"`
+}
+
+// ParseIfMatch splits an If-Match header into its entity tags.
+func ParseIfMatch(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// ETagMatches reports whether a row last updated at updatedAt satisfies the
+// If-Match tags. Comparison is strong, so weak tags never match.
+func ETagMatches(tags []string, updatedAt TIME) bool {
+	current := ETag(updatedAt)
+	for _, tag := range tags {
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+type ifMatchKey struct{}
+
+// WithIfMatch makes the writes done with ctx conditional on the row still
+// matching one of tags.
+func WithIfMatch(ctx context.Context, tags []string) context.Context {
+	return context.WithValue(ctx, ifMatchKey{}, tags)
+}
+
+func IfMatchFrom(ctx context.Context) ([]string, bool) {
+	tags, ok := ctx.Value(ifMatchKey{}).([]string)
+	return tags, ok
+}
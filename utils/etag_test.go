@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{header: "", want: nil},
+		{header: " , ", want: nil},
+		{header: `"abc"`, want: []string{`"abc"`}},
+		{header: `"abc", W/"def" ,"ghi"`, want: []string{`"abc"`, `W/"def"`, `"ghi"`}},
+		{header: "*", want: []string{"*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := ParseIfMatch(tt.header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseIfMatch(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	updatedAt := TIME(time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC))
+	current := ETag(updatedAt)
+	stale := ETag(TIME(time.Time(updatedAt).Add(-time.Microsecond)))
+
+	tests := []struct {
+		name string
+		tags []string
+		want bool
+	}{
+		{name: "current", tags: []string{current}, want: true},
+		{name: "any", tags: []string{"*"}, want: true},
+		{name: "one of several", tags: []string{stale, current}, want: true},
+		{name: "stale", tags: []string{stale}, want: false},
+		{name: "weak", tags: []string{"W/" + current}, want: false},
+		{name: "unquoted", tags: []string{current[1 : len(current)-1]}, want: false},
+		{name: "none", tags: nil, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ETagMatches(tt.tags, updatedAt); got != tt.want {
+				t.Errorf("ETagMatches(%q) = %v, want %v", tt.tags, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"database/sql/driver"
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -16,3 +18,66 @@ type INT int
 type JSONB json.RawMessage
 
 type TIME time.Time
+
+func (t *TIME) Scan(src any) error {
+	switch v := src.(type) {
+	case time.Time:
+		*t = TIME(v)
+	case nil:
+		*t = TIME{}
+	default:
+		return fmt.Errorf("cannot scan %T into TIME", src)
+	}
+	return nil
+}
+
+func (t TIME) Value() (driver.Value, error) {
+	return time.Time(t), nil
+}
+
+func (t TIME) MarshalJSON() ([]byte, error) {
+	return time.Time(t).MarshalJSON()
+}
+
+func (t *TIME) UnmarshalJSON(data []byte) error {
+	var v time.Time
+	if err := v.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*t = TIME(v)
+	return nil
+}
+
+func (j *JSONB) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case string:
+		*j = JSONB(v)
+	case nil:
+		*j = nil
+	default:
+		return fmt.Errorf("cannot scan %T into JSONB", src)
+	}
+	return nil
+}
+
+// Value stores an empty document as {} so NOT NULL JSONB columns accept it.
+func (j JSONB) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return []byte("{}"), nil
+	}
+	return []byte(j), nil
+}
+
+func (j JSONB) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+func (j *JSONB) UnmarshalJSON(data []byte) error {
+	*j = append((*j)[:0], data...)
+	return nil
+}
@@ -0,0 +1,29 @@
+package utils
+
+import "context"
+
+// DefaultLocationId is the store seeded by init.sql. Requests that do not
+// select a location work against it.
+const DefaultLocationId = "00000000-0000-0000-0000-000000000001"
+
+type locationKey struct{}
+
+// WithLocation stores the location selected by the caller in ctx.
+func WithLocation(ctx context.Context, locationId string) context.Context {
+	return context.WithValue(ctx, locationKey{}, locationId)
+}
+
+// SelectedLocation returns the location selected for the request, if any.
+// Reports use it to decide between one store and all of them.
+func SelectedLocation(ctx context.Context) (string, bool) {
+	locationId, ok := ctx.Value(locationKey{}).(string)
+	return locationId, ok && locationId != ""
+}
+
+// LocationOrDefault returns the selected location or the default store.
+func LocationOrDefault(ctx context.Context) string {
+	if locationId, ok := SelectedLocation(ctx); ok {
+		return locationId
+	}
+	return DefaultLocationId
+}
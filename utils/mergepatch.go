@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// MergePatch applies a JSON merge patch (RFC 7396) to doc: members of the
+// patch replace those of doc, objects are merged recursively and null
+// removes a member.
+func MergePatch(doc []byte, patch []byte) ([]byte, error) {
+	var target any
+	if len(bytes.TrimSpace(doc)) > 0 {
+		if err := json.Unmarshal(doc, &target); err != nil {
+			return nil, err
+		}
+	}
+	var changes any
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeValue(target, changes))
+}
+
+func mergeValue(target any, patch any) any {
+	changes, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	merged, ok := target.(map[string]any)
+	if !ok {
+		merged = map[string]any{}
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(merged, name)
+			continue
+		}
+		merged[name] = mergeValue(merged[name], value)
+	}
+	return merged
+}
+
+// PatchFields returns the top-level members a merge patch changes. A patch
+// that is not a JSON object replaces the whole document, which no resource
+// allows, so it is rejected.
+func PatchFields(patch []byte) ([]string, error) {
+	var changes map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &changes); err != nil || changes == nil {
+		return nil, ErrInvalidMergePatch
+	}
+	fields := make([]string, 0, len(changes))
+	for name := range changes {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
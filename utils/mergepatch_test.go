@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{name: "replaces a member", doc: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "adds a member", doc: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "null removes a member", doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "removing a missing member", doc: `{"a":"b"}`, patch: `{"c":null}`, want: `{"a":"b"}`},
+		{name: "arrays are replaced", doc: `{"a":[1,2]}`, patch: `{"a":[3]}`, want: `{"a":[3]}`},
+		{name: "objects merge recursively", doc: `{"a":{"b":1,"c":2}}`, patch: `{"a":{"b":null,"d":3}}`, want: `{"a":{"c":2,"d":3}}`},
+		{name: "object replaces a scalar", doc: `{"a":1}`, patch: `{"a":{"b":null,"c":1}}`, want: `{"a":{"c":1}}`},
+		{name: "non-object patch replaces the document", doc: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+		{name: "empty document", doc: ``, patch: `{"a":{"b":null}}`, want: `{"a":{}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("MergePatch() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MergePatch() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePatchInvalidJSON(t *testing.T) {
+	if _, err := MergePatch([]byte(`{"a":1}`), []byte(`{`)); err == nil {
+		t.Error("MergePatch() with an invalid patch: want an error")
+	}
+	if _, err := MergePatch([]byte(`{`), []byte(`{}`)); err == nil {
+		t.Error("MergePatch() with an invalid document: want an error")
+	}
+}
+
+func TestPatchFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		want    []string
+		wantErr error
+	}{
+		{name: "sorted members", patch: `{"b":1,"a":null}`, want: []string{"a", "b"}},
+		{name: "empty object", patch: `{}`, want: []string{}},
+		{name: "array", patch: `[1]`, wantErr: ErrInvalidMergePatch},
+		{name: "null", patch: `null`, wantErr: ErrInvalidMergePatch},
+		{name: "invalid JSON", patch: `{"a"`, wantErr: ErrInvalidMergePatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PatchFields([]byte(tt.patch))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PatchFields() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PatchFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CourierAdvance is the width of every Courier glyph as a fraction of the
+// font size.
+const CourierAdvance = 0.6
+
+// PDF writes plain documents: pages of text in Courier and Courier-Bold,
+// which every PDF reader provides, so no font has to be embedded.
+// Coordinates are in points from the bottom left corner of the page.
+type PDF struct {
+	width  float64
+	height float64
+	pages  []*bytes.Buffer
+}
+
+func NewPDF(width float64, height float64) *PDF {
+	return &PDF{width: width, height: height}
+}
+
+// AddPage starts a new page; drawing goes to the last page.
+func (p *PDF) AddPage() {
+	p.pages = append(p.pages, &bytes.Buffer{})
+}
+
+func (p *PDF) page() *bytes.Buffer {
+	if len(p.pages) == 0 {
+		p.AddPage()
+	}
+	return p.pages[len(p.pages)-1]
+}
+
+// Text writes s with its baseline starting at x, y. Characters outside
+// Windows-1252 are printed as "?".
+func (p *PDF) Text(x float64, y float64, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(p.page(), "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, pdfNumber(size), pdfNumber(x), pdfNumber(y), pdfString(s))
+}
+
+// Line draws a straight line of the given width.
+func (p *PDF) Line(x1 float64, y1 float64, x2 float64, y2 float64, width float64) {
+	fmt.Fprintf(p.page(), "%s w %s %s m %s %s l S\n", pdfNumber(width), pdfNumber(x1), pdfNumber(y1), pdfNumber(x2), pdfNumber(y2))
+}
+
+// Bytes returns the document.
+func (p *PDF) Bytes() []byte {
+	p.page()
+
+	var out bytes.Buffer
+	// The binary comment marks the file as binary for transfer programs
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	objects := 4 + 2*len(p.pages)
+	offsets := make([]int, objects+1)
+	object := func(id int, body string) {
+		offsets[id] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", id, body)
+	}
+
+	kids := make([]string, len(p.pages))
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	object(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %s %s] >>",
+		strings.Join(kids, " "), len(p.pages), pdfNumber(p.width), pdfNumber(p.height)))
+	object(3, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	object(4, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range p.pages {
+		pageId, contentId := 5+2*i, 6+2*i
+		object(pageId, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", contentId))
+		object(contentId, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", objects+1)
+	for id := 1; id <= objects; id++ {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offsets[id])
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", objects+1, xref)
+
+	return out.Bytes()
+}
+
+func pdfNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// winAnsiExtras maps the characters Windows-1252 places in 0x80-0x9F.
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// pdfString encodes s in Windows-1252 and escapes it for a literal string.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r >= 0x20 && r < 0x7F, r >= 0xA0 && r <= 0xFF:
+			b.WriteByte(byte(r))
+		case winAnsiExtras[r] != 0:
+			b.WriteByte(winAnsiExtras[r])
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestPDFString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Latte", want: "Latte"},
+		{in: `(a) \ b`, want: `\(a\) \\ b`},
+		{in: "Café", want: "Caf\xe9"},
+		{in: "€ 3–4", want: "\x80 3\x964"},
+		{in: "咖啡", want: "??"},
+		{in: "a\tb\n", want: "a?b?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := pdfString(tt.in); got != tt.want {
+				t.Errorf("pdfString(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPDFBytes(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages int
+	}{
+		{name: "no page drawn", pages: 0},
+		{name: "one page", pages: 1},
+		{name: "three pages", pages: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdf := NewPDF(226.77, 400)
+			for i := 0; i < tt.pages; i++ {
+				pdf.AddPage()
+				pdf.Text(10, 380, 9, i == 0, fmt.Sprintf("Page (%d)", i+1))
+				pdf.Line(10, 370, 216.77, 370, 0.5)
+			}
+			out := pdf.Bytes()
+
+			pages := max(tt.pages, 1)
+			if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+				t.Fatalf("not framed as a PDF: %q", out)
+			}
+			if got := bytes.Count(out, []byte("/Type /Page /")); got != pages {
+				t.Errorf("%d page objects, want %d", got, pages)
+			}
+			if !bytes.Contains(out, []byte(fmt.Sprintf("/Count %d", pages))) {
+				t.Errorf("page tree does not count %d pages", pages)
+			}
+			if tt.pages > 0 && !bytes.Contains(out, []byte(`BT /F2 9 Tf 10 380 Td (Page \(1\)) Tj ET`)) {
+				t.Error("text of the first page missing")
+			}
+
+			// Every xref entry points at the start of its object
+			startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+			if startxref == nil {
+				t.Fatal("startxref missing")
+			}
+			xref, _ := strconv.Atoi(string(startxref[1]))
+			if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+				t.Fatalf("startxref %d does not point at the xref table", xref)
+			}
+			entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllSubmatch(out[xref:], -1)
+			if len(entries) != 4+2*pages {
+				t.Fatalf("%d xref entries, want %d", len(entries), 4+2*pages)
+			}
+			for i, entry := range entries {
+				offset, _ := strconv.Atoi(string(entry[1]))
+				if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(out[offset:], []byte(want)) {
+					t.Errorf("xref entry %d points at %q", i+1, out[offset:min(offset+10, len(out))])
+				}
+			}
+		})
+	}
+}